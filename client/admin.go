@@ -0,0 +1,288 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/memoio/go-settlement/server/api"
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/utils"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+)
+
+// admin ops by name in command line
+var adminOps = map[string]uint8{
+	"create-group":       contract.OpCreateGroup,
+	"register-token":     contract.OpRegisterToken,
+	"set-pledge":         contract.OpSetPledgeMoney,
+	"pledge":             contract.OpPledge,
+	"recharge":           contract.OpRecharge,
+	"add-admin":          contract.OpAddAdmin,
+	"remove-admin":       contract.OpRemoveAdmin,
+	"set-threshold":      contract.OpSetThreshold,
+	"transfer-ownership": contract.OpTransferOwnership,
+	"set-change-delay":   contract.OpSetChangeDelay,
+}
+
+func adminOpNames() string {
+	names := make([]string, 0, len(adminOps))
+	for name := range adminOps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// proposalParas are paras of propose by flags
+func proposalParas(cctx *cli.Context) (*contract.ProposalParas, error) {
+	paras := &contract.ProposalParas{
+		Index:      cctx.Uint64("index"),
+		TokenIndex: uint32(cctx.Uint("token-index")),
+		Level:      uint16(cctx.Uint("level")),
+		Threshold:  uint16(cctx.Uint("threshold")),
+		Delay:      cctx.Uint64("delay"),
+	}
+
+	var err error
+	if cctx.IsSet("addr") {
+		paras.Addr, err = parseAddr(cctx.String("addr"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cctx.IsSet("money") {
+		paras.Money, err = parseBig(cctx.String("money"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cctx.IsSet("ppledge") {
+		paras.PPledge, err = parseBig(cctx.String("ppledge"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return paras, nil
+}
+
+var adminCmd = &cli.Command{
+	Name:  "admin",
+	Usage: "Propose, approve and execute admin ops of RoleMgr",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "propose",
+			Usage:     "Propose an admin op, approved by --from too",
+			ArgsUsage: "<op>",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "addr", Usage: "address of register-token, add-admin, remove-admin and transfer-ownership"},
+				&cli.Uint64Flag{Name: "index", Usage: "role index of pledge and recharge"},
+				&cli.UintFlag{Name: "token-index", Usage: "token index of recharge"},
+				&cli.UintFlag{Name: "level", Usage: "level of create-group"},
+				&cli.UintFlag{Name: "threshold", Usage: "approvals needed, of set-threshold"},
+				&cli.StringFlag{Name: "money", Usage: "amount of pledge and recharge, or pledge of keeper of set-pledge"},
+				&cli.StringFlag{Name: "ppledge", Usage: "pledge of provider of set-pledge"},
+				&cli.Uint64Flag{Name: "delay", Usage: "seconds of set-change-delay"},
+			},
+			Action: func(cctx *cli.Context) error {
+				err := needArgs(cctx, 1)
+				if err != nil {
+					return err
+				}
+
+				op, ok := adminOps[cctx.Args().First()]
+				if !ok {
+					return xerrors.Errorf("op %q is not one of %s", cctx.Args().First(), adminOpNames())
+				}
+
+				paras, err := proposalParas(cctx)
+				if err != nil {
+					return err
+				}
+
+				return send(cctx, "Propose", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.Propose(uid, sig, caller, op, paras)
+				}, op, paras)
+			},
+		},
+		{
+			Name:      "approve",
+			Usage:     "Approve a proposal",
+			ArgsUsage: "<id>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				id := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "ApproveProposal", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.ApproveProposal(uid, sig, caller, id)
+				}, id)
+			},
+		},
+		{
+			Name:      "execute",
+			Usage:     "Execute a proposal with enough approvals",
+			ArgsUsage: "<id>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				id := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "ExecuteProposal", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.ExecuteProposal(uid, sig, caller, id)
+				}, id)
+			},
+		},
+		{
+			Name:      "proposal",
+			Usage:     "Show a proposal",
+			ArgsUsage: "<id>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				id := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetProposal(caller, id)
+				})
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "Show admins and threshold",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetAdmins(caller)
+				})
+			},
+		},
+	},
+}
+
+var ownerCmd = &cli.Command{
+	Name:  "owner",
+	Usage: "Transfer ownership of token and role mgr contracts in two steps",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "transfer",
+			Usage:     "Offer ownership of contract to new owner, by owner",
+			ArgsUsage: "<contract> <new owner>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 2)
+				caddr, newOwner := p.addr(), p.addr()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "TransferOwnership", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.TransferOwnership(uid, sig, caller, caddr, newOwner)
+				}, caddr, newOwner)
+			},
+		},
+		{
+			Name:      "accept",
+			Usage:     "Accept ownership of contract, by pending owner",
+			ArgsUsage: "<contract>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				caddr := p.addr()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "AcceptOwnership", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.AcceptOwnership(uid, sig, caller, caddr)
+				}, caddr)
+			},
+		},
+		{
+			Name:      "info",
+			Usage:     "Show owner and pending owner of contract",
+			ArgsUsage: "<contract>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				caddr := p.addr()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetOwnerInfo(caller, caddr)
+				})
+			},
+		},
+	},
+}
+
+var addressCmd = &cli.Command{
+	Name:  "address",
+	Usage: "Change address of a registered index",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "change",
+			Usage:     "Remap index to new address, signed by old and new address",
+			ArgsUsage: "<index> <new address>",
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "old-sign", Usage: "sig of old address in hex, if it is not --from"},
+				&cli.StringFlag{Name: "new-sign", Usage: "sig of new address in hex, if it is not --from"},
+			},
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 2)
+				index, newAddr := p.uint64(), p.addr()
+				oldSig, newSig := p.hexFlag("old-sign"), p.hexFlag("new-sign")
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "ChangeAddress", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.ChangeAddress(uid, sig, caller, index, newAddr, oldSig, newSig)
+				}, index, newAddr, oldSig, newSig)
+			},
+		},
+		{
+			Name:      "confirm",
+			Usage:     "Confirm a pending change after its delay",
+			ArgsUsage: "<index>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				index := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "ConfirmAddress", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.ConfirmAddress(uid, sig, caller, index)
+				}, index)
+			},
+		},
+		{
+			Name:      "cancel",
+			Usage:     "Cancel a pending change, by old address",
+			ArgsUsage: "<index>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				index := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "CancelAddress", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.CancelAddress(uid, sig, caller, index)
+				}, index)
+			},
+		},
+		{
+			Name:      "pending",
+			Usage:     "Show pending change of index",
+			ArgsUsage: "<index>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				index := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetAddrChange(caller, index)
+				})
+			},
+		},
+	},
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/memoio/go-settlement/utils"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+)
+
+// needArgs checks count of args is n
+func needArgs(cctx *cli.Context, n int) error {
+	if cctx.Args().Len() != n {
+		return xerrors.Errorf("need %d args: %s", n, cctx.Command.ArgsUsage)
+	}
+	return nil
+}
+
+func parseAddr(s string) (utils.Address, error) {
+	var addr utils.Address
+	err := addr.UnmarshalText([]byte(s))
+	if err != nil {
+		return addr, xerrors.Errorf("address %q: %w", s, err)
+	}
+	return addr, nil
+}
+
+func parseAddrs(ss []string) ([]utils.Address, error) {
+	addrs := make([]utils.Address, len(ss))
+	for i, s := range ss {
+		addr, err := parseAddr(s)
+		if err != nil {
+			return nil, err
+		}
+		addrs[i] = addr
+	}
+	return addrs, nil
+}
+
+func parseUint(s string, bits int) (uint64, error) {
+	v, err := strconv.ParseUint(s, 10, bits)
+	if err != nil {
+		return 0, xerrors.Errorf("number %q: %w", s, err)
+	}
+	return v, nil
+}
+
+// parseBig is an integer amount in wei
+func parseBig(s string) (*big.Int, error) {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, xerrors.Errorf("amount %q is not an integer", s)
+	}
+	return v, nil
+}
+
+// parseHex is bytes in hex with or without 0x, empty is nil
+func parseHex(s string) ([]byte, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if s == "" {
+		return nil, nil
+	}
+	buf, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, xerrors.Errorf("hex %q: %w", s, err)
+	}
+	return buf, nil
+}
+
+func parseHexes(ss []string) ([][]byte, error) {
+	res := make([][]byte, len(ss))
+	for i, s := range ss {
+		buf, err := parseHex(s)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = buf
+	}
+	return res, nil
+}
+
+// args parses args of a command in order; the first error is kept
+type args struct {
+	cctx *cli.Context
+	i    int
+	err  error
+}
+
+func newArgs(cctx *cli.Context, n int) *args {
+	return &args{cctx: cctx, err: needArgs(cctx, n)}
+}
+
+func (a *args) next() string {
+	s := a.cctx.Args().Get(a.i)
+	a.i++
+	return s
+}
+
+func (a *args) addr() utils.Address {
+	s := a.next()
+	if a.err != nil {
+		return utils.NilAddress
+	}
+	v, err := parseAddr(s)
+	a.err = err
+	return v
+}
+
+func (a *args) uint64() uint64 {
+	s := a.next()
+	if a.err != nil {
+		return 0
+	}
+	v, err := parseUint(s, 64)
+	a.err = err
+	return v
+}
+
+func (a *args) uint32() uint32 {
+	s := a.next()
+	if a.err != nil {
+		return 0
+	}
+	v, err := parseUint(s, 32)
+	a.err = err
+	return uint32(v)
+}
+
+func (a *args) uint16() uint16 {
+	s := a.next()
+	if a.err != nil {
+		return 0
+	}
+	v, err := parseUint(s, 16)
+	a.err = err
+	return uint16(v)
+}
+
+func (a *args) big() *big.Int {
+	s := a.next()
+	if a.err != nil {
+		return nil
+	}
+	v, err := parseBig(s)
+	a.err = err
+	return v
+}
+
+func (a *args) hash() utils.Hash {
+	s := a.next()
+	if a.err != nil {
+		return utils.NilHash
+	}
+	buf, err := parseHex(s)
+	if err == nil && len(buf) != utils.HashLength {
+		err = xerrors.Errorf("hash %q is not %d bytes", s, utils.HashLength)
+	}
+	a.err = err
+	return utils.BytesToHash(buf)
+}
+
+// hexFlag is bytes of flag name in hex
+func (a *args) hexFlag(name string) []byte {
+	if a.err != nil {
+		return nil
+	}
+	v, err := parseHex(a.cctx.String(name))
+	a.err = err
+	return v
+}
+
+func (a *args) hexesFlag(name string) [][]byte {
+	if a.err != nil {
+		return nil
+	}
+	v, err := parseHexes(a.cctx.StringSlice(name))
+	a.err = err
+	return v
+}
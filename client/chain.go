@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/memoio/go-settlement/server/api"
+	"github.com/memoio/go-settlement/server/api/client"
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+)
+
+var authCmd = &cli.Command{
+	Name:  "auth",
+	Usage: "Manage api tokens, by an admin token",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "new",
+			Usage:     "Create a token of perms",
+			ArgsUsage: "<perm>...",
+			Action: func(cctx *cli.Context) error {
+				if cctx.Args().Len() == 0 {
+					return xerrors.New("need perms: read, write, sign or admin")
+				}
+
+				perms := make([]auth.Permission, 0, cctx.Args().Len())
+				for _, p := range cctx.Args().Slice() {
+					perms = append(perms, auth.Permission(p))
+				}
+
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					token, err := a.AuthNew(cctx.Context, perms)
+					return string(token), err
+				})
+			},
+		},
+		{
+			Name:      "verify",
+			Usage:     "Show perms of a token",
+			ArgsUsage: "<token>",
+			Action: func(cctx *cli.Context) error {
+				err := needArgs(cctx, 1)
+				if err != nil {
+					return err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.AuthVerify(cctx.Context, cctx.Args().First())
+				})
+			},
+		},
+	},
+}
+
+var eventFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "contract",
+		Usage: "address of contract emitting events",
+	},
+	&cli.StringSliceFlag{
+		Name:  "type",
+		Usage: "type of events",
+	},
+	&cli.Int64SliceFlag{
+		Name:  "index",
+		Usage: "role or group index involved",
+	},
+	&cli.Uint64Flag{
+		Name:  "from-height",
+		Usage: "first block of events",
+	},
+	&cli.Uint64Flag{
+		Name:  "to-height",
+		Usage: "last block of events, latest if 0",
+	},
+}
+
+func eventFilter(cctx *cli.Context) (*types.EventFilter, error) {
+	f := &types.EventFilter{
+		Types:      cctx.StringSlice("type"),
+		FromHeight: cctx.Uint64("from-height"),
+		ToHeight:   cctx.Uint64("to-height"),
+	}
+
+	for _, index := range cctx.Int64Slice("index") {
+		if index < 0 {
+			return nil, xerrors.Errorf("index %d is negative", index)
+		}
+		f.Indexes = append(f.Indexes, uint64(index))
+	}
+
+	if cctx.IsSet("contract") {
+		addr, err := parseAddr(cctx.String("contract"))
+		if err != nil {
+			return nil, err
+		}
+		f.Contract = addr
+	}
+
+	return f, nil
+}
+
+var chainCmd = &cli.Command{
+	Name:  "chain",
+	Usage: "Query chain and send messages",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "id",
+			Usage: "Show chain id",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.ChainID(caller), nil
+				})
+			},
+		},
+		{
+			Name:  "gas-price",
+			Usage: "Show fee per gas of calls",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetGasPrice(caller), nil
+				})
+			},
+		},
+		{
+			Name:      "nonce",
+			Usage:     "Show next nonce of address, --from if not given",
+			ArgsUsage: "[address]",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					addr := caller
+					if cctx.Args().Present() {
+						var err error
+						addr, err = parseAddr(cctx.Args().First())
+						if err != nil {
+							return nil, err
+						}
+					}
+					return a.GetNonce(caller, addr), nil
+				})
+			},
+		},
+		{
+			Name:      "advance-time",
+			Usage:     "Move mock clock of a dev node forward, by an admin token and a RoleMgr admin key",
+			ArgsUsage: "<seconds>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				d := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "AdvanceTime", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.AdvanceTime(uid, sig, caller, d)
+				}, d)
+			},
+		},
+		{
+			Name:  "head",
+			Usage: "Show latest sealed block",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.ChainHead(caller)
+				})
+			},
+		},
+		{
+			Name:      "block",
+			Usage:     "Show block by height or hash",
+			ArgsUsage: "<height|hash>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				if strings.HasPrefix(cctx.Args().First(), "0x") {
+					h := p.hash()
+					if p.err != nil {
+						return p.err
+					}
+					return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+						return a.GetBlockByHash(caller, h)
+					})
+				}
+
+				height := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetBlockByHeight(caller, height)
+				})
+			},
+		},
+		{
+			Name:      "receipt",
+			Usage:     "Show receipt of a tx",
+			ArgsUsage: "<tx>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				tx := p.hash()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetReceipt(caller, tx)
+				})
+			},
+		},
+		{
+			Name:  "events",
+			Usage: "List events of sealed blocks",
+			Flags: eventFlags,
+			Action: func(cctx *cli.Context) error {
+				f, err := eventFilter(cctx)
+				if err != nil {
+					return err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetEvents(caller, f)
+				})
+			},
+		},
+		{
+			Name:  "watch-heads",
+			Usage: "Print each new block until interrupted",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					ch, err := a.SubscribeHeads(cctx.Context, caller)
+					if err != nil {
+						return nil, err
+					}
+					for b := range ch {
+						err = printResult(cctx, b)
+						if err != nil {
+							return nil, err
+						}
+					}
+					return nil, nil
+				})
+			},
+		},
+		{
+			Name:  "watch-events",
+			Usage: "Print each new event until interrupted",
+			Flags: eventFlags,
+			Action: func(cctx *cli.Context) error {
+				f, err := eventFilter(cctx)
+				if err != nil {
+					return err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					ch, err := a.SubscribeEvents(cctx.Context, caller, f)
+					if err != nil {
+						return nil, err
+					}
+					for e := range ch {
+						err = printResult(cctx, e)
+						if err != nil {
+							return nil, err
+						}
+					}
+					return nil, nil
+				})
+			},
+		},
+		{
+			Name:      "pending",
+			Usage:     "List calls waiting for earlier nonces of address, of all if not given",
+			ArgsUsage: "[address]",
+			Action: func(cctx *cli.Context) error {
+				addr := utils.NilAddress
+				if cctx.Args().Present() {
+					var err error
+					addr, err = parseAddr(cctx.Args().First())
+					if err != nil {
+						return err
+					}
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.MpoolPending(caller, addr)
+				})
+			},
+		},
+		{
+			Name:      "push",
+			Usage:     "Sign a message in json by key of --from and push it",
+			ArgsUsage: "<file|->",
+			Action: func(cctx *cli.Context) error {
+				err := needArgs(cctx, 1)
+				if err != nil {
+					return err
+				}
+
+				buf, err := readInput(cctx.Args().First())
+				if err != nil {
+					return err
+				}
+
+				m := new(message.Message)
+				err = json.Unmarshal(buf, m)
+				if err != nil {
+					return err
+				}
+
+				key, err := loadKey(cctx)
+				if err != nil {
+					return err
+				}
+
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					sm, err := client.SignMessage(a, key.SecretKey, m)
+					if err != nil {
+						return nil, err
+					}
+					return a.PushMessage(sm)
+				})
+			},
+		},
+	},
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"github.com/memoio/go-settlement/server/api"
+	"github.com/memoio/go-settlement/utils"
+	"github.com/urfave/cli/v2"
+)
+
+var fsCmd = &cli.Command{
+	Name:  "fs",
+	Usage: "Recharge and withdraw in fs of groups",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "recharge",
+			Usage:     "Recharge amount of token for user, from --from or air drop by admin",
+			ArgsUsage: "<user> <token index> <amount>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 3)
+				user, tIndex, money := p.uint64(), p.uint32(), p.big()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "Recharge", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.Recharge(uid, sig, caller, user, tIndex, money)
+				}, user, tIndex, money)
+			},
+		},
+		{
+			Name:      "withdraw",
+			Usage:     "Withdraw amount of token from fs, by user or keeper",
+			ArgsUsage: "<index> <token index> <amount>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 3)
+				index, tIndex, amount := p.uint64(), p.uint32(), p.big()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "WithdrawFromFs", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.WithdrawFromFs(uid, sig, caller, index, tIndex, amount)
+				}, index, tIndex, amount)
+			},
+		},
+		{
+			Name:      "pro-withdraw",
+			Usage:     "Withdraw pay of provider, auth by keepers",
+			ArgsUsage: "<provider> <token index> <pay> <lost>",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "ksign",
+					Usage: "sigs of keepers in hex, in order",
+				},
+			},
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 4)
+				proIndex, tIndex, pay, lost := p.uint64(), p.uint32(), p.big(), p.big()
+				ksigns := p.hexesFlag("ksign")
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "ProWithdraw", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.ProWithdraw(uid, sig, caller, proIndex, tIndex, pay, lost, ksigns)
+				}, proIndex, tIndex, pay, lost, ksigns)
+			},
+		},
+		{
+			Name:      "balance",
+			Usage:     "Show balance of index in fs of its group",
+			ArgsUsage: "<index> <token index>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 2)
+				index, tIndex := p.uint64(), p.uint32()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetBalanceInFs(caller, index, tIndex)
+				})
+			},
+		},
+		{
+			Name:      "settle",
+			Usage:     "Show settlement of provider in token",
+			ArgsUsage: "<provider> <token index>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 2)
+				index, tIndex := p.uint64(), p.uint32()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetSettleInfo(caller, index, tIndex)
+				})
+			},
+		},
+	},
+}
+
+var orderFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "usign",
+		Usage: "sig of user in hex",
+	},
+	&cli.StringFlag{
+		Name:  "psign",
+		Usage: "sig of provider in hex",
+	},
+	&cli.StringSliceFlag{
+		Name:  "ksign",
+		Usage: "sigs of keepers in hex, in order; or one aggregated BLS sig with bitmap of signers",
+	},
+}
+
+// orderCommand is add or sub of an order by method
+func orderCommand(name, usage, method string) *cli.Command {
+	return &cli.Command{
+		Name:      name,
+		Usage:     usage,
+		ArgsUsage: "<user> <provider> <start> <end> <size> <nonce> <token index> <price>",
+		Flags:     orderFlags,
+		Action: func(cctx *cli.Context) error {
+			p := newArgs(cctx, 8)
+			user, proIndex, start, end, size, nonce := p.uint64(), p.uint64(), p.uint64(), p.uint64(), p.uint64(), p.uint64()
+			tIndex, sprice := p.uint32(), p.big()
+			usign, psign, ksigns := p.hexFlag("usign"), p.hexFlag("psign"), p.hexesFlag("ksign")
+			if p.err != nil {
+				return p.err
+			}
+			return send(cctx, method, func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+				if method == "SubOrder" {
+					return a.SubOrder(uid, sig, caller, user, proIndex, start, end, size, nonce, tIndex, sprice, usign, psign, ksigns)
+				}
+				return a.AddOrder(uid, sig, caller, user, proIndex, start, end, size, nonce, tIndex, sprice, usign, psign, ksigns)
+			}, user, proIndex, start, end, size, nonce, tIndex, sprice, usign, psign, ksigns)
+		},
+	}
+}
+
+// repairCommand is add or sub of a repair by method
+func repairCommand(name, usage, method string) *cli.Command {
+	return &cli.Command{
+		Name:      name,
+		Usage:     usage,
+		ArgsUsage: "<provider> <new provider> <start> <end> <size> <nonce> <token index> <price>",
+		Flags:     orderFlags[1:],
+		Action: func(cctx *cli.Context) error {
+			p := newArgs(cctx, 8)
+			proIndex, newPro, start, end, size, nonce := p.uint64(), p.uint64(), p.uint64(), p.uint64(), p.uint64(), p.uint64()
+			tIndex, sprice := p.uint32(), p.big()
+			psign, ksigns := p.hexFlag("psign"), p.hexesFlag("ksign")
+			if p.err != nil {
+				return p.err
+			}
+			return send(cctx, method, func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+				if method == "SubRepair" {
+					return a.SubRepair(uid, sig, caller, proIndex, newPro, start, end, size, nonce, tIndex, sprice, psign, ksigns)
+				}
+				return a.AddRepair(uid, sig, caller, proIndex, newPro, start, end, size, nonce, tIndex, sprice, psign, ksigns)
+			}, proIndex, newPro, start, end, size, nonce, tIndex, sprice, psign, ksigns)
+		},
+	}
+}
+
+var orderCmd = &cli.Command{
+	Name:  "order",
+	Usage: "Add or sub storage orders, signed by user, provider and keepers",
+	Subcommands: []*cli.Command{
+		orderCommand("add", "Add an order of user at provider", "AddOrder"),
+		orderCommand("sub", "Sub an expired order of user at provider", "SubOrder"),
+		repairCommand("add-repair", "Move an order of provider to new provider, signed by new provider and keepers", "AddRepair"),
+		repairCommand("sub-repair", "Sub an expired repair at new provider", "SubRepair"),
+	},
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/memoio/go-settlement/keystore"
+	"github.com/memoio/go-settlement/utils"
+	"github.com/mitchellh/go-homedir"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+	"golang.org/x/xerrors"
+)
+
+var (
+	ErrNoKey      = errors.New("no key is selected, set --from")
+	ErrNoPassword = errors.New("no password and no terminal to ask for it")
+)
+
+// keyAddrs are addresses of one key
+type keyAddrs struct {
+	Address    utils.Address
+	EthAddress common.Address
+}
+
+// scryptN of new keys, light in tests
+var scryptN = keystore.StandardScryptN
+
+// openKeystore opens keystore in repo
+func openKeystore(cctx *cli.Context) (*keystore.KeyStore, error) {
+	repoDir, err := homedir.Expand(cctx.String("repo"))
+	if err != nil {
+		return nil, err
+	}
+	return keystore.NewKeyStore(filepath.Join(repoDir, "keystore"), scryptN)
+}
+
+func listKeys(cctx *cli.Context) ([]utils.Address, error) {
+	ks, err := openKeystore(cctx)
+	if err != nil {
+		return nil, err
+	}
+	return ks.List()
+}
+
+// password is read from --password-file, SETTLE_PASSWORD or terminal;
+// a new one is asked twice on terminal
+func password(cctx *cli.Context, isNew bool) (string, error) {
+	if cctx.IsSet("password-file") {
+		return readPassword(cctx.String("password-file"))
+	}
+
+	if pw, ok := os.LookupEnv("SETTLE_PASSWORD"); ok {
+		return pw, nil
+	}
+
+	return promptPassword("Password", isNew)
+}
+
+// ethPassword is password of an ethereum key file, read from
+// --eth-password-file or terminal
+func ethPassword(cctx *cli.Context, isNew bool) (string, error) {
+	if cctx.IsSet("eth-password-file") {
+		return readPassword(cctx.String("eth-password-file"))
+	}
+
+	return promptPassword("Ethereum key password", isNew)
+}
+
+func readPassword(name string) (string, error) {
+	buf, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(buf), "\r\n"), nil
+}
+
+func promptPassword(label string, isNew bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", ErrNoPassword
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: ", label) // nolint:errcheck
+	pw, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr) // nolint:errcheck
+	if err != nil {
+		return "", err
+	}
+
+	if isNew {
+		fmt.Fprintf(os.Stderr, "Repeat %s: ", strings.ToLower(label)) // nolint:errcheck
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr) // nolint:errcheck
+		if err != nil {
+			return "", err
+		}
+		if string(again) != string(pw) {
+			return "", xerrors.New("passwords do not match")
+		}
+	}
+
+	return string(pw), nil
+}
+
+// fromAddress is address of --from, or the only key in keystore
+func fromAddress(cctx *cli.Context) (utils.Address, error) {
+	if cctx.IsSet("from") {
+		var addr utils.Address
+		err := addr.UnmarshalText([]byte(cctx.String("from")))
+		return addr, err
+	}
+
+	addrs, err := listKeys(cctx)
+	if err != nil {
+		return utils.NilAddress, err
+	}
+	if len(addrs) != 1 {
+		return utils.NilAddress, ErrNoKey
+	}
+	return addrs[0], nil
+}
+
+// loadKey decrypts key of fromAddress in keystore
+func loadKey(cctx *cli.Context) (*utils.Key, error) {
+	addr, err := fromAddress(cctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ks, err := openKeystore(cctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ks.Has(addr) {
+		return nil, xerrors.Errorf("no key of %s: %w", addr, keystore.ErrNoKey)
+	}
+
+	pw, err := password(cctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return ks.Get(addr, pw)
+}
+
+// readInput reads file, or stdin if it is -
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(name)
+}
+
+var keyCmd = &cli.Command{
+	Name:  "key",
+	Usage: "Manage keys signing calls, encrypted by password in keystore",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "new",
+			Usage: "Generate a key in keystore",
+			Action: func(cctx *cli.Context) error {
+				ks, err := openKeystore(cctx)
+				if err != nil {
+					return err
+				}
+
+				pw, err := password(cctx, true)
+				if err != nil {
+					return err
+				}
+
+				addr, err := ks.NewKey(pw)
+				if err != nil {
+					return err
+				}
+
+				return printResult(cctx, addr)
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "List addresses of keys in keystore",
+			Action: func(cctx *cli.Context) error {
+				addrs, err := listKeys(cctx)
+				if err != nil {
+					return err
+				}
+				return printResult(cctx, addrs)
+			},
+		},
+		{
+			Name:      "import",
+			Usage:     "Import a secret key in hex into keystore",
+			ArgsUsage: "<file|->",
+			Action: func(cctx *cli.Context) error {
+				err := needArgs(cctx, 1)
+				if err != nil {
+					return err
+				}
+
+				buf, err := readInput(cctx.Args().First())
+				if err != nil {
+					return err
+				}
+
+				// not parseHex, which puts input in error
+				s := strings.TrimPrefix(strings.TrimSpace(string(buf)), "0x")
+				sk, err := hex.DecodeString(s)
+				if err != nil {
+					return utils.ErrKey
+				}
+
+				ks, err := openKeystore(cctx)
+				if err != nil {
+					return err
+				}
+
+				pw, err := password(cctx, true)
+				if err != nil {
+					return err
+				}
+
+				addr, err := ks.Import(sk, pw)
+				if err != nil {
+					return err
+				}
+
+				return printResult(cctx, addr)
+			},
+		},
+		{
+			Name:      "import-eth",
+			Usage:     "Import a go-ethereum keystore v3 file into keystore",
+			ArgsUsage: "<file|->",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "eth-password-file",
+					Usage: "file of password of the key file, prompt if not set",
+				},
+			},
+			Action: func(cctx *cli.Context) error {
+				err := needArgs(cctx, 1)
+				if err != nil {
+					return err
+				}
+
+				keyjson, err := readInput(cctx.Args().First())
+				if err != nil {
+					return err
+				}
+
+				ks, err := openKeystore(cctx)
+				if err != nil {
+					return err
+				}
+
+				epw, err := ethPassword(cctx, false)
+				if err != nil {
+					return err
+				}
+
+				pw, err := password(cctx, true)
+				if err != nil {
+					return err
+				}
+
+				addr, err := ks.ImportEth(keyjson, epw, pw)
+				if err != nil {
+					return err
+				}
+
+				key, err := ks.Get(addr, pw)
+				if err != nil {
+					return err
+				}
+
+				return printResult(cctx, &keyAddrs{
+					Address:    addr,
+					EthAddress: keystore.EthAddress(key.PubKey),
+				})
+			},
+		},
+		{
+			Name:      "export-eth",
+			Usage:     "Write key of address as a go-ethereum keystore v3 file",
+			ArgsUsage: "<address>",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "eth-password-file",
+					Usage: "file of password of the key file, prompt if not set",
+				},
+				&cli.StringFlag{
+					Name:  "out",
+					Usage: "file to write, stdout if not set",
+				},
+			},
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				addr := p.addr()
+				if p.err != nil {
+					return p.err
+				}
+
+				ks, err := openKeystore(cctx)
+				if err != nil {
+					return err
+				}
+
+				pw, err := password(cctx, false)
+				if err != nil {
+					return err
+				}
+
+				epw, err := ethPassword(cctx, true)
+				if err != nil {
+					return err
+				}
+
+				keyjson, err := ks.ExportEth(addr, pw, epw)
+				if err != nil {
+					return err
+				}
+
+				if cctx.IsSet("out") {
+					return ioutil.WriteFile(cctx.String("out"), keyjson, 0600)
+				}
+
+				_, err = fmt.Fprintln(cctx.App.Writer, string(keyjson))
+				return err
+			},
+		},
+		{
+			Name:      "export",
+			Usage:     "Print secret key of address in hex",
+			ArgsUsage: "<address>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				addr := p.addr()
+				if p.err != nil {
+					return p.err
+				}
+
+				ks, err := openKeystore(cctx)
+				if err != nil {
+					return err
+				}
+
+				pw, err := password(cctx, false)
+				if err != nil {
+					return err
+				}
+
+				sk, err := ks.Export(addr, pw)
+				if err != nil {
+					return err
+				}
+
+				return printResult(cctx, hex.EncodeToString(sk))
+			},
+		},
+		{
+			Name:      "delete",
+			Usage:     "Delete key of address from keystore",
+			ArgsUsage: "<address>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				addr := p.addr()
+				if p.err != nil {
+					return p.err
+				}
+
+				ks, err := openKeystore(cctx)
+				if err != nil {
+					return err
+				}
+
+				pw, err := password(cctx, false)
+				if err != nil {
+					return err
+				}
+
+				return ks.Delete(addr, pw)
+			},
+		},
+	},
+}
@@ -1,25 +1,91 @@
 package main
 
 import (
-	"context"
-	"crypto/rand"
-	"encoding/binary"
 	"fmt"
 	"net/http"
+	"os"
 
-	"github.com/filecoin-project/go-jsonrpc"
 	"github.com/memoio/go-settlement/server/api"
 	"github.com/memoio/go-settlement/server/api/client"
 	"github.com/memoio/go-settlement/utils"
-	"github.com/minio/blake2b-simd"
 	"github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/urfave/cli/v2"
 )
 
 var log = utils.Logger("main")
 
-func GetApi() (api.FullNode, jsonrpc.ClientCloser, error) {
-	apiaddr, err := multiaddr.NewMultiaddr("/ip4/127.0.0.1/tcp/18000")
+func main() {
+	app := newApp()
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n\n", err) // nolint:errcheck
+		os.Exit(1)
+	}
+}
+
+func newApp() *cli.App {
+	app := &cli.App{
+		Name:                 "settle-cli",
+		Usage:                "Client of settlement chain",
+		Version:              "1.0.0",
+		EnableBashCompletion: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "api",
+				Usage:   "multiaddr of server rpc",
+				EnvVars: []string{"SETTLE_API"},
+				Value:   "/ip4/127.0.0.1/tcp/18000",
+			},
+			&cli.StringFlag{
+				Name:    "token",
+				Usage:   "api token, needed if server runs with auth",
+				EnvVars: []string{"SETTLE_TOKEN"},
+			},
+			&cli.StringFlag{
+				Name:    "repo",
+				Usage:   "repo of client, keys are kept in it",
+				EnvVars: []string{"SETTLE_CLI_REPO"},
+				Value:   "~/.settle-cli",
+			},
+			&cli.StringFlag{
+				Name:    "from",
+				Usage:   "address of key which signs calls; the only key in keystore if not set",
+				EnvVars: []string{"SETTLE_FROM"},
+			},
+			&cli.StringFlag{
+				Name:  "password-file",
+				Usage: "file of password of keys; SETTLE_PASSWORD or prompt if not set",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "table or json",
+				Value:   "table",
+			},
+		},
+
+		Commands: []*cli.Command{
+			keyCmd,
+			authCmd,
+			chainCmd,
+			tokenCmd,
+			roleCmd,
+			groupCmd,
+			fsCmd,
+			orderCmd,
+			adminCmd,
+			ownerCmd,
+			addressCmd,
+		},
+	}
+
+	app.Setup()
+	return app
+}
+
+// getAPI connects to server of --api with --token
+func getAPI(cctx *cli.Context) (api.FullNode, func(), error) {
+	apiaddr, err := multiaddr.NewMultiaddr(cctx.String("api"))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -30,48 +96,69 @@ func GetApi() (api.FullNode, jsonrpc.ClientCloser, error) {
 	}
 
 	endpoint := "ws://" + raddr + "/rpc/v0"
-	//log.Info("rpc endpoint:", endpoint)
+	log.Debug("rpc endpoint: ", endpoint)
 
-	var headers http.Header
-	ctx := context.Background()
+	headers := http.Header{}
+	if cctx.String("token") != "" {
+		headers.Add("Authorization", "Bearer "+cctx.String("token"))
+	}
 
-	return client.NewFullNodeRPC(ctx, endpoint, headers)
-}
+	a, closer, err := client.NewFullNodeRPC(cctx.Context, endpoint, headers)
+	if err != nil {
+		return nil, nil, err
+	}
 
-func main() {
-	log.Info("create")
-	api, closer, _ := GetApi()
+	return a, closer, nil
+}
 
+// query calls fn with caller of --from, which may be nil, and prints its
+// result; nothing is printed if fn prints itself and returns nil
+func query(cctx *cli.Context, fn func(a api.FullNode, caller utils.Address) (interface{}, error)) error {
+	a, closer, err := getAPI(cctx)
+	if err != nil {
+		return err
+	}
 	defer closer()
 
-	key, err := utils.GenerateKey(rand.Reader)
+	caller, err := fromAddress(cctx)
 	if err != nil {
-		return
+		caller = utils.NilAddress
 	}
 
-	uAddr := utils.ToAddress(key.PubKey)
-
-	uid := api.GetNonce(uAddr, uAddr)
+	res, err := fn(a, caller)
+	if err != nil || res == nil {
+		return err
+	}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
+	return printResult(cctx, res)
+}
 
-	sig, err := utils.Sign(key.SecretKey, msg[:])
+// send signs method with params by key of --from at its nonce, then calls
+// fn and prints its result; params are arguments of the api method after
+// uid, sig and caller, in order
+func send(cctx *cli.Context, method string, fn func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error), params ...interface{}) error {
+	a, closer, err := getAPI(cctx)
 	if err != nil {
-		return
+		return err
 	}
+	defer closer()
 
-	addr, err := api.CreateErcToken(uid, sig, uAddr)
+	key, err := loadKey(cctx)
 	if err != nil {
-		return
+		return err
 	}
+	caller := utils.ToAddress(key.PubKey)
 
-	fmt.Println("create token addr: ", addr)
-
-	bal := api.BalanceOf(addr, uAddr, uAddr)
+	uid := a.GetNonce(caller, caller)
+	sig, err := client.SignCall(a, key.SecretKey, method, uid, params...)
+	if err != nil {
+		return err
+	}
 
-	fmt.Println(uAddr, "has balance: ", bal)
+	res, err := fn(a, uid, sig, caller)
+	if err != nil {
+		return err
+	}
 
-	return
+	return printResult(cctx, res)
 }
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/go-jsonrpc"
+	"github.com/memoio/go-settlement/keystore"
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/impl"
+	"github.com/memoio/go-settlement/server/impl/node"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// testServer serves a dev node, returns multiaddr of its rpc
+func testServer(t *testing.T) string {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	n, err := node.NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpcServer := jsonrpc.NewServer()
+	rpcServer.Register("Memoriae", impl.New(ctx, n, []byte("01234567890123456789012345678901")))
+
+	m := http.NewServeMux()
+	m.Handle("/rpc/v0", rpcServer)
+	srv := httptest.NewServer(m)
+	t.Cleanup(srv.Close)
+
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+	return fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", addr.Port)
+}
+
+func TestCli(t *testing.T) {
+	maddr := testServer(t)
+	repo := t.TempDir()
+	scryptN = keystore.LightScryptN
+
+	pwFile := filepath.Join(t.TempDir(), "password")
+	err := ioutil.WriteFile(pwFile, []byte("pass\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) (string, error) {
+		app := newApp()
+		buf := new(bytes.Buffer)
+		app.Writer = buf
+		err := app.Run(append([]string{"settle-cli", "--api", maddr, "--repo", repo, "--password-file", pwFile}, args...))
+		return strings.TrimSpace(buf.String()), err
+	}
+
+	mustRun := func(args ...string) string {
+		out, err := run(args...)
+		if err != nil {
+			t.Fatal(args, err)
+		}
+		return out
+	}
+
+	_, err = run("token", "create")
+	if err != ErrNoKey {
+		t.Fatal("call without key should fail: ", err)
+	}
+
+	admin := mustRun("key", "new")
+	out := mustRun("key", "list")
+	if !strings.HasPrefix(out, "0") || !strings.HasSuffix(out, admin) {
+		t.Fatal("key is not listed: ", out)
+	}
+
+	// address of new token comes with its tx hash
+	res := new(types.CreateResult)
+	err = json.Unmarshal([]byte(mustRun("-o", "json", "token", "create")), res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Addr == utils.NilAddress || res.TxHash == utils.NilHash {
+		t.Fatal("result of create is wrong: ", res)
+	}
+	taddr := res.Addr.String()
+
+	other := mustRun("key", "new")
+
+	// keys are kept encrypted, export and import by secret key
+	sk := mustRun("key", "export", other)
+	skFile := filepath.Join(t.TempDir(), "sk")
+	err = ioutil.WriteFile(skFile, []byte(sk), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = run("key", "import", skFile)
+	if err != keystore.ErrExist {
+		t.Fatal("import of stored key should fail: ", err)
+	}
+	mustRun("key", "delete", other)
+	if mustRun("key", "import", skFile) != other {
+		t.Fatal("imported address is wrong")
+	}
+
+	// round trip by an ethereum key file of another password
+	ethPwFile := filepath.Join(t.TempDir(), "eth-password")
+	err = ioutil.WriteFile(ethPwFile, []byte("eth"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ethFile := filepath.Join(t.TempDir(), "eth.json")
+	mustRun("key", "export-eth", "--eth-password-file", ethPwFile, "--out", ethFile, other)
+	mustRun("key", "delete", other)
+	_, err = run("key", "import-eth", "--eth-password-file", pwFile, ethFile)
+	if err != keystore.ErrPassword {
+		t.Fatal("import with wrong eth password should fail: ", err)
+	}
+	var ka keyAddrs
+	err = json.Unmarshal([]byte(mustRun("-o", "json", "key", "import-eth", "--eth-password-file", ethPwFile, ethFile)), &ka)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ka.Address.String() != other || ka.Address == utils.Address(ka.EthAddress) {
+		t.Fatal("addresses of imported eth key are wrong: ", ka)
+	}
+
+	err = ioutil.WriteFile(pwFile, []byte("wrong"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = run("--from", admin, "token", "transfer", taddr, other, "100")
+	if err != keystore.ErrPassword {
+		t.Fatal("wrong password should fail: ", err)
+	}
+	err = ioutil.WriteFile(pwFile, []byte("pass"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two keys, the one of --from signs
+	_, err = run("token", "transfer", taddr, other, "100")
+	if err != ErrNoKey {
+		t.Fatal("call with two keys should need --from: ", err)
+	}
+
+	mustRun("--from", admin, "token", "transfer", taddr, other, "100")
+	if mustRun("token", "balance", taddr, other) != "100" {
+		t.Fatal("balance is wrong: ", mustRun("token", "balance", taddr, other))
+	}
+
+	_, err = run("token", "balance", taddr, "0x12")
+	if err == nil {
+		t.Fatal("short address should fail")
+	}
+
+	mustRun("--from", admin, "role", "create-mgr", other, taddr)
+	mustRun("--from", admin, "group", "create", "2")
+
+	var gs []*contract.GroupInfo
+	err = json.Unmarshal([]byte(mustRun("-o", "json", "group", "list")), &gs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gs) != 1 || gs[0].Level != 2 {
+		t.Fatal("group is not created: ", gs)
+	}
+
+	out = mustRun("group", "list")
+	if !strings.HasPrefix(out, "ISACTIVE") {
+		t.Fatal("group table is wrong: ", out)
+	}
+
+	out = mustRun("role", "info", "0")
+	if !strings.Contains(out, "RoleType") {
+		t.Fatal("info table is wrong: ", out)
+	}
+
+	var id uint64
+	err = json.Unmarshal([]byte(mustRun("-o", "json", "chain", "id")), &id)
+	if err != nil || id != utils.DefaultChainID {
+		t.Fatal("chain id is wrong: ", id, err)
+	}
+
+	_, err = run("admin", "propose", "no-such-op")
+	if err == nil {
+		t.Fatal("unknown op should fail")
+	}
+}
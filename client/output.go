@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+)
+
+// printResult writes v to app writer as --output: a table of fields,
+// one row for each item of a list, or indented json
+func printResult(cctx *cli.Context, v interface{}) error {
+	w := cctx.App.Writer
+
+	switch cctx.String("output") {
+	case "json":
+		buf, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(buf))
+		return err
+	case "table":
+	default:
+		return xerrors.Errorf("output %q is not table or json", cctx.String("output"))
+	}
+
+	tw := tabwriter.NewWriter(w, 2, 4, 2, ' ', 0)
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	switch {
+	case isStruct(rv):
+		// one field a row
+		for _, f := range fields(rv) {
+			fmt.Fprintf(tw, "%s\t%s\n", f.name, cell(f.v))
+		}
+	case isList(rv) && rv.Len() > 0 && isStruct(deref(rv.Index(0))):
+		// one item a row, fields are columns
+		names := make([]string, 0)
+		for _, f := range fields(deref(rv.Index(0))) {
+			names = append(names, strings.ToUpper(f.name))
+		}
+		fmt.Fprintln(tw, strings.Join(names, "\t"))
+
+		for i := 0; i < rv.Len(); i++ {
+			row := make([]string, 0, len(names))
+			for _, f := range fields(deref(rv.Index(i))) {
+				row = append(row, cell(f.v))
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+	case isList(rv):
+		for i := 0; i < rv.Len(); i++ {
+			fmt.Fprintf(tw, "%d\t%s\n", i, cell(rv.Index(i)))
+		}
+	default:
+		fmt.Fprintln(tw, cell(rv))
+	}
+
+	return tw.Flush()
+}
+
+func deref(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// isStruct is a struct of fields; big.Int and others which print
+// themselves are values
+func isStruct(v reflect.Value) bool {
+	return v.Kind() == reflect.Struct && !reflect.PtrTo(v.Type()).Implements(stringerType)
+}
+
+type field struct {
+	name string
+	v    reflect.Value
+}
+
+// fields are exported fields of struct v, embedded structs are flattened
+func fields(v reflect.Value) []field {
+	fs := make([]field, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		if sf.Anonymous && isStruct(deref(v.Field(i))) {
+			fs = append(fs, fields(deref(v.Field(i)))...)
+			continue
+		}
+		fs = append(fs, field{name: sf.Name, v: v.Field(i)})
+	}
+	return fs
+}
+
+// isList is a slice, but not bytes
+func isList(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8
+}
+
+// cell is v in one line
+func cell(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.IsNil() {
+		return ""
+	}
+
+	if tm, ok := v.Interface().(encoding.TextMarshaler); ok {
+		buf, err := tm.MarshalText()
+		if err == nil {
+			return string(buf)
+		}
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		return cell(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			buf := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(buf), v)
+			return "0x" + hex.EncodeToString(buf)
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = cell(v.Index(i))
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	case reflect.Struct:
+		items := make([]string, 0, v.NumField())
+		for _, f := range fields(v) {
+			items = append(items, f.name+":"+cell(f.v))
+		}
+		return "{" + strings.Join(items, " ") + "}"
+	}
+
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"math/big"
+
+	"github.com/memoio/go-settlement/server/api"
+	"github.com/memoio/go-settlement/server/api/client"
+	"github.com/memoio/go-settlement/utils"
+	"github.com/urfave/cli/v2"
+)
+
+var signFlag = &cli.StringFlag{
+	Name:  "sign",
+	Usage: "sig of the role in hex, if it is not --from",
+}
+
+var blsFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "bls-key",
+		Usage: "bls public key with its proof of possession in hex",
+	},
+	&cli.StringFlag{
+		Name:  "bls-secret",
+		Usage: "bls secret key in hex, bls-key is made of it",
+	},
+}
+
+// blsKey is blsKey of RegisterKeeper and RegisterUser by flags
+func blsKey(cctx *cli.Context) ([]byte, error) {
+	if cctx.IsSet("bls-secret") {
+		sk, err := parseHex(cctx.String("bls-secret"))
+		if err != nil {
+			return nil, err
+		}
+		return client.BlsKey(sk)
+	}
+	return parseHex(cctx.String("bls-key"))
+}
+
+var roleCmd = &cli.Command{
+	Name:  "role",
+	Usage: "Register roles and pledge in RoleMgr",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "create-mgr",
+			Usage:     "Create RoleMgr admined by --from, token is its primary token",
+			ArgsUsage: "<foundation> <token>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 2)
+				founder, taddr := p.addr(), p.addr()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "CreateRoleMgr", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.CreateRoleMgr(uid, sig, caller, founder, taddr)
+				}, founder, taddr)
+			},
+		},
+		{
+			Name:      "register",
+			Usage:     "Register address to get its index, --from if not given",
+			ArgsUsage: "[address]",
+			Flags:     []cli.Flag{signFlag},
+			Action: func(cctx *cli.Context) error {
+				var addr utils.Address
+				var err error
+				if cctx.Args().Present() {
+					addr, err = parseAddr(cctx.Args().First())
+				} else {
+					addr, err = fromAddress(cctx)
+				}
+				if err != nil {
+					return err
+				}
+
+				psign, err := parseHex(cctx.String("sign"))
+				if err != nil {
+					return err
+				}
+				return send(cctx, "Register", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.Register(uid, sig, caller, addr, psign)
+				}, addr, psign)
+			},
+		},
+		{
+			Name:      "register-token",
+			Usage:     "Register token for payment, by admin",
+			ArgsUsage: "<token>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				taddr := p.addr()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "RegisterToken", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.RegisterToken(uid, sig, caller, taddr)
+				}, taddr)
+			},
+		},
+		{
+			Name:      "register-keeper",
+			Usage:     "Register index as keeper, after pledge of keeper",
+			ArgsUsage: "<index>",
+			Flags:     append([]cli.Flag{signFlag}, blsFlags...),
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				index := p.uint64()
+				ksign := p.hexFlag("sign")
+				if p.err != nil {
+					return p.err
+				}
+				bk, err := blsKey(cctx)
+				if err != nil {
+					return err
+				}
+				return send(cctx, "RegisterKeeper", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.RegisterKeeper(uid, sig, caller, index, bk, ksign)
+				}, index, bk, ksign)
+			},
+		},
+		{
+			Name:      "register-provider",
+			Usage:     "Register index as provider, after pledge of provider",
+			ArgsUsage: "<index>",
+			Flags:     []cli.Flag{signFlag},
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				index := p.uint64()
+				psign := p.hexFlag("sign")
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "RegisterProvider", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.RegisterProvider(uid, sig, caller, index, psign)
+				}, index, psign)
+			},
+		},
+		{
+			Name:      "register-user",
+			Usage:     "Register index as user of an active group",
+			ArgsUsage: "<index> <group>",
+			Flags:     blsFlags,
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 2)
+				index, gIndex := p.uint64(), p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				bk, err := blsKey(cctx)
+				if err != nil {
+					return err
+				}
+				return send(cctx, "RegisterUser", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.RegisterUser(uid, sig, caller, index, gIndex, bk)
+				}, index, gIndex, bk)
+			},
+		},
+		{
+			Name:      "pledge",
+			Usage:     "Pledge amount of primary token for index, approve pledge address first",
+			ArgsUsage: "<index> <amount>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 2)
+				index, money := p.uint64(), p.big()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "Pledge", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.Pledge(uid, sig, caller, index, money)
+				}, index, money)
+			},
+		},
+		{
+			Name:      "withdraw",
+			Usage:     "Withdraw pledge of index in token, amount 0 is all",
+			ArgsUsage: "<index> <token index> <amount>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 3)
+				index, tIndex, money := p.uint64(), p.uint32(), p.big()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "Withdraw", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.Withdraw(uid, sig, caller, index, tIndex, money)
+				}, index, tIndex, money)
+			},
+		},
+		{
+			Name:      "index",
+			Usage:     "Show index of address, --from if not given",
+			ArgsUsage: "[address]",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					addr := caller
+					if cctx.Args().Present() {
+						var err error
+						addr, err = parseAddr(cctx.Args().First())
+						if err != nil {
+							return nil, err
+						}
+					}
+					return a.GetIndex(caller, addr)
+				})
+			},
+		},
+		{
+			Name:      "addr",
+			Usage:     "Show address of index",
+			ArgsUsage: "<index>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				index := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetAddr(caller, index)
+				})
+			},
+		},
+		{
+			Name:      "info",
+			Usage:     "Show role of index",
+			ArgsUsage: "<index>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				index := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetInfo(caller, index)
+				})
+			},
+		},
+		{
+			Name:      "balance",
+			Usage:     "Show pledge of index in each token",
+			ArgsUsage: "<index>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				index := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetBalance(caller, index)
+				})
+			},
+		},
+		{
+			Name:      "token-index",
+			Usage:     "Show index of registered token",
+			ArgsUsage: "<token>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				taddr := p.addr()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetTokenIndex(caller, taddr)
+				})
+			},
+		},
+		{
+			Name:      "token-address",
+			Usage:     "Show address of registered token",
+			ArgsUsage: "<token index>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				tIndex := p.uint32()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetTokenAddress(caller, tIndex)
+				})
+			},
+		},
+		{
+			Name:  "tokens",
+			Usage: "List registered tokens",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetAllTokens(caller), nil
+				})
+			},
+		},
+		{
+			Name:  "addrs",
+			Usage: "List registered addresses, by index",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetAllAddrs(caller), nil
+				})
+			},
+		},
+		{
+			Name:  "foundation",
+			Usage: "Show foundation address",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetFoundation(caller), nil
+				})
+			},
+		},
+		{
+			Name:  "pledge-info",
+			Usage: "Show pledge address, pledge of keeper and provider, and total pledge in each token",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return &pledgeInfo{
+						Address:  a.GetPledgeAddress(caller),
+						Keeper:   a.GetKeeperPledge(caller),
+						Provider: a.GetProviderPledge(caller),
+						Total:    a.GetPledgeBalance(caller),
+					}, nil
+				})
+			},
+		},
+	},
+}
+
+// pledgeInfo is pledge of RoleMgr
+type pledgeInfo struct {
+	Address  utils.Address
+	Keeper   *big.Int
+	Provider *big.Int
+	Total    []*big.Int // by token index
+}
+
+var groupCmd = &cli.Command{
+	Name:  "group",
+	Usage: "Manage groups of keepers and providers",
+	Subcommands: []*cli.Command{
+		{
+			Name:      "create",
+			Usage:     "Create a group needing level keepers, by admin",
+			ArgsUsage: "<level>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				level := p.uint16()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "CreateGroup", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.CreateGroup(uid, sig, caller, level)
+				}, level)
+			},
+		},
+		{
+			Name:      "add-keeper",
+			Usage:     "Add keeper to group, by keeper or admin with sigs of threshold of admins",
+			ArgsUsage: "<index> <group>",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "asign",
+					Usage: "sigs of admins in hex, in order of admins; empty for --from",
+				},
+			},
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 2)
+				index, gIndex := p.uint64(), p.uint64()
+				asigns := p.hexesFlag("asign")
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "AddKeeperToGroup", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.AddKeeperToGroup(uid, sig, caller, index, gIndex, asigns)
+				}, index, gIndex, asigns)
+			},
+		},
+		{
+			Name:      "set-ready",
+			Usage:     "Mark group formed, signed by its keepers",
+			ArgsUsage: "<group>",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "ksign",
+					Usage: "sigs of keepers in hex, in order; or one aggregated BLS sig with bitmap of signers",
+				},
+			},
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				gIndex := p.uint64()
+				ksigns := p.hexesFlag("ksign")
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "SetReady", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.SetReady(uid, sig, caller, gIndex, ksigns)
+				}, gIndex, ksigns)
+			},
+		},
+		{
+			Name:      "add-provider",
+			Usage:     "Add provider to group",
+			ArgsUsage: "<index> <group>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 2)
+				index, gIndex := p.uint64(), p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "AddProviderToGroup", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.AddProviderToGroup(uid, sig, caller, index, gIndex)
+				}, index, gIndex)
+			},
+		},
+		{
+			Name:      "info",
+			Usage:     "Show group",
+			ArgsUsage: "<group>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				gIndex := p.uint64()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetGroupInfo(caller, gIndex)
+				})
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "List all groups",
+			Action: func(cctx *cli.Context) error {
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.GetAllGroups(caller), nil
+				})
+			},
+		},
+	},
+}
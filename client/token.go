@@ -0,0 +1,178 @@
+package main
+
+import (
+	"github.com/memoio/go-settlement/server/api"
+	"github.com/memoio/go-settlement/utils"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+)
+
+var tokenCmd = &cli.Command{
+	Name:  "token",
+	Usage: "Create and move erc20 tokens",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "create",
+			Usage: "Create a token admined by --from, one for each address",
+			Action: func(cctx *cli.Context) error {
+				return send(cctx, "CreateErcToken", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.CreateErcToken(uid, sig, caller)
+				})
+			},
+		},
+		{
+			Name:      "supply",
+			Usage:     "Show total supply of token",
+			ArgsUsage: "<token>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 1)
+				taddr := p.addr()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.TotalSupply(taddr, caller), nil
+				})
+			},
+		},
+		{
+			Name:      "balance",
+			Usage:     "Show balance of owner in token, --from if not given",
+			ArgsUsage: "<token> [owner]",
+			Action: func(cctx *cli.Context) error {
+				if cctx.Args().Len() < 1 || cctx.Args().Len() > 2 {
+					return xerrors.Errorf("need args: %s", cctx.Command.ArgsUsage)
+				}
+				taddr, err := parseAddr(cctx.Args().Get(0))
+				if err != nil {
+					return err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					owner := caller
+					if cctx.Args().Len() == 2 {
+						owner, err = parseAddr(cctx.Args().Get(1))
+						if err != nil {
+							return nil, err
+						}
+					}
+					return a.BalanceOf(taddr, caller, owner), nil
+				})
+			},
+		},
+		{
+			Name:      "allowance",
+			Usage:     "Show amount of owner which spender may transfer",
+			ArgsUsage: "<token> <owner> <spender>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 3)
+				taddr, owner, spender := p.addr(), p.addr(), p.addr()
+				if p.err != nil {
+					return p.err
+				}
+				return query(cctx, func(a api.FullNode, caller utils.Address) (interface{}, error) {
+					return a.Allowance(taddr, caller, owner, spender), nil
+				})
+			},
+		},
+		{
+			Name:      "approve",
+			Usage:     "Allow spender to transfer more of --from",
+			ArgsUsage: "<token> <spender> <amount>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 3)
+				taddr, spender, value := p.addr(), p.addr(), p.big()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "Approve", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.Approve(uid, sig, taddr, caller, spender, value)
+				}, taddr, spender, value)
+			},
+		},
+		{
+			Name:      "transfer",
+			Usage:     "Transfer amount of --from to an address",
+			ArgsUsage: "<token> <to> <amount>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 3)
+				taddr, to, value := p.addr(), p.addr(), p.big()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "Transfer", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.Transfer(uid, sig, taddr, caller, to, value)
+				}, taddr, to, value)
+			},
+		},
+		{
+			Name:      "transfer-from",
+			Usage:     "Transfer amount of an address allowed to --from",
+			ArgsUsage: "<token> <from> <to> <amount>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 4)
+				taddr, from, to, value := p.addr(), p.addr(), p.addr(), p.big()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "TransferFrom", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.TransferFrom(uid, sig, taddr, caller, from, to, value)
+				}, taddr, from, to, value)
+			},
+		},
+		{
+			Name:      "mint",
+			Usage:     "Mint amount to target, by token admin",
+			ArgsUsage: "<token> <target> <amount>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 3)
+				taddr, target, value := p.addr(), p.addr(), p.big()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "MintToken", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.MintToken(uid, sig, taddr, caller, target, value)
+				}, taddr, target, value)
+			},
+		},
+		{
+			Name:      "burn",
+			Usage:     "Burn amount of token admin",
+			ArgsUsage: "<token> <amount>",
+			Action: func(cctx *cli.Context) error {
+				p := newArgs(cctx, 2)
+				taddr, value := p.addr(), p.big()
+				if p.err != nil {
+					return p.err
+				}
+				return send(cctx, "Burn", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.Burn(uid, sig, taddr, caller, value)
+				}, taddr, value)
+			},
+		},
+		{
+			Name:      "airdrop",
+			Usage:     "Transfer amount to each address, by token admin",
+			ArgsUsage: "<token> <amount> <address>...",
+			Action: func(cctx *cli.Context) error {
+				if cctx.Args().Len() < 3 {
+					return xerrors.Errorf("need args: %s", cctx.Command.ArgsUsage)
+				}
+				taddr, err := parseAddr(cctx.Args().Get(0))
+				if err != nil {
+					return err
+				}
+				money, err := parseBig(cctx.Args().Get(1))
+				if err != nil {
+					return err
+				}
+				addrs, err := parseAddrs(cctx.Args().Slice()[2:])
+				if err != nil {
+					return err
+				}
+				return send(cctx, "AirDrop", func(a api.FullNode, uid uint64, sig []byte, caller utils.Address) (interface{}, error) {
+					return a.AirDrop(uid, sig, taddr, caller, addrs, money)
+				}, taddr, addrs, money)
+			},
+		},
+	},
+}
@@ -0,0 +1,92 @@
+package keystore
+
+import (
+	"encoding/json"
+
+	ethks "github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// Keys in go-ethereum keystore files are the same secp256k1 keys as
+// settlement keys, but one key has two addresses: the settlement address
+// (utils.ToAddress) is the last 20 bytes of blake2b-256 of the 64 byte
+// uncompressed pubkey, and the Ethereum address (EthAddress) is the last 20
+// bytes of keccak-256 of the same bytes. Neither address can be computed
+// from the other, only from the pubkey.
+
+// EthAddress is Ethereum address of pubkey pk
+func EthAddress(pk []byte) common.Address {
+	if len(pk) == 65 {
+		pk = pk[1:]
+	}
+	return common.BytesToAddress(crypto.Keccak256(pk)[12:])
+}
+
+// ImportEth decrypts a go-ethereum keystore file by ethPassword, and stores
+// its key with password
+func (ks *KeyStore) ImportEth(keyjson []byte, ethPassword, password string) (utils.Address, error) {
+	key, err := decryptEthKey(keyjson, ethPassword)
+	if err != nil {
+		return utils.NilAddress, err
+	}
+	return ks.store(key, password)
+}
+
+// ExportEth encrypts key of addr into a go-ethereum keystore v3 file by
+// ethPassword, with scrypt N of keystore
+func (ks *KeyStore) ExportEth(addr utils.Address, password, ethPassword string) ([]byte, error) {
+	key, err := ks.Get(addr, password)
+	if err != nil {
+		return nil, err
+	}
+
+	sk, err := crypto.ToECDSA(key.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	return ethks.EncryptKey(&ethks.Key{
+		Id:         id,
+		Address:    crypto.PubkeyToAddress(sk.PublicKey),
+		PrivateKey: sk,
+	}, ethPassword, ks.scryptN, scryptP)
+}
+
+func decryptEthKey(keyjson []byte, password string) (*utils.Key, error) {
+	ek, err := ethks.DecryptKey(keyjson, password)
+	if err != nil {
+		if err == ethks.ErrDecrypt {
+			return nil, ErrPassword
+		}
+		return nil, ErrFormat
+	}
+
+	// old keys may be shorter than 32 bytes
+	key, err := utils.ToKey(math.PaddedBigBytes(ek.PrivateKey.D, 32))
+	if err != nil {
+		return nil, err
+	}
+
+	// address in file is optional, but must be of the key if given
+	var kf struct {
+		Address string `json:"address"`
+	}
+	err = json.Unmarshal(keyjson, &kf)
+	if err != nil {
+		return nil, ErrFormat
+	}
+	if kf.Address != "" && common.HexToAddress(kf.Address) != ek.Address {
+		return nil, ErrFormat
+	}
+
+	return key, nil
+}
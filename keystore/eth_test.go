@@ -0,0 +1,86 @@
+package keystore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	ethks "github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// vectors of go-ethereum accounts/keystore/testdata
+const (
+	ethLightScrypt = `{"address":"45dea0fb0bba44f4fcf290bba71fd57d7117cbb8","crypto":{"cipher":"aes-128-ctr","ciphertext":"b87781948a1befd247bff51ef4063f716cf6c2d3481163e9a8f42e1f9bb74145","cipherparams":{"iv":"dc4926b48a105133d2f16b96833abf1e"},"kdf":"scrypt","kdfparams":{"dklen":32,"n":2,"p":1,"r":8,"salt":"004244bbdc51cadda545b1cfa43cff9ed2ae88e08c61f1479dbb45410722f8f0"},"mac":"39990c1684557447940d4c69e06b1b82b2aceacb43f284df65c956daf3046b85"},"id":"ce541d8d-c79b-40f8-9f8c-20f59616faba","version":3}`
+
+	ethPbkdf2 = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"6087dab2f9fdbbfaddc31a909735c1e6"},"ciphertext":"5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46","kdf":"pbkdf2","kdfparams":{"c":262144,"dklen":32,"prf":"hmac-sha256","salt":"ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"},"mac":"517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+
+	ethShortKey = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"e0c41130a323adc1446fc82f724bca2f"},"ciphertext":"9517cd5bdbe69076f9bf5057248c6c050141e970efa36ce53692d5d59a3984","kdf":"scrypt","kdfparams":{"dklen":32,"n":2,"r":8,"p":1,"salt":"711f816911c92d649fb4c84b047915679933555030b3552c1212609b38208c63"},"mac":"d5e116151c6aa71470e67a7d42c9620c75c4d23229847dcc127794f0732b0db5"},"id":"fecfc4ce-e956-48fd-953b-30f8b52ed66c","version":3}`
+)
+
+func TestEthKey(t *testing.T) {
+	key, err := decryptEthKey([]byte(ethPbkdf2), "testpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(key.SecretKey) != "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d" {
+		t.Fatal("pbkdf2 key is wrong")
+	}
+	if EthAddress(key.PubKey) != common.HexToAddress("0x008aeeda4d805471df9b2a5b0f38a0c3bcba786b") {
+		t.Fatal("eth address is wrong: ", EthAddress(key.PubKey))
+	}
+	if utils.ToAddress(key.PubKey) == utils.Address(EthAddress(key.PubKey)) {
+		t.Fatal("settlement address should differ from eth address")
+	}
+
+	key, err = decryptEthKey([]byte(ethShortKey), "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(key.SecretKey) != "00fa7b3db73dc7dfdf8c5fbdb796d741e4488628c41fc4febd9160a866ba0f35" {
+		t.Fatal("short key is not padded: ", hex.EncodeToString(key.SecretKey))
+	}
+
+	_, err = decryptEthKey([]byte(ethShortKey), "bar")
+	if err != ErrPassword {
+		t.Fatal("wrong password should fail: ", err)
+	}
+
+	ks, err := NewKeyStore(t.TempDir(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := ks.ImportEth([]byte(ethLightScrypt), "", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err = ks.Get(addr, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if EthAddress(key.PubKey) != common.HexToAddress("45dea0fb0bba44f4fcf290bba71fd57d7117cbb8") {
+		t.Fatal("imported key is wrong")
+	}
+
+	_, err = ks.ImportEth([]byte(ethLightScrypt), "", "pass")
+	if err != ErrExist {
+		t.Fatal("import of stored key should fail: ", err)
+	}
+
+	keyjson, err := ks.ExportEth(addr, "pass", "eth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// go-ethereum reads exported file
+	ekey, err := ethks.DecryptKey(keyjson, "eth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(crypto.FromECDSA(ekey.PrivateKey), key.SecretKey) || ekey.Address != EthAddress(key.PubKey) {
+		t.Fatal("exported key is wrong")
+	}
+}
@@ -0,0 +1,289 @@
+// Package keystore keeps secret keys of addresses in a dir, each encrypted
+// by a password with scrypt and AES-GCM.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/memoio/go-settlement/utils"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	version = 1
+
+	// StandardScryptN is N of scrypt for keys in use, about 1s to unlock
+	StandardScryptN = 1 << 18
+	// LightScryptN is N of scrypt for tests
+	LightScryptN = 1 << 12
+
+	scryptR     = 8
+	scryptP     = 1
+	scryptDKLen = 32
+)
+
+var (
+	ErrNoKey    = errors.New("key is not in keystore")
+	ErrExist    = errors.New("key is already in keystore")
+	ErrPassword = errors.New("password is not right")
+	ErrFormat   = errors.New("key file is not supported")
+)
+
+// KeyStore is a dir of key files, each is named by its address
+type KeyStore struct {
+	dir     string
+	scryptN int
+}
+
+type keyFile struct {
+	Version int           `json:"version"`
+	Address utils.Address `json:"address"`
+	Crypto  cryptoParams  `json:"crypto"`
+}
+
+type cryptoParams struct {
+	Cipher     string        `json:"cipher"`
+	Ciphertext hexutil.Bytes `json:"ciphertext"`
+	Nonce      hexutil.Bytes `json:"nonce"`
+	KDF        string        `json:"kdf"`
+	KDFParams  scryptParams  `json:"kdfparams"`
+}
+
+type scryptParams struct {
+	N     int           `json:"n"`
+	R     int           `json:"r"`
+	P     int           `json:"p"`
+	DKLen int           `json:"dklen"`
+	Salt  hexutil.Bytes `json:"salt"`
+}
+
+// NewKeyStore opens keystore in dir, creates it if not exist; new keys are
+// encrypted with scryptN
+func NewKeyStore(dir string, scryptN int) (*KeyStore, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyStore{
+		dir:     dir,
+		scryptN: scryptN,
+	}, nil
+}
+
+func (ks *KeyStore) path(addr utils.Address) string {
+	return filepath.Join(ks.dir, addr.String())
+}
+
+// List returns addresses of keys in keystore
+func (ks *KeyStore) List() ([]utils.Address, error) {
+	fis, err := ioutil.ReadDir(ks.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]utils.Address, 0, len(fis))
+	for _, fi := range fis {
+		var addr utils.Address
+		err := addr.UnmarshalText([]byte(fi.Name()))
+		if err != nil || fi.IsDir() {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+// Has reports whether key of addr is in keystore
+func (ks *KeyStore) Has(addr utils.Address) bool {
+	_, err := os.Stat(ks.path(addr))
+	return err == nil
+}
+
+// NewKey generates a key and stores it with password
+func (ks *KeyStore) NewKey(password string) (utils.Address, error) {
+	key, err := utils.GenerateKey(rand.Reader)
+	if err != nil {
+		return utils.NilAddress, err
+	}
+	return ks.store(key, password)
+}
+
+// Import stores secret key sk with password
+func (ks *KeyStore) Import(sk []byte, password string) (utils.Address, error) {
+	key, err := utils.ToKey(sk)
+	if err != nil {
+		return utils.NilAddress, err
+	}
+	return ks.store(key, password)
+}
+
+// Export returns secret key of addr
+func (ks *KeyStore) Export(addr utils.Address, password string) ([]byte, error) {
+	key, err := ks.Get(addr, password)
+	if err != nil {
+		return nil, err
+	}
+	return key.SecretKey, nil
+}
+
+// Get decrypts key of addr, its SecretKey signs with utils.Sign
+func (ks *KeyStore) Get(addr utils.Address, password string) (*utils.Key, error) {
+	buf, err := ioutil.ReadFile(ks.path(addr))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoKey
+		}
+		return nil, err
+	}
+
+	kf := new(keyFile)
+	err = json.Unmarshal(buf, kf)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := decryptKey(kf, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if kf.Address != addr || utils.ToAddress(key.PubKey) != addr {
+		return nil, ErrFormat
+	}
+
+	return key, nil
+}
+
+// Delete removes key of addr, after password is checked
+func (ks *KeyStore) Delete(addr utils.Address, password string) error {
+	_, err := ks.Get(addr, password)
+	if err != nil {
+		return err
+	}
+	return os.Remove(ks.path(addr))
+}
+
+func (ks *KeyStore) store(key *utils.Key, password string) (utils.Address, error) {
+	addr := utils.ToAddress(key.PubKey)
+	if ks.Has(addr) {
+		return addr, ErrExist
+	}
+
+	kf, err := encryptKey(key, password, ks.scryptN)
+	if err != nil {
+		return addr, err
+	}
+
+	buf, err := json.Marshal(kf)
+	if err != nil {
+		return addr, err
+	}
+
+	// write to a tmp file then rename, no partial key file is left
+	f, err := ioutil.TempFile(ks.dir, "."+addr.String()+".tmp")
+	if err != nil {
+		return addr, err
+	}
+	_, err = f.Write(buf)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return addr, err
+	}
+	err = f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return addr, err
+	}
+
+	return addr, os.Rename(f.Name(), ks.path(addr))
+}
+
+// encryptKey encrypts secret key by aes-256-gcm, the key of which is derived
+// from password by scrypt; address is authenticated as additional data
+func encryptKey(key *utils.Key, password string, scryptN int) (*keyFile, error) {
+	salt := make([]byte, 32)
+	_, err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	kp := scryptParams{
+		N:     scryptN,
+		R:     scryptR,
+		P:     scryptP,
+		DKLen: scryptDKLen,
+		Salt:  salt,
+	}
+
+	aead, err := newAEAD(password, kp)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := utils.ToAddress(key.PubKey)
+
+	return &keyFile{
+		Version: version,
+		Address: addr,
+		Crypto: cryptoParams{
+			Cipher:     "aes-256-gcm",
+			Ciphertext: aead.Seal(nil, nonce, key.SecretKey, addr[:]),
+			Nonce:      nonce,
+			KDF:        "scrypt",
+			KDFParams:  kp,
+		},
+	}, nil
+}
+
+func decryptKey(kf *keyFile, password string) (*utils.Key, error) {
+	if kf.Version != version || kf.Crypto.Cipher != "aes-256-gcm" || kf.Crypto.KDF != "scrypt" || kf.Crypto.KDFParams.DKLen != scryptDKLen {
+		return nil, ErrFormat
+	}
+
+	aead, err := newAEAD(password, kf.Crypto.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(kf.Crypto.Nonce) != aead.NonceSize() {
+		return nil, ErrFormat
+	}
+
+	sk, err := aead.Open(nil, kf.Crypto.Nonce, kf.Crypto.Ciphertext, kf.Address[:])
+	if err != nil {
+		return nil, ErrPassword
+	}
+
+	return utils.ToKey(sk)
+}
+
+func newAEAD(password string, kp scryptParams) (cipher.AEAD, error) {
+	dk, err := scrypt.Key([]byte(password), kp.Salt, kp.N, kp.R, kp.P, kp.DKLen)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(dk)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,123 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestKeyStore(t *testing.T) {
+	ks, err := NewKeyStore(t.TempDir(), LightScryptN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := ks.NewKey("pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ks.Get(addr, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if utils.ToAddress(key.PubKey) != addr {
+		t.Fatal("key is not of address")
+	}
+
+	msg := make([]byte, 32)
+	sig, err := utils.Sign(key.SecretKey, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utils.Verify(addr, msg, sig) {
+		t.Fatal("sig by stored key is wrong")
+	}
+
+	_, err = ks.Get(addr, "other")
+	if err != ErrPassword {
+		t.Fatal("wrong password should fail: ", err)
+	}
+
+	// secret key is not kept in plain
+	buf, err := ioutil.ReadFile(filepath.Join(ks.dir, addr.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf, key.SecretKey) || strings.Contains(string(buf), hex.EncodeToString(key.SecretKey)) {
+		t.Fatal("secret key is in plain")
+	}
+
+	sk, err := ks.Export(addr, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sk, key.SecretKey) {
+		t.Fatal("exported key is wrong")
+	}
+
+	_, err = ks.Import(sk, "pass2")
+	if err != ErrExist {
+		t.Fatal("import of stored key should fail: ", err)
+	}
+
+	other, err := utils.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oaddr, err := ks.Import(other.SecretKey, "pass2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oaddr != utils.ToAddress(other.PubKey) {
+		t.Fatal("imported address is wrong")
+	}
+
+	addrs, err := ks.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 {
+		t.Fatal("list is wrong: ", addrs)
+	}
+
+	err = ks.Delete(addr, "other")
+	if err != ErrPassword {
+		t.Fatal("delete with wrong password should fail: ", err)
+	}
+	err = ks.Delete(addr, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ks.Has(addr) {
+		t.Fatal("key is not deleted")
+	}
+	_, err = ks.Get(addr, "pass")
+	if err != ErrNoKey {
+		t.Fatal("deleted key should not be got: ", err)
+	}
+
+	// key file of one address is not key of another
+	err = copyFile(filepath.Join(ks.dir, oaddr.String()), filepath.Join(ks.dir, addr.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ks.Get(addr, "pass2")
+	if err != ErrFormat {
+		t.Fatal("renamed key file should fail: ", err)
+	}
+}
+
+func copyFile(from, to string) error {
+	buf, err := ioutil.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(to, buf, 0600)
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"github.com/memoio/go-settlement/server/api"
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// SignCall signs call of method at nonce by sk for chain of a;
+// params are arguments of the api method after uid, sig and caller, in order
+func SignCall(a api.FullNode, sk []byte, method string, nonce uint64, params ...interface{}) ([]byte, error) {
+	return utils.SignCall(sk, method, a.ChainID(utils.NilAddress), nonce, params...)
+}
+
+// SignMessage signs m by sk for chain of a
+func SignMessage(a api.FullNode, sk []byte, m *message.Message) (*message.SignedMessage, error) {
+	h, err := m.Digest(a.ChainID(utils.NilAddress))
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := utils.Sign(sk, h[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &message.SignedMessage{
+		Message:   *m,
+		Signature: sig,
+	}, nil
+}
+
+// BlsKey is blsKey of RegisterKeeper and RegisterUser: BLS public key of sk
+// followed by its proof of possession
+func BlsKey(sk []byte) ([]byte, error) {
+	pk, err := utils.BlsPublicKey(sk)
+	if err != nil {
+		return nil, err
+	}
+
+	pop, err := utils.BlsProve(sk)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(pk, pop...), nil
+}
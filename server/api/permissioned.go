@@ -1,9 +1,11 @@
 package api
 
 import (
+	"context"
 	"reflect"
 
 	"github.com/filecoin-project/go-jsonrpc/auth"
+	"golang.org/x/xerrors"
 )
 
 const (
@@ -18,17 +20,73 @@ const (
 var AllPermissions = []auth.Permission{PermRead, PermWrite, PermSign, PermAdmin}
 var DefaultPerms = []auth.Permission{PermRead}
 
-func permissionedProxies(in, out interface{}) {
+// PermissionedFullAPI returns a for caller with perms; a method is called
+// only if perms has its perm tag, else it fails with no permission
+func PermissionedFullAPI(a FullNode, perms []auth.Permission) FullNode {
+	var out FullNodeStruct
+	permissionedProxies(a, &out, perms)
+	return &out
+}
+
+// ContextPerms returns perms put in ctx by auth.Handler, DefaultPerms if none
+func ContextPerms(ctx context.Context) []auth.Permission {
+	var perms []auth.Permission
+	for _, p := range AllPermissions {
+		if auth.HasPerm(ctx, DefaultPerms, p) {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// methods of api take no ctx, so perms are bound when proxy is made,
+// instead of checked per call as auth.PermissionedProxy does
+func permissionedProxies(in, out interface{}, perms []auth.Permission) {
 	outs := GetInternalStructs(out)
 	for _, o := range outs {
-		auth.PermissionedProxy(AllPermissions, DefaultPerms, in, o)
+		permissionedProxy(in, o, perms)
 	}
 }
 
-func PermissionedFullAPI(a FullNode) FullNode {
-	var out FullNodeStruct
-	permissionedProxies(a, &out)
-	return &out
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func permissionedProxy(in, out interface{}, perms []auth.Permission) {
+	rint := reflect.ValueOf(out).Elem()
+	ra := reflect.ValueOf(in)
+
+	for f := 0; f < rint.NumField(); f++ {
+		field := rint.Type().Field(f)
+		requiredPerm := auth.Permission(field.Tag.Get("perm"))
+		if !hasPerm(AllPermissions, requiredPerm) {
+			panic("missing or unknown 'perm' tag on " + field.Name) // ok
+		}
+
+		if hasPerm(perms, requiredPerm) {
+			rint.Field(f).Set(ra.MethodByName(field.Name))
+			continue
+		}
+
+		err := xerrors.Errorf("missing permission to invoke '%s' (need '%s')", field.Name, requiredPerm)
+		rint.Field(f).Set(reflect.MakeFunc(field.Type, func(args []reflect.Value) []reflect.Value {
+			res := make([]reflect.Value, field.Type.NumOut())
+			for i := range res {
+				res[i] = reflect.Zero(field.Type.Out(i))
+			}
+			if n := len(res); n > 0 && field.Type.Out(n-1) == errorType {
+				res[n-1] = reflect.ValueOf(&err).Elem()
+			}
+			return res
+		}))
+	}
+}
+
+func hasPerm(perms []auth.Permission, perm auth.Permission) bool {
+	for _, p := range perms {
+		if p == perm {
+			return true
+		}
+	}
+	return false
 }
 
 var _internalField = "Internal"
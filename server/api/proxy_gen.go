@@ -6,6 +6,8 @@ import (
 
 	"github.com/filecoin-project/go-jsonrpc/auth"
 	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/server/types"
 	"github.com/memoio/go-settlement/utils"
 )
 
@@ -21,53 +23,82 @@ type FullNodeStruct struct {
 	CommonStruct
 
 	Internal struct {
-		GetNonce func(caller, addr utils.Address) uint64
-
-		CreateErcToken func(uid uint64, sig []byte, caller utils.Address) (utils.Address, error)
-		TotalSupply    func(tAddr, caller utils.Address) *big.Int
-		BalanceOf      func(tAddr, caller, tokenOwner utils.Address) *big.Int
-		Allowance      func(tAddr, caller, tokenOwner, spender utils.Address) *big.Int
-		Approve        func(uid uint64, sig []byte, tAddr, caller, spender utils.Address, value *big.Int) error
-		Transfer       func(uid uint64, sig []byte, tAddr, caller, to utils.Address, value *big.Int) error
-		TransferFrom   func(uid uint64, sig []byte, tAddr, caller, from, to utils.Address, value *big.Int) error
-		MintToken      func(uid uint64, sig []byte, tAddr, caller, target utils.Address, mintedAmount *big.Int) error
-		Burn           func(uid uint64, sig []byte, tAddr, caller utils.Address, burnAmount *big.Int) error
-		AirDrop        func(uid uint64, sig []byte, tAddr, caller utils.Address, addrs []utils.Address, money *big.Int) error
-
-		CreateRoleMgr      func(uid uint64, sig []byte, caller, founder, token utils.Address) (utils.Address, error)
-		Register           func(uid uint64, sig []byte, caller, addr utils.Address, sign []byte) error
-		RegisterToken      func(uid uint64, sig []byte, caller, taddr utils.Address) error
-		RegisterKeeper     func(uid uint64, sig []byte, caller utils.Address, index uint64, blsKey, signature []byte) error
-		RegisterProvider   func(uid uint64, sig []byte, caller utils.Address, index uint64, signature []byte) error
-		RegisterUser       func(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, blsKey []byte) error
-		Pledge             func(uid uint64, sig []byte, caller utils.Address, index uint64, money *big.Int) error
-		Withdraw           func(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, money *big.Int) error
-		CreateGroup        func(uid uint64, sig []byte, caller utils.Address, level uint16) error
-		AddKeeperToGroup   func(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, asign []byte) error
-		AddProviderToGroup func(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64) error
-		Recharge           func(uid uint64, sig []byte, caller utils.Address, user uint64, tokenIndex uint32, money *big.Int) error
-		ProWithdraw        func(uid uint64, sig []byte, caller utils.Address, proIndex uint64, tokenIndex uint32, pay, lost *big.Int, ksigns [][]byte) error
-		WithdrawFromFs     func(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, amount *big.Int) error
-		AddOrder           func(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error
-		SubOrder           func(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error
-
-		GetIndex          func(caller, addr utils.Address) (uint64, error)
-		GetAddr           func(caller utils.Address, index uint64) (utils.Address, error)
-		GetInfo           func(caller utils.Address, index uint64) (*contract.BaseInfo, error)
-		GetTokenIndex     func(caller, taddr utils.Address) (uint32, error)
-		GetTokenAddress   func(caller utils.Address, index uint32) (utils.Address, error)
-		GetGroupInfo      func(caller utils.Address, gindex uint64) (*contract.GroupInfo, error)
-		GetBalance        func(caller utils.Address, index uint64) ([]*big.Int, error)
-		GetBalanceInFs    func(caller utils.Address, index uint64, tIndex uint32) ([]*big.Int, error)
-		GetSettleInfo     func(caller utils.Address, index uint64, tIndex uint32) (*contract.Settlement, error)
-		GetPledgeAddress  func(caller utils.Address) utils.Address
-		GetKeeperPledge   func(caller utils.Address) *big.Int
-		GetProviderPledge func(caller utils.Address) *big.Int
-		GetPledgeBalance  func(caller utils.Address) []*big.Int
-		GetAllTokens      func(caller utils.Address) []utils.Address
-		GetAllAddrs       func(caller utils.Address) []utils.Address
-		GetAllGroups      func(caller utils.Address) []*contract.GroupInfo
-		GetFoundation     func(caller utils.Address) utils.Address
+		GetNonce    func(caller, addr utils.Address) uint64                                      `perm:"read"`
+		ChainID     func(caller utils.Address) uint64                                            `perm:"read"`
+		GetGasPrice func(caller utils.Address) *big.Int                                          `perm:"read"`
+		AdvanceTime func(uid uint64, sig []byte, caller utils.Address, d uint64) (uint64, error) `perm:"admin"`
+
+		ChainHead        func(caller utils.Address) (*types.Block, error)                                                        `perm:"read"`
+		GetBlockByHeight func(caller utils.Address, height uint64) (*types.Block, error)                                         `perm:"read"`
+		GetBlockByHash   func(caller utils.Address, h utils.Hash) (*types.Block, error)                                          `perm:"read"`
+		GetReceipt       func(caller utils.Address, tx utils.Hash) (*types.Receipt, error)                                       `perm:"read"`
+		GetEvents        func(caller utils.Address, filter *types.EventFilter) ([]*types.Event, error)                           `perm:"read"`
+		SubscribeHeads   func(ctx context.Context, caller utils.Address) (<-chan *types.Block, error)                            `perm:"read"`
+		SubscribeEvents  func(ctx context.Context, caller utils.Address, filter *types.EventFilter) (<-chan *types.Event, error) `perm:"read"`
+
+		PushMessage  func(sm *message.SignedMessage) (utils.Hash, error)            `perm:"write"`
+		MpoolPending func(caller, addr utils.Address) ([]*types.PendingCall, error) `perm:"read"`
+
+		CreateErcToken func(uid uint64, sig []byte, caller utils.Address) (*types.CreateResult, error)                                      `perm:"write"`
+		TotalSupply    func(tAddr, caller utils.Address) *big.Int                                                                           `perm:"read"`
+		BalanceOf      func(tAddr, caller, tokenOwner utils.Address) *big.Int                                                               `perm:"read"`
+		Allowance      func(tAddr, caller, tokenOwner, spender utils.Address) *big.Int                                                      `perm:"read"`
+		Approve        func(uid uint64, sig []byte, tAddr, caller, spender utils.Address, value *big.Int) (utils.Hash, error)               `perm:"write"`
+		Transfer       func(uid uint64, sig []byte, tAddr, caller, to utils.Address, value *big.Int) (utils.Hash, error)                    `perm:"write"`
+		TransferFrom   func(uid uint64, sig []byte, tAddr, caller, from, to utils.Address, value *big.Int) (utils.Hash, error)              `perm:"write"`
+		MintToken      func(uid uint64, sig []byte, tAddr, caller, target utils.Address, mintedAmount *big.Int) (utils.Hash, error)         `perm:"write"`
+		Burn           func(uid uint64, sig []byte, tAddr, caller utils.Address, burnAmount *big.Int) (utils.Hash, error)                   `perm:"write"`
+		AirDrop        func(uid uint64, sig []byte, tAddr, caller utils.Address, addrs []utils.Address, money *big.Int) (utils.Hash, error) `perm:"write"`
+
+		CreateRoleMgr      func(uid uint64, sig []byte, caller, founder, token utils.Address) (*types.CreateResult, error)                                                                                                  `perm:"write"`
+		Register           func(uid uint64, sig []byte, caller, addr utils.Address, sign []byte) (utils.Hash, error)                                                                                                        `perm:"write"`
+		RegisterToken      func(uid uint64, sig []byte, caller, taddr utils.Address) (utils.Hash, error)                                                                                                                    `perm:"write"`
+		RegisterKeeper     func(uid uint64, sig []byte, caller utils.Address, index uint64, blsKey, signature []byte) (utils.Hash, error)                                                                                   `perm:"write"`
+		RegisterProvider   func(uid uint64, sig []byte, caller utils.Address, index uint64, signature []byte) (utils.Hash, error)                                                                                           `perm:"write"`
+		RegisterUser       func(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, blsKey []byte) (utils.Hash, error)                                                                                      `perm:"write"`
+		Pledge             func(uid uint64, sig []byte, caller utils.Address, index uint64, money *big.Int) (utils.Hash, error)                                                                                             `perm:"write"`
+		Withdraw           func(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, money *big.Int) (utils.Hash, error)                                                                          `perm:"write"`
+		CreateGroup        func(uid uint64, sig []byte, caller utils.Address, level uint16) (utils.Hash, error)                                                                                                             `perm:"write"`
+		Propose            func(uid uint64, sig []byte, caller utils.Address, op uint8, paras *contract.ProposalParas) (*types.ProposeResult, error)                                                                        `perm:"write"`
+		ApproveProposal    func(uid uint64, sig []byte, caller utils.Address, id uint64) (utils.Hash, error)                                                                                                                `perm:"write"`
+		ExecuteProposal    func(uid uint64, sig []byte, caller utils.Address, id uint64) (utils.Hash, error)                                                                                                                `perm:"write"`
+		TransferOwnership  func(uid uint64, sig []byte, caller, caddr, newOwner utils.Address) (utils.Hash, error)                                                                                                          `perm:"write"`
+		AcceptOwnership    func(uid uint64, sig []byte, caller, caddr utils.Address) (utils.Hash, error)                                                                                                                    `perm:"write"`
+		ChangeAddress      func(uid uint64, sig []byte, caller utils.Address, index uint64, newAddr utils.Address, oldSig, newSig []byte) (utils.Hash, error)                                                               `perm:"write"`
+		ConfirmAddress     func(uid uint64, sig []byte, caller utils.Address, index uint64) (utils.Hash, error)                                                                                                             `perm:"write"`
+		CancelAddress      func(uid uint64, sig []byte, caller utils.Address, index uint64) (utils.Hash, error)                                                                                                             `perm:"write"`
+		AddKeeperToGroup   func(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, asigns [][]byte) (utils.Hash, error)                                                                                    `perm:"write"`
+		AddProviderToGroup func(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64) (utils.Hash, error)                                                                                                     `perm:"write"`
+		SetReady           func(uid uint64, sig []byte, caller utils.Address, gIndex uint64, ksigns [][]byte) (utils.Hash, error)                                                                                           `perm:"write"`
+		Recharge           func(uid uint64, sig []byte, caller utils.Address, user uint64, tokenIndex uint32, money *big.Int) (utils.Hash, error)                                                                           `perm:"write"`
+		ProWithdraw        func(uid uint64, sig []byte, caller utils.Address, proIndex uint64, tokenIndex uint32, pay, lost *big.Int, ksigns [][]byte) (utils.Hash, error)                                                  `perm:"write"`
+		WithdrawFromFs     func(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, amount *big.Int) (utils.Hash, error)                                                                         `perm:"write"`
+		AddOrder           func(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) (utils.Hash, error) `perm:"write"`
+		SubOrder           func(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) (utils.Hash, error) `perm:"write"`
+		AddRepair          func(uid uint64, sig []byte, caller utils.Address, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) (utils.Hash, error)      `perm:"write"`
+		SubRepair          func(uid uint64, sig []byte, caller utils.Address, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) (utils.Hash, error)      `perm:"write"`
+
+		GetIndex          func(caller, addr utils.Address) (uint64, error)                                      `perm:"read"`
+		GetAddr           func(caller utils.Address, index uint64) (utils.Address, error)                       `perm:"read"`
+		GetInfo           func(caller utils.Address, index uint64) (*contract.BaseInfo, error)                  `perm:"read"`
+		GetTokenIndex     func(caller, taddr utils.Address) (uint32, error)                                     `perm:"read"`
+		GetTokenAddress   func(caller utils.Address, index uint32) (utils.Address, error)                       `perm:"read"`
+		GetGroupInfo      func(caller utils.Address, gindex uint64) (*contract.GroupInfo, error)                `perm:"read"`
+		GetBalance        func(caller utils.Address, index uint64) ([]*big.Int, error)                          `perm:"read"`
+		GetBalanceInFs    func(caller utils.Address, index uint64, tIndex uint32) ([]*big.Int, error)           `perm:"read"`
+		GetSettleInfo     func(caller utils.Address, index uint64, tIndex uint32) (*contract.Settlement, error) `perm:"read"`
+		GetPledgeAddress  func(caller utils.Address) utils.Address                                              `perm:"read"`
+		GetKeeperPledge   func(caller utils.Address) *big.Int                                                   `perm:"read"`
+		GetProviderPledge func(caller utils.Address) *big.Int                                                   `perm:"read"`
+		GetPledgeBalance  func(caller utils.Address) []*big.Int                                                 `perm:"read"`
+		GetAllTokens      func(caller utils.Address) []utils.Address                                            `perm:"read"`
+		GetAllAddrs       func(caller utils.Address) []utils.Address                                            `perm:"read"`
+		GetAllGroups      func(caller utils.Address) []*contract.GroupInfo                                      `perm:"read"`
+		GetFoundation     func(caller utils.Address) utils.Address                                              `perm:"read"`
+		GetAdmins         func(caller utils.Address) (*contract.AdminInfo, error)                               `perm:"read"`
+		GetProposal       func(caller utils.Address, id uint64) (*contract.Proposal, error)                     `perm:"read"`
+		GetOwnerInfo      func(caller, caddr utils.Address) (*contract.OwnerInfo, error)                        `perm:"read"`
+		GetAddrChange     func(caller utils.Address, index uint64) (*contract.AddrChange, error)                `perm:"read"`
 	}
 }
 
@@ -83,7 +114,55 @@ func (s *FullNodeStruct) GetNonce(caller, addr utils.Address) uint64 {
 	return s.Internal.GetNonce(caller, addr)
 }
 
-func (s *FullNodeStruct) CreateErcToken(uid uint64, sig []byte, caller utils.Address) (utils.Address, error) {
+func (s *FullNodeStruct) ChainID(caller utils.Address) uint64 {
+	return s.Internal.ChainID(caller)
+}
+
+func (s *FullNodeStruct) GetGasPrice(caller utils.Address) *big.Int {
+	return s.Internal.GetGasPrice(caller)
+}
+
+func (s *FullNodeStruct) AdvanceTime(uid uint64, sig []byte, caller utils.Address, d uint64) (uint64, error) {
+	return s.Internal.AdvanceTime(uid, sig, caller, d)
+}
+
+func (s *FullNodeStruct) ChainHead(caller utils.Address) (*types.Block, error) {
+	return s.Internal.ChainHead(caller)
+}
+
+func (s *FullNodeStruct) GetBlockByHeight(caller utils.Address, height uint64) (*types.Block, error) {
+	return s.Internal.GetBlockByHeight(caller, height)
+}
+
+func (s *FullNodeStruct) GetBlockByHash(caller utils.Address, h utils.Hash) (*types.Block, error) {
+	return s.Internal.GetBlockByHash(caller, h)
+}
+
+func (s *FullNodeStruct) GetReceipt(caller utils.Address, tx utils.Hash) (*types.Receipt, error) {
+	return s.Internal.GetReceipt(caller, tx)
+}
+
+func (s *FullNodeStruct) GetEvents(caller utils.Address, filter *types.EventFilter) ([]*types.Event, error) {
+	return s.Internal.GetEvents(caller, filter)
+}
+
+func (s *FullNodeStruct) SubscribeHeads(ctx context.Context, caller utils.Address) (<-chan *types.Block, error) {
+	return s.Internal.SubscribeHeads(ctx, caller)
+}
+
+func (s *FullNodeStruct) SubscribeEvents(ctx context.Context, caller utils.Address, filter *types.EventFilter) (<-chan *types.Event, error) {
+	return s.Internal.SubscribeEvents(ctx, caller, filter)
+}
+
+func (s *FullNodeStruct) PushMessage(sm *message.SignedMessage) (utils.Hash, error) {
+	return s.Internal.PushMessage(sm)
+}
+
+func (s *FullNodeStruct) MpoolPending(caller, addr utils.Address) ([]*types.PendingCall, error) {
+	return s.Internal.MpoolPending(caller, addr)
+}
+
+func (s *FullNodeStruct) CreateErcToken(uid uint64, sig []byte, caller utils.Address) (*types.CreateResult, error) {
 	return s.Internal.CreateErcToken(uid, sig, caller)
 }
 
@@ -99,94 +178,138 @@ func (s *FullNodeStruct) Allowance(tAddr, caller, tokenOwner, spender utils.Addr
 	return s.Internal.Allowance(tAddr, caller, tokenOwner, spender)
 }
 
-func (s *FullNodeStruct) Approve(uid uint64, sig []byte, tAddr, caller, spender utils.Address, value *big.Int) error {
+func (s *FullNodeStruct) Approve(uid uint64, sig []byte, tAddr, caller, spender utils.Address, value *big.Int) (utils.Hash, error) {
 	return s.Internal.Approve(uid, sig, tAddr, caller, spender, value)
 }
 
-func (s *FullNodeStruct) Transfer(uid uint64, sig []byte, tAddr, caller, to utils.Address, value *big.Int) error {
+func (s *FullNodeStruct) Transfer(uid uint64, sig []byte, tAddr, caller, to utils.Address, value *big.Int) (utils.Hash, error) {
 	return s.Internal.Transfer(uid, sig, tAddr, caller, to, value)
 }
 
-func (s *FullNodeStruct) TransferFrom(uid uint64, sig []byte, tAddr, caller, from, to utils.Address, value *big.Int) error {
+func (s *FullNodeStruct) TransferFrom(uid uint64, sig []byte, tAddr, caller, from, to utils.Address, value *big.Int) (utils.Hash, error) {
 	return s.Internal.TransferFrom(uid, sig, tAddr, caller, from, to, value)
 }
 
-func (s *FullNodeStruct) MintToken(uid uint64, sig []byte, tAddr, caller, target utils.Address, mintedAmount *big.Int) error {
+func (s *FullNodeStruct) MintToken(uid uint64, sig []byte, tAddr, caller, target utils.Address, mintedAmount *big.Int) (utils.Hash, error) {
 	return s.Internal.MintToken(uid, sig, tAddr, caller, target, mintedAmount)
 }
 
-func (s *FullNodeStruct) Burn(uid uint64, sig []byte, tAddr, caller utils.Address, burnAmount *big.Int) error {
+func (s *FullNodeStruct) Burn(uid uint64, sig []byte, tAddr, caller utils.Address, burnAmount *big.Int) (utils.Hash, error) {
 	return s.Internal.Burn(uid, sig, tAddr, caller, burnAmount)
 }
 
-func (s *FullNodeStruct) AirDrop(uid uint64, sig []byte, tAddr, caller utils.Address, addrs []utils.Address, money *big.Int) error {
+func (s *FullNodeStruct) AirDrop(uid uint64, sig []byte, tAddr, caller utils.Address, addrs []utils.Address, money *big.Int) (utils.Hash, error) {
 	return s.Internal.AirDrop(uid, sig, tAddr, caller, addrs, money)
 }
 
-func (s *FullNodeStruct) CreateRoleMgr(uid uint64, sig []byte, caller, founder, token utils.Address) (utils.Address, error) {
+func (s *FullNodeStruct) CreateRoleMgr(uid uint64, sig []byte, caller, founder, token utils.Address) (*types.CreateResult, error) {
 	return s.Internal.CreateRoleMgr(uid, sig, caller, founder, token)
 }
 
-func (s *FullNodeStruct) Register(uid uint64, sig []byte, caller, addr utils.Address, sign []byte) error {
+func (s *FullNodeStruct) Register(uid uint64, sig []byte, caller, addr utils.Address, sign []byte) (utils.Hash, error) {
 	return s.Internal.Register(uid, sig, caller, addr, sign)
 }
 
-func (s *FullNodeStruct) RegisterToken(uid uint64, sig []byte, caller, taddr utils.Address) error {
+func (s *FullNodeStruct) RegisterToken(uid uint64, sig []byte, caller, taddr utils.Address) (utils.Hash, error) {
 	return s.Internal.RegisterToken(uid, sig, caller, taddr)
 }
 
-func (s *FullNodeStruct) RegisterKeeper(uid uint64, sig []byte, caller utils.Address, index uint64, blsKey, signature []byte) error {
+func (s *FullNodeStruct) RegisterKeeper(uid uint64, sig []byte, caller utils.Address, index uint64, blsKey, signature []byte) (utils.Hash, error) {
 	return s.Internal.RegisterKeeper(uid, sig, caller, index, blsKey, signature)
 }
 
-func (s *FullNodeStruct) RegisterProvider(uid uint64, sig []byte, caller utils.Address, index uint64, signature []byte) error {
+func (s *FullNodeStruct) RegisterProvider(uid uint64, sig []byte, caller utils.Address, index uint64, signature []byte) (utils.Hash, error) {
 	return s.Internal.RegisterProvider(uid, sig, caller, index, signature)
 }
 
-func (s *FullNodeStruct) RegisterUser(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, blsKey []byte) error {
+func (s *FullNodeStruct) RegisterUser(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, blsKey []byte) (utils.Hash, error) {
 	return s.Internal.RegisterUser(uid, sig, caller, index, gIndex, blsKey)
 }
 
-func (s *FullNodeStruct) Pledge(uid uint64, sig []byte, caller utils.Address, index uint64, money *big.Int) error {
+func (s *FullNodeStruct) Pledge(uid uint64, sig []byte, caller utils.Address, index uint64, money *big.Int) (utils.Hash, error) {
 	return s.Internal.Pledge(uid, sig, caller, index, money)
 }
 
-func (s *FullNodeStruct) Withdraw(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, money *big.Int) error {
+func (s *FullNodeStruct) Withdraw(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, money *big.Int) (utils.Hash, error) {
 	return s.Internal.Withdraw(uid, sig, caller, index, tokenIndex, money)
 }
 
-func (s *FullNodeStruct) CreateGroup(uid uint64, sig []byte, caller utils.Address, level uint16) error {
+func (s *FullNodeStruct) CreateGroup(uid uint64, sig []byte, caller utils.Address, level uint16) (utils.Hash, error) {
 	return s.Internal.CreateGroup(uid, sig, caller, level)
 }
 
-func (s *FullNodeStruct) AddKeeperToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, asign []byte) error {
-	return s.Internal.AddKeeperToGroup(uid, sig, caller, index, gIndex, asign)
+func (s *FullNodeStruct) Propose(uid uint64, sig []byte, caller utils.Address, op uint8, paras *contract.ProposalParas) (*types.ProposeResult, error) {
+	return s.Internal.Propose(uid, sig, caller, op, paras)
+}
+
+func (s *FullNodeStruct) ApproveProposal(uid uint64, sig []byte, caller utils.Address, id uint64) (utils.Hash, error) {
+	return s.Internal.ApproveProposal(uid, sig, caller, id)
+}
+
+func (s *FullNodeStruct) ExecuteProposal(uid uint64, sig []byte, caller utils.Address, id uint64) (utils.Hash, error) {
+	return s.Internal.ExecuteProposal(uid, sig, caller, id)
+}
+
+func (s *FullNodeStruct) TransferOwnership(uid uint64, sig []byte, caller, caddr, newOwner utils.Address) (utils.Hash, error) {
+	return s.Internal.TransferOwnership(uid, sig, caller, caddr, newOwner)
+}
+
+func (s *FullNodeStruct) AcceptOwnership(uid uint64, sig []byte, caller, caddr utils.Address) (utils.Hash, error) {
+	return s.Internal.AcceptOwnership(uid, sig, caller, caddr)
+}
+
+func (s *FullNodeStruct) ChangeAddress(uid uint64, sig []byte, caller utils.Address, index uint64, newAddr utils.Address, oldSig, newSig []byte) (utils.Hash, error) {
+	return s.Internal.ChangeAddress(uid, sig, caller, index, newAddr, oldSig, newSig)
 }
 
-func (s *FullNodeStruct) AddProviderToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64) error {
+func (s *FullNodeStruct) ConfirmAddress(uid uint64, sig []byte, caller utils.Address, index uint64) (utils.Hash, error) {
+	return s.Internal.ConfirmAddress(uid, sig, caller, index)
+}
+
+func (s *FullNodeStruct) CancelAddress(uid uint64, sig []byte, caller utils.Address, index uint64) (utils.Hash, error) {
+	return s.Internal.CancelAddress(uid, sig, caller, index)
+}
+
+func (s *FullNodeStruct) AddKeeperToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, asigns [][]byte) (utils.Hash, error) {
+	return s.Internal.AddKeeperToGroup(uid, sig, caller, index, gIndex, asigns)
+}
+
+func (s *FullNodeStruct) AddProviderToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64) (utils.Hash, error) {
 	return s.Internal.AddProviderToGroup(uid, sig, caller, index, gIndex)
 }
 
-func (s *FullNodeStruct) Recharge(uid uint64, sig []byte, caller utils.Address, user uint64, tokenIndex uint32, money *big.Int) error {
+func (s *FullNodeStruct) SetReady(uid uint64, sig []byte, caller utils.Address, gIndex uint64, ksigns [][]byte) (utils.Hash, error) {
+	return s.Internal.SetReady(uid, sig, caller, gIndex, ksigns)
+}
+
+func (s *FullNodeStruct) Recharge(uid uint64, sig []byte, caller utils.Address, user uint64, tokenIndex uint32, money *big.Int) (utils.Hash, error) {
 	return s.Internal.Recharge(uid, sig, caller, user, tokenIndex, money)
 }
 
-func (s *FullNodeStruct) ProWithdraw(uid uint64, sig []byte, caller utils.Address, proIndex uint64, tokenIndex uint32, pay, lost *big.Int, ksigns [][]byte) error {
+func (s *FullNodeStruct) ProWithdraw(uid uint64, sig []byte, caller utils.Address, proIndex uint64, tokenIndex uint32, pay, lost *big.Int, ksigns [][]byte) (utils.Hash, error) {
 	return s.Internal.ProWithdraw(uid, sig, caller, proIndex, tokenIndex, pay, lost, ksigns)
 }
 
-func (s *FullNodeStruct) WithdrawFromFs(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, amount *big.Int) error {
+func (s *FullNodeStruct) WithdrawFromFs(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, amount *big.Int) (utils.Hash, error) {
 	return s.Internal.WithdrawFromFs(uid, sig, caller, index, tokenIndex, amount)
 }
 
-func (s *FullNodeStruct) AddOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error {
+func (s *FullNodeStruct) AddOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) (utils.Hash, error) {
 	return s.Internal.AddOrder(uid, sig, caller, user, proIndex, start, end, size, nonce, tokenIndex, sprice, usign, psign, ksigns)
 }
 
-func (s *FullNodeStruct) SubOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error {
+func (s *FullNodeStruct) SubOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) (utils.Hash, error) {
 	return s.Internal.SubOrder(uid, sig, caller, user, proIndex, start, end, size, nonce, tokenIndex, sprice, usign, psign, ksigns)
 }
 
+func (s *FullNodeStruct) AddRepair(uid uint64, sig []byte, caller utils.Address, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) (utils.Hash, error) {
+	return s.Internal.AddRepair(uid, sig, caller, proIndex, newPro, start, end, size, nonce, tokenIndex, sprice, psign, ksigns)
+}
+
+func (s *FullNodeStruct) SubRepair(uid uint64, sig []byte, caller utils.Address, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) (utils.Hash, error) {
+	return s.Internal.SubRepair(uid, sig, caller, proIndex, newPro, start, end, size, nonce, tokenIndex, sprice, psign, ksigns)
+}
+
 func (s *FullNodeStruct) GetIndex(caller, addr utils.Address) (uint64, error) {
 	return s.Internal.GetIndex(caller, addr)
 }
@@ -254,3 +377,19 @@ func (s *FullNodeStruct) GetAllGroups(caller utils.Address) []*contract.GroupInf
 func (s *FullNodeStruct) GetFoundation(caller utils.Address) utils.Address {
 	return s.Internal.GetFoundation(caller)
 }
+
+func (s *FullNodeStruct) GetAdmins(caller utils.Address) (*contract.AdminInfo, error) {
+	return s.Internal.GetAdmins(caller)
+}
+
+func (s *FullNodeStruct) GetProposal(caller utils.Address, id uint64) (*contract.Proposal, error) {
+	return s.Internal.GetProposal(caller, id)
+}
+
+func (s *FullNodeStruct) GetOwnerInfo(caller, caddr utils.Address) (*contract.OwnerInfo, error) {
+	return s.Internal.GetOwnerInfo(caller, caddr)
+}
+
+func (s *FullNodeStruct) GetAddrChange(caller utils.Address, index uint64) (*contract.AddrChange, error) {
+	return s.Internal.GetAddrChange(caller, index)
+}
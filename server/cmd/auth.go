@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/memoio/go-settlement/server/api"
+	"github.com/memoio/go-settlement/server/impl/common"
+	"github.com/mitchellh/go-homedir"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
+)
+
+// secret of api tokens in repo, made at first use
+const secretFile = "api.secret"
+
+func loadSecret(repoDir string) ([]byte, error) {
+	p := filepath.Join(repoDir, secretFile)
+	secret, err := ioutil.ReadFile(p)
+	if err == nil {
+		if len(secret) < 32 {
+			return nil, xerrors.Errorf("api secret %s is too short", p)
+		}
+		return secret, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	secret = make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(repoDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(p, secret, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// permsUpTo returns perm and all perms below it, as api.AllPermissions
+// is ordered from read to admin
+func permsUpTo(perm string) ([]auth.Permission, error) {
+	for i, p := range api.AllPermissions {
+		if string(p) == perm {
+			return api.AllPermissions[:i+1], nil
+		}
+	}
+	return nil, xerrors.Errorf("perm %q is not one of %v", perm, api.AllPermissions)
+}
+
+var authCmd = &cli.Command{
+	Name:  "auth",
+	Usage: "Manage api tokens of the server",
+	Subcommands: []*cli.Command{
+		authCreateTokenCmd,
+	},
+}
+
+var authCreateTokenCmd = &cli.Command{
+	Name:  "create-token",
+	Usage: "Create an api token with perm and all perms below it",
+	Flags: []cli.Flag{
+		repoFlag,
+		&cli.StringFlag{
+			Name:     "perm",
+			Usage:    "permission of token: read, write, sign or admin",
+			Required: true,
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		perms, err := permsUpTo(cctx.String("perm"))
+		if err != nil {
+			return err
+		}
+
+		repoDir, err := homedir.Expand(cctx.String("repo"))
+		if err != nil {
+			return err
+		}
+
+		secret, err := loadSecret(repoDir)
+		if err != nil {
+			return err
+		}
+
+		token, err := common.NewCommonAPI(secret).AuthNew(cctx.Context, perms)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(token))
+		return nil
+	},
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/memoio/go-settlement/server/config"
+	"github.com/mitchellh/go-homedir"
+	"github.com/urfave/cli/v2"
+)
+
+// flags overriding config, of settle init and settle run
+var configFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "listen",
+		Usage: "multiaddr of rpc",
+	},
+	&cli.StringFlag{
+		Name:  "datastore",
+		Usage: "datastore path, relative to repo if not absolute",
+	},
+	&cli.StringFlag{
+		Name:  "log-level",
+		Usage: "debug, info, warn or error",
+	},
+	&cli.StringFlag{
+		Name:  "log-output",
+		Usage: "stdout, stderr, or a log dir relative to repo if not absolute",
+	},
+	&cli.BoolFlag{
+		Name:  "auth",
+		Usage: "check api token of each rpc call by its perm; no token has read perm only",
+	},
+	&cli.Uint64Flag{
+		Name:  "chain-id",
+		Usage: "chain id of a new chain, it is kept in genesis; 0 uses the stored or default one",
+	},
+	&cli.Uint64Flag{
+		Name:  "keeper-deposit",
+		Usage: "pledge of keeper in Token, used when RoleMgr is created",
+	},
+	&cli.Uint64Flag{
+		Name:  "provider-deposit",
+		Usage: "pledge of provider in Token, used when RoleMgr is created",
+	},
+	&cli.Uint64Flag{
+		Name:  "gas-price",
+		Usage: "fee per gas of calls, in smallest unit of fee token; 0 is free",
+	},
+	&cli.StringFlag{
+		Name:  "fee-token",
+		Usage: "token of gas fee, registered in RoleMgr; primary token if not set",
+	},
+}
+
+var repoFlag = &cli.StringFlag{
+	Name:    "repo",
+	Usage:   "repo directory, config and state are kept in it",
+	EnvVars: []string{"SETTLE_REPO"},
+	Value:   "~/.memo",
+}
+
+// loadConfig reads config in repo, or default if there is none,
+// then overrides it by flags set
+func loadConfig(cctx *cli.Context, repoDir string) (*config.Config, error) {
+	cfg := config.Default()
+
+	p := filepath.Join(repoDir, config.FileName)
+	_, err := os.Stat(p)
+	if err == nil {
+		cfg, err = config.Load(p)
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if cctx.IsSet("listen") {
+		cfg.API.ListenAddress = cctx.String("listen")
+	}
+	if cctx.IsSet("auth") {
+		cfg.API.Auth = cctx.Bool("auth")
+	}
+	if cctx.IsSet("datastore") {
+		cfg.Repo.Datastore = cctx.String("datastore")
+	}
+	if cctx.IsSet("log-level") {
+		cfg.Log.Level = cctx.String("log-level")
+	}
+	if cctx.IsSet("log-output") {
+		cfg.Log.Output = cctx.String("log-output")
+	}
+	if cctx.IsSet("chain-id") {
+		cfg.Chain.ChainID = cctx.Uint64("chain-id")
+	}
+	if cctx.IsSet("keeper-deposit") {
+		cfg.Chain.KeeperDeposit = cctx.Uint64("keeper-deposit")
+	}
+	if cctx.IsSet("provider-deposit") {
+		cfg.Chain.ProviderDeposit = cctx.Uint64("provider-deposit")
+	}
+	if cctx.IsSet("gas-price") {
+		cfg.Chain.GasPrice = cctx.Uint64("gas-price")
+	}
+	if cctx.IsSet("fee-token") {
+		err := cfg.Chain.FeeToken.UnmarshalText([]byte(cctx.String("fee-token")))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// repoPath returns p under repoDir if it is relative
+func repoPath(repoDir, p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(repoDir, p)
+}
+
+var initCmd = &cli.Command{
+	Name:  "init",
+	Usage: "Create repo with config and api secret",
+	Flags: append([]cli.Flag{repoFlag}, configFlags...),
+	Action: func(cctx *cli.Context) error {
+		repoDir, err := homedir.Expand(cctx.String("repo"))
+		if err != nil {
+			return err
+		}
+
+		cfg, err := loadConfig(cctx, repoDir)
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(repoDir, 0755)
+		if err != nil {
+			return err
+		}
+
+		p := filepath.Join(repoDir, config.FileName)
+		err = cfg.Write(p)
+		if err != nil {
+			return err
+		}
+
+		_, err = loadSecret(repoDir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("repo is created at", repoDir)
+		return nil
+	},
+}
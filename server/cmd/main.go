@@ -1,21 +1,32 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
+	"time"
 
+	"github.com/memoio/go-settlement/server/contract"
 	"github.com/memoio/go-settlement/server/impl"
+	"github.com/memoio/go-settlement/server/impl/node"
+	"github.com/memoio/go-settlement/server/store"
 	"github.com/memoio/go-settlement/utils"
+	"github.com/mitchellh/go-homedir"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/xerrors"
 )
 
 var log = utils.Logger("main")
 
 func main() {
 	local := []*cli.Command{
+		initCmd,
 		runCmd,
-		createCmd,
+		replayCmd,
+		authCmd,
 	}
 
 	app := &cli.App{
@@ -39,18 +50,98 @@ func main() {
 var runCmd = &cli.Command{
 	Name:  "run",
 	Usage: "Start settlement server",
-	Flags: []cli.Flag{},
+	Flags: append([]cli.Flag{
+		repoFlag,
+		&cli.BoolFlag{
+			Name:  "mock-clock",
+			Usage: "use a clock which only moves by AdvanceTime, for dev node",
+		},
+		&cli.StringFlag{
+			Name:  "genesis",
+			Usage: "genesis spec in json, applied to a new chain; a chain made otherwise is rejected",
+		},
+	}, configFlags...),
 	Action: func(cctx *cli.Context) error {
+		repoDir, err := homedir.Expand(cctx.String("repo"))
+		if err != nil {
+			return err
+		}
+
+		cfg, err := loadConfig(cctx, repoDir)
+		if err != nil {
+			return err
+		}
+
+		output := cfg.Log.Output
+		if output != "stdout" && output != "stderr" {
+			output = repoPath(repoDir, output)
+		}
+		err = utils.SetupLogger(cfg.Log.Level, output)
+		if err != nil {
+			return err
+		}
+
 		log.Info("Starting server")
-		fullapi := impl.New()
 
-		h, err := FullNodeHandler(fullapi, false)
+		ds, err := store.NewLevelStore(repoPath(repoDir, cfg.Repo.Datastore))
+		if err != nil {
+			log.Errorf("failed to open datastore: %s", err)
+			return err
+		}
+
+		if cctx.IsSet("genesis") {
+			spec, err := readGenesis(cctx.String("genesis"), cfg.Chain.ChainID)
+			if err != nil {
+				log.Errorf("failed to read genesis: %s", err)
+				return err
+			}
+
+			_, err = node.ApplyGenesis(ds, spec)
+			if err != nil {
+				log.Errorf("failed to apply genesis: %s", err)
+				return err
+			}
+		} else if cfg.Chain.ChainID > 0 {
+			_, err = node.InitGenesis(ds, cfg.Chain.ChainID)
+			if err != nil {
+				log.Errorf("failed to init genesis: %s", err)
+				return err
+			}
+		}
+
+		var clk contract.Clock
+		if cctx.Bool("mock-clock") {
+			log.Info("use mock clock")
+			clk = contract.NewMockClock(uint64(time.Now().Unix()))
+		}
+
+		secret, err := loadSecret(repoDir)
+		if err != nil {
+			log.Errorf("failed to load api secret: %s", err)
+			return err
+		}
+
+		n, err := node.NewNode(ds, clk)
+		if err != nil {
+			log.Errorf("failed to load node: %s", err)
+			return err
+		}
+		n.SetDeposit(tokens(cfg.Chain.KeeperDeposit), tokens(cfg.Chain.ProviderDeposit))
+		n.SetFeeToken(cfg.Chain.FeeToken)
+		n.SetGasPrice(new(big.Int).SetUint64(cfg.Chain.GasPrice))
+
+		ctx, cancel := context.WithCancel(cctx.Context)
+		defer cancel()
+
+		fullapi := impl.New(ctx, n, secret)
+
+		h, err := FullNodeHandler(fullapi, cfg.API.Auth)
 		if err != nil {
 			log.Errorf("failed to instantiate rpc handler: %s", err)
 			return err
 		}
 
-		endpoint, err := multiaddr.NewMultiaddr("/ip4/0.0.0.0/tcp/18000")
+		endpoint, err := multiaddr.NewMultiaddr(cfg.API.ListenAddress)
 		if err != nil {
 			return err
 		}
@@ -66,9 +157,41 @@ var runCmd = &cli.Command{
 
 		finishCh := impl.MonitorShutdown(shutdownChan,
 			impl.ShutdownHandler{Component: "rpc server", StopFunc: rpcStopper},
+			impl.ShutdownHandler{Component: "block producer", StopFunc: func(context.Context) error { cancel(); return nil }},
+			impl.ShutdownHandler{Component: "datastore", StopFunc: func(context.Context) error { return ds.Close() }},
 		)
 		<-finishCh
 
 		return nil
 	},
 }
+
+// readGenesis reads spec at path; chainID of config is used if spec has none
+func readGenesis(path string, chainID uint64) (*node.GenesisSpec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	spec := new(node.GenesisSpec)
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	err = dec.Decode(spec)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to parse genesis %s: %w", path, err)
+	}
+
+	if spec.ChainID == 0 {
+		spec.ChainID = chainID
+	} else if chainID > 0 && chainID != spec.ChainID {
+		return nil, node.ErrChainID
+	}
+
+	return spec, nil
+}
+
+// tokens is amount of v Token
+func tokens(v uint64) *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(v), new(big.Int).SetUint64(contract.Token))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/memoio/go-settlement/server/impl/node"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/mitchellh/go-homedir"
+	"github.com/urfave/cli/v2"
+)
+
+// prefixes of state rebuilt by replay; journal is kept
+var statePrefixes = [][]byte{
+	[]byte("contract/"),
+	[]byte("node/meta"),
+	[]byte("block/"),
+	[]byte("blockhash/"),
+	[]byte("receipt/"),
+	[]byte("event/"),
+}
+
+var replayCmd = &cli.Command{
+	Name:  "replay",
+	Usage: "Rebuild state by executing the journal from the start",
+	Flags: []cli.Flag{
+		repoFlag,
+		&cli.Uint64Flag{
+			Name:  "to",
+			Usage: "stop after this journal seq, state is not written back",
+		},
+		&cli.BoolFlag{
+			Name:  "verbose",
+			Usage: "print each replayed call and its result",
+		},
+	},
+	Action: func(cctx *cli.Context) error {
+		repoDir, err := homedir.Expand(cctx.String("repo"))
+		if err != nil {
+			return err
+		}
+
+		cfg, err := loadConfig(cctx, repoDir)
+		if err != nil {
+			return err
+		}
+
+		ds, err := store.NewLevelStore(repoPath(repoDir, cfg.Repo.Datastore))
+		if err != nil {
+			return err
+		}
+		defer ds.Close()
+
+		// build on memory first, datastore is untouched if replay fails
+		mds := store.NewMemStore()
+		spec, err := node.LoadGenesisSpec(ds)
+		if err == nil {
+			// genesis state is made by spec again
+			_, err = node.ApplyGenesis(mds, spec)
+		} else if err == store.ErrNotFound {
+			var g *types.Block
+			g, err = node.LoadGenesis(ds)
+			if err == nil {
+				_, err = node.InitGenesis(mds, g.ChainID)
+			}
+		}
+		if err != nil && err != node.ErrBlock {
+			return err
+		}
+
+		n, err := node.NewNode(mds, nil)
+		if err != nil {
+			return err
+		}
+		n.SetDeposit(tokens(cfg.Chain.KeeperDeposit), tokens(cfg.Chain.ProviderDeposit))
+		n.SetFeeToken(cfg.Chain.FeeToken)
+
+		to := cctx.Uint64("to")
+		verbose := cctx.Bool("verbose")
+		cnt := 0
+		failed := 0
+		err = n.Replay(ds, to, func(seq uint64, c *node.Call, err error) {
+			cnt++
+			if err != nil {
+				failed++
+			}
+
+			if verbose {
+				res := "ok"
+				if err != nil {
+					res = err.Error()
+				}
+				fmt.Printf("%d %d %s %s nonce %d: %s\n", seq, c.Time, c.Caller, c.Method, c.Uid, res)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("replayed %d calls, %d failed\n", cnt, failed)
+
+		if to > 0 {
+			return nil
+		}
+
+		b := ds.NewBatch()
+		for _, prefix := range statePrefixes {
+			err = ds.Iter(prefix, func(key, value []byte) error {
+				b.Delete(append([]byte(nil), key...))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			err = mds.Iter(prefix, func(key, value []byte) error {
+				b.Put(append([]byte(nil), key...), append([]byte(nil), value...))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = b.Commit()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("state is rebuilt from journal")
+
+		return nil
+	},
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"net/http"
 	_ "net/http/pprof"
+	"strings"
+	"sync"
 
 	jsonrpc "github.com/filecoin-project/go-jsonrpc"
 	"github.com/filecoin-project/go-jsonrpc/auth"
@@ -39,24 +41,56 @@ func ServeRPC(h http.Handler, id string, addr ma.Multiaddr) (impl.StopFunc, erro
 }
 
 func FullNodeHandler(a api.FullNode, permissioned bool) (http.Handler, error) {
-	m := mux.NewRouter()
+	if !permissioned {
+		return rpcHandler(a), nil
+	}
+
+	ph := &permHandler{
+		a:        a,
+		handlers: make(map[string]http.Handler),
+	}
 
-	if permissioned {
-		a = api.PermissionedFullAPI(a)
+	ah := &auth.Handler{
+		Verify: a.AuthVerify,
+		Next:   ph.ServeHTTP,
 	}
+	return ah, nil
+}
+
+func rpcHandler(a api.FullNode) http.Handler {
+	m := mux.NewRouter()
 
 	rpcServer := jsonrpc.NewServer()
 	rpcServer.Register("Memoriae", a)
 
 	m.Handle("/rpc/v0", rpcServer)
+	return m
+}
 
-	if !permissioned {
-		return m, nil
+// permHandler serves each request by rpc handler of its perms,
+// so a websocket connection keeps perms of its token
+type permHandler struct {
+	sync.Mutex
+	a        api.FullNode
+	handlers map[string]http.Handler // by perms
+}
+
+func (ph *permHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	perms := api.ContextPerms(r.Context())
+
+	ps := make([]string, len(perms))
+	for i, p := range perms {
+		ps[i] = string(p)
 	}
+	key := strings.Join(ps, ",")
 
-	ah := &auth.Handler{
-		Verify: a.AuthVerify,
-		Next:   m.ServeHTTP,
+	ph.Lock()
+	h, ok := ph.handlers[key]
+	if !ok {
+		h = rpcHandler(api.PermissionedFullAPI(ph.a, perms))
+		ph.handlers[key] = h
 	}
-	return ah, nil
+	ph.Unlock()
+
+	h.ServeHTTP(w, r)
 }
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/api"
+	"github.com/memoio/go-settlement/server/api/client"
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/impl"
+	"github.com/memoio/go-settlement/server/impl/node"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestAuth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	secret, err := loadSecret(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := node.NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fullapi := impl.New(ctx, n, secret)
+
+	h, err := FullNodeHandler(fullapi, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	connect := func(perm string) api.FullNode {
+		headers := http.Header{}
+		if perm != "" {
+			perms, err := permsUpTo(perm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := fullapi.AuthNew(ctx, perms)
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers.Add("Authorization", "Bearer "+string(token))
+		}
+
+		a, closer, err := client.NewFullNodeRPC(ctx, srv.URL+"/rpc/v0", headers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(closer)
+		return a
+	}
+
+	// no token reads
+	a := connect("")
+	if a.ChainID(utils.NilAddress) != utils.DefaultChainID {
+		t.Fatal("read without token fails")
+	}
+
+	// admin of RoleMgr signs advance
+	key, err := utils.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := utils.ToAddress(key.PubKey)
+	signCall := func(method string, params ...interface{}) (uint64, []byte) {
+		uid := n.GetNonce(admin, admin)
+		sig, err := utils.SignCall(key.SecretKey, method, utils.DefaultChainID, uid, params...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uid, sig
+	}
+
+	uid, sig := signCall("CreateErcToken")
+	et, err := n.CreateErcToken(uid, sig, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	taddr := et.Addr
+	uid, sig = signCall("CreateRoleMgr", admin, taddr)
+	_, err = n.CreateRoleMgr(uid, sig, admin, admin, taddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid, sig = signCall("AdvanceTime", uint64(10))
+	_, err = a.AdvanceTime(uid, sig, admin, 10)
+	if err == nil || !strings.Contains(err.Error(), "missing permission") {
+		t.Fatal("advance time without token should fail: ", err)
+	}
+
+	_, err = connect("write").AdvanceTime(uid, sig, admin, 10)
+	if err == nil {
+		t.Fatal("advance time with write token should fail")
+	}
+
+	ntime, err := connect("admin").AdvanceTime(uid, sig, admin, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ntime != 1600000010 {
+		t.Fatal("time is not advanced: ", ntime)
+	}
+
+	_, err = permsUpTo("root")
+	if err == nil {
+		t.Fatal("unknown perm should fail")
+	}
+}
+
+func TestSetReady(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n, err := node.NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := loadSecret(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := FullNodeHandler(impl.New(ctx, n, secret), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	a, closer, err := client.NewFullNodeRPC(ctx, srv.URL+"/rpc/v0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer()
+
+	newKey := func() *utils.Key {
+		key, err := utils.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	signCall := func(key *utils.Key, method string, params ...interface{}) (uint64, []byte, utils.Address) {
+		addr := utils.ToAddress(key.PubKey)
+		uid := a.GetNonce(addr, addr)
+		sig, err := utils.SignCall(key.SecretKey, method, utils.DefaultChainID, uid, params...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uid, sig, addr
+	}
+
+	akey := newKey()
+	uid, sig, admin := signCall(akey, "CreateErcToken")
+	et, err := a.CreateErcToken(uid, sig, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	taddr := et.Addr
+	uid, sig, _ = signCall(akey, "CreateRoleMgr", admin, taddr)
+	rm, err := a.CreateRoleMgr(uid, sig, admin, admin, taddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, sig, _ = signCall(akey, "CreateGroup", uint16(2))
+	_, err = a.CreateGroup(uid, sig, admin, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gIndex := uint64(len(a.GetAllGroups(admin)) - 1)
+
+	// two keepers with BLS keys join group
+	amount := a.GetKeeperPledge(admin)
+	plAddr := a.GetPledgeAddress(admin)
+	bkeys := make([]*utils.BlsKey, 2)
+	var kkey *utils.Key
+	for i := range bkeys {
+		kkey = newKey()
+		ka := utils.ToAddress(kkey.PubKey)
+		bk, err := utils.GenerateBlsKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bkeys[i] = bk
+		pop, err := utils.BlsProve(bk.SecretKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blsKey := append(append([]byte(nil), bk.PubKey...), pop...)
+
+		uid, sig, _ = signCall(akey, "Transfer", taddr, ka, amount)
+		_, err = a.Transfer(uid, sig, taddr, admin, ka, amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uid, sig, _ = signCall(kkey, "Register", ka, nil)
+		_, err = a.Register(uid, sig, ka, ka, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uid, sig, _ = signCall(kkey, "Approve", taddr, plAddr, amount)
+		_, err = a.Approve(uid, sig, taddr, ka, plAddr, amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index, err := a.GetIndex(ka, ka)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uid, sig, _ = signCall(kkey, "Pledge", index, amount)
+		_, err = a.Pledge(uid, sig, ka, index, amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uid, sig, _ = signCall(kkey, "RegisterKeeper", index, blsKey, nil)
+		_, err = a.RegisterKeeper(uid, sig, ka, index, blsKey, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uid, sig, _ = signCall(akey, "AddKeeperToGroup", index, gIndex, nil)
+		_, err = a.AddKeeperToGroup(uid, sig, admin, index, gIndex, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rh, err := contract.ReadyDigest(utils.DefaultChainID, rm.Addr, gIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigs := make([][]byte, len(bkeys))
+	for i, bk := range bkeys {
+		sigs[i], err = utils.BlsSign(bk.SecretKey, rh[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ksigns := contract.KeeperAggSign(sigs[0], []byte{0b01})
+	uid, sig, ka := signCall(kkey, "SetReady", gIndex, ksigns)
+	_, err = a.SetReady(uid, sig, ka, gIndex, ksigns)
+	if err == nil {
+		t.Fatal("one keeper is not enough for level 2")
+	}
+
+	asig, err := utils.BlsAggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ksigns = contract.KeeperAggSign(asig, []byte{0b11})
+	uid, sig, ka = signCall(kkey, "SetReady", gIndex, ksigns)
+	_, err = a.SetReady(uid, sig, ka, gIndex, ksigns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gi, err := a.GetGroupInfo(admin, gIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gi.IsReady {
+		t.Fatal("group is not ready by aggregated sig")
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/xerrors"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// FileName of config in repo
+const FileName = "config.toml"
+
+// Config of settle node; flags of settle run override it
+type Config struct {
+	API   API
+	Repo  Repo
+	Log   Log
+	Chain Chain
+}
+
+type API struct {
+	ListenAddress string // multiaddr of rpc
+	Auth          bool   // check api token of rpc calls
+}
+
+type Repo struct {
+	Datastore string // relative to repo if not absolute
+}
+
+type Log struct {
+	Level  string // debug, info, warn or error
+	Output string // stdout, stderr, or a log dir; relative to repo if not absolute
+}
+
+type Chain struct {
+	ChainID         uint64        // of a new chain, kept in genesis; 0 uses the stored or default one
+	KeeperDeposit   uint64        // Token, pledge of keeper in RoleMgr created by node
+	ProviderDeposit uint64        // Token, pledge of provider in RoleMgr created by node
+	GasPrice        uint64        // fee per gas of calls, in smallest unit of fee token; 0 is free
+	FeeToken        utils.Address // token of gas fee, registered in RoleMgr; nil is its primary token
+}
+
+func Default() *Config {
+	return &Config{
+		API: API{
+			ListenAddress: "/ip4/0.0.0.0/tcp/18000",
+		},
+		Repo: Repo{
+			Datastore: "datastore",
+		},
+		Log: Log{
+			Level:  "debug",
+			Output: "logs",
+		},
+		Chain: Chain{
+			KeeperDeposit:   contract.KeeperDeposit,
+			ProviderDeposit: contract.ProviderDeposit,
+		},
+	}
+}
+
+// Load reads config at path, missing keys keep default values
+func Load(path string) (*Config, error) {
+	c := Default()
+	_, err := toml.DecodeFile(path, c)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Write writes c to path, which must not exist
+func (c *Config) Write(path string) error {
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(c)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(path)
+	if err == nil {
+		return xerrors.Errorf("config %s exists", path)
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestConfig(t *testing.T) {
+	p := filepath.Join(t.TempDir(), FileName)
+
+	c := Default()
+	c.API.Auth = true
+	c.Chain.ChainID = 7
+	c.Chain.GasPrice = 2
+	c.Chain.FeeToken = utils.BytesToAddress([]byte("fee token"))
+	err := c.Write(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Write(p)
+	if err == nil {
+		t.Fatal("existed config should not be overwritten")
+	}
+
+	lc, err := Load(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *lc != *c {
+		t.Fatal("config is not same after load: ", lc, c)
+	}
+
+	// missing keys keep default
+	p = filepath.Join(t.TempDir(), FileName)
+	err = ioutil.WriteFile(p, []byte("[Log]\nLevel = \"warn\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lc, err = Load(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc := Default()
+	dc.Log.Level = "warn"
+	if *lc != *dc {
+		t.Fatal("partial config is wrong: ", lc)
+	}
+
+	err = ioutil.WriteFile(p, []byte("[Log]\nLevel = 1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Load(p)
+	if err == nil {
+		t.Fatal("malformed config should fail")
+	}
+}
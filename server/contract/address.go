@@ -0,0 +1,191 @@
+package contract
+
+import (
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// AddrChange is a pending change of address of an index
+type AddrChange struct {
+	NewAddr utils.Address
+	Ready   uint64 // time after which it can be confirmed
+}
+
+// ChangeAddress remaps index to newAddr, signed by both old and new address;
+// pledge, fs balance and group of index go with it. With a change delay set by
+// admin, it is pending till confirmed, and the old address can cancel it before
+func (r *roleMgr) ChangeAddress(caller utils.Address, index uint64, newAddr utils.Address, oldSig, newSig []byte) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	bi, err := r.getInfo(index)
+	if err != nil {
+		return err
+	}
+
+	if bi.IsBanned {
+		return ErrPermission
+	}
+
+	err = r.checkNewAddr(index, newAddr)
+	if err != nil {
+		return err
+	}
+
+	_, ok := r.changes[index]
+	if ok {
+		return ErrExist
+	}
+
+	h, err := ChangeAddressDigest(r.state.chainID, r.local, index, newAddr)
+	if err != nil {
+		return err
+	}
+
+	err = checkSign(caller, r.addrs[index], h, oldSig)
+	if err != nil {
+		return err
+	}
+
+	err = checkSign(caller, newAddr, h, newSig)
+	if err != nil {
+		return err
+	}
+
+	if r.changeDelay == 0 {
+		r.changeAddress(index, newAddr)
+		return nil
+	}
+
+	ac := &AddrChange{
+		NewAddr: newAddr,
+		Ready:   r.state.GetTime() + r.changeDelay,
+	}
+	r.changes[index] = ac
+
+	r.state.emit(r.local, types.EventAddressChanging, []uint64{index}, &types.AddressEvent{
+		Index:   index,
+		OldAddr: r.addrs[index],
+		NewAddr: newAddr,
+		Ready:   ac.Ready,
+	})
+
+	return nil
+}
+
+// ConfirmAddress applies pending change of index after its delay,
+// called by old or new address
+func (r *roleMgr) ConfirmAddress(caller utils.Address, index uint64) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	ac, ok := r.changes[index]
+	if !ok {
+		return ErrEmpty
+	}
+
+	if caller != r.addrs[index] && caller != ac.NewAddr {
+		return ErrPermission
+	}
+
+	if r.state.GetTime() < ac.Ready {
+		return ErrPermission
+	}
+
+	// newAddr may be registered during the delay
+	err = r.checkNewAddr(index, ac.NewAddr)
+	if err != nil {
+		return err
+	}
+
+	r.changeAddress(index, ac.NewAddr)
+	return nil
+}
+
+// CancelAddress drops pending change of index, called by old address
+func (r *roleMgr) CancelAddress(caller utils.Address, index uint64) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	ac, ok := r.changes[index]
+	if !ok {
+		return ErrEmpty
+	}
+
+	if caller != r.addrs[index] {
+		return ErrPermission
+	}
+
+	delete(r.changes, index)
+
+	r.state.emit(r.local, types.EventAddressCanceled, []uint64{index}, &types.AddressEvent{
+		Index:   index,
+		OldAddr: caller,
+		NewAddr: ac.NewAddr,
+		Ready:   ac.Ready,
+	})
+
+	return nil
+}
+
+func (r *roleMgr) GetAddrChange(caller utils.Address, index uint64) (*AddrChange, error) {
+	ac, ok := r.changes[index]
+	if !ok {
+		return nil, ErrEmpty
+	}
+	return ac, nil
+}
+
+// checkNewAddr checks newAddr is not registered, nor pending for other index
+func (r *roleMgr) checkNewAddr(index uint64, newAddr utils.Address) error {
+	if newAddr == utils.NilAddress {
+		return ErrInput
+	}
+
+	_, ok := r.info[newAddr]
+	if ok {
+		return ErrExist
+	}
+
+	for i, ac := range r.changes {
+		if i != index && ac.NewAddr == newAddr {
+			return ErrExist
+		}
+	}
+
+	return nil
+}
+
+func (r *roleMgr) changeAddress(index uint64, newAddr utils.Address) {
+	old := r.addrs[index]
+	bi := r.info[old]
+
+	delete(r.info, old)
+	r.info[newAddr] = bi
+	r.addrs[index] = newAddr
+	delete(r.changes, index)
+
+	// index 0 is foundation, which gets fees and tax
+	if index == 0 {
+		r.foundation = newAddr
+	}
+
+	r.state.emit(r.local, types.EventAddressChanged, []uint64{index}, &types.AddressEvent{
+		Index:   index,
+		OldAddr: old,
+		NewAddr: newAddr,
+		Ready:   r.state.GetTime(),
+	})
+}
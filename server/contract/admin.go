@@ -0,0 +1,295 @@
+package contract
+
+import (
+	"math/big"
+
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// ops of admin, proposed by one admin and executed after Threshold approvals
+const (
+	OpCreateGroup       uint8 = iota + 1 // Paras: Level
+	OpRegisterToken                      // Paras: Addr
+	OpSetPledgeMoney                     // Paras: Money of keeper, PPledge of provider
+	OpPledge                             // air drop; Paras: Index, Money
+	OpRecharge                           // air drop; Paras: Index, TokenIndex, Money
+	OpAddAdmin                           // Paras: Addr
+	OpRemoveAdmin                        // Paras: Addr
+	OpSetThreshold                       // Paras: Threshold
+	OpTransferOwnership                  // Paras: Addr, accepted by it then
+	OpSetChangeDelay                     // Paras: Delay of address change
+)
+
+// ProposalParas are params of a proposal, by its Op
+type ProposalParas struct {
+	Addr       utils.Address
+	Index      uint64
+	TokenIndex uint32
+	Level      uint16
+	Threshold  uint16
+	Money      *big.Int
+	PPledge    *big.Int
+	Delay      uint64
+}
+
+// AdminInfo is admin set of roleMgr
+type AdminInfo struct {
+	Admins    []utils.Address
+	Threshold uint16 // approvals needed by admin ops
+}
+
+type Proposal struct {
+	ID        uint64
+	Op        uint8
+	Paras     ProposalParas
+	Proposer  utils.Address
+	Approvals []utils.Address // admins approved, proposer first
+	Executed  bool
+}
+
+func (r *roleMgr) isAdmin(addr utils.Address) bool {
+	for _, a := range r.admins {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAdmin allows an admin op called directly, only when one admin is enough
+func (r *roleMgr) checkAdmin(caller utils.Address) error {
+	if r.threshold > 1 || !r.isAdmin(caller) {
+		return ErrPermission
+	}
+	return nil
+}
+
+// checkAdmins verifies asigns[i] by i-th admin on h, empty one is skipped;
+// caller counts as signed if it is an admin, and at least threshold admins
+// must have signed
+func (r *roleMgr) checkAdmins(caller utils.Address, h utils.Hash, asigns [][]byte) error {
+	if len(asigns) > len(r.admins) {
+		return ErrSign
+	}
+
+	signed := 0
+	for i, a := range r.admins {
+		if a == caller {
+			signed++
+			continue
+		}
+
+		if i >= len(asigns) || len(asigns[i]) == 0 {
+			continue
+		}
+
+		if !utils.Verify(a, h[:], asigns[i]) {
+			return ErrSign
+		}
+		signed++
+	}
+
+	if signed < int(r.threshold) {
+		return ErrPermission
+	}
+
+	return nil
+}
+
+func (r *roleMgr) GetAdmins(caller utils.Address) *AdminInfo {
+	return &AdminInfo{
+		Admins:    r.admins,
+		Threshold: r.threshold,
+	}
+}
+
+func (r *roleMgr) GetProposal(caller utils.Address, id uint64) (*Proposal, error) {
+	if id >= uint64(len(r.proposals)) {
+		return nil, ErrInput
+	}
+	return r.proposals[id], nil
+}
+
+// Propose by an admin, who approves it too; returns id of the proposal
+func (r *roleMgr) Propose(caller utils.Address, op uint8, paras *ProposalParas) (uint64, error) {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return 0, err
+	}
+
+	if !r.isAdmin(caller) {
+		return 0, ErrPermission
+	}
+
+	if op < OpCreateGroup || op > OpSetChangeDelay {
+		return 0, ErrInput
+	}
+
+	p := &Proposal{
+		ID:        uint64(len(r.proposals)),
+		Op:        op,
+		Paras:     *paras,
+		Proposer:  caller,
+		Approvals: []utils.Address{caller},
+	}
+	r.proposals = append(r.proposals, p)
+
+	r.emitProposal(types.EventProposed, p, caller)
+
+	return p.ID, nil
+}
+
+// ApproveProposal by another admin
+func (r *roleMgr) ApproveProposal(caller utils.Address, id uint64) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	if !r.isAdmin(caller) {
+		return ErrPermission
+	}
+
+	p, err := r.GetProposal(caller, id)
+	if err != nil {
+		return err
+	}
+
+	if p.Executed {
+		return ErrPermission
+	}
+
+	for _, a := range p.Approvals {
+		if a == caller {
+			return ErrExist
+		}
+	}
+
+	p.Approvals = append(p.Approvals, caller)
+
+	r.emitProposal(types.EventApproved, p, caller)
+
+	return nil
+}
+
+// ExecuteProposal by an admin, when Threshold admins approve it;
+// approvals of removed admins are not counted
+func (r *roleMgr) ExecuteProposal(caller utils.Address, id uint64) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	if !r.isAdmin(caller) {
+		return ErrPermission
+	}
+
+	p, err := r.GetProposal(caller, id)
+	if err != nil {
+		return err
+	}
+
+	if p.Executed {
+		return ErrPermission
+	}
+
+	cnt := 0
+	for _, a := range p.Approvals {
+		if r.isAdmin(a) {
+			cnt++
+		}
+	}
+	if cnt < int(r.threshold) {
+		return ErrPermission
+	}
+
+	err = r.execProposal(p)
+	if err != nil {
+		return err
+	}
+	p.Executed = true
+
+	r.emitProposal(types.EventExecuted, p, caller)
+
+	return nil
+}
+
+func (r *roleMgr) execProposal(p *Proposal) error {
+	pa := &p.Paras
+	switch p.Op {
+	case OpCreateGroup:
+		return r.createGroup(pa.Level)
+	case OpRegisterToken:
+		return r.registerToken(pa.Addr)
+	case OpSetPledgeMoney:
+		if pa.Money == nil || pa.PPledge == nil {
+			return ErrInput
+		}
+		r.setPledgeMoney(pa.Money, pa.PPledge)
+		return nil
+	case OpPledge:
+		if pa.Money == nil {
+			return ErrInput
+		}
+		return r.pledgeFor(r.local, pa.Index, pa.Money, true)
+	case OpRecharge:
+		if pa.Money == nil {
+			return ErrInput
+		}
+		return r.rechargeFor(r.local, pa.Index, pa.TokenIndex, pa.Money, true)
+	case OpAddAdmin:
+		if r.isAdmin(pa.Addr) {
+			return ErrExist
+		}
+		r.admins = append(r.admins, pa.Addr)
+		return nil
+	case OpRemoveAdmin:
+		for i, a := range r.admins {
+			if a == pa.Addr {
+				// keep enough admins to reach threshold
+				if len(r.admins)-1 < int(r.threshold) {
+					return ErrInput
+				}
+				r.admins = append(r.admins[:i:i], r.admins[i+1:]...)
+				return nil
+			}
+		}
+		return ErrInput
+	case OpSetThreshold:
+		if pa.Threshold == 0 || int(pa.Threshold) > len(r.admins) {
+			return ErrInput
+		}
+		r.threshold = pa.Threshold
+		return nil
+	case OpTransferOwnership:
+		if pa.Addr == r.admin {
+			return ErrInput
+		}
+		r.pendingAdmin = pa.Addr
+		r.state.emit(r.local, types.EventOwnershipPending, nil, &types.OwnershipEvent{
+			Owner:    r.admin,
+			NewOwner: pa.Addr,
+		})
+		return nil
+	case OpSetChangeDelay:
+		r.changeDelay = pa.Delay
+		return nil
+	default:
+		return ErrInput
+	}
+}
+
+func (r *roleMgr) emitProposal(typ string, p *Proposal, admin utils.Address) {
+	r.state.emit(r.local, typ, nil, &types.ProposalEvent{
+		ID:    p.ID,
+		Op:    p.Op,
+		Admin: admin,
+	})
+}
@@ -0,0 +1,52 @@
+package contract
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock gives the time seen by contracts, in unix seconds
+type Clock interface {
+	Now() uint64
+}
+
+type realClock struct{}
+
+// NewRealClock returns a clock following the system time
+func NewRealClock() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() uint64 {
+	return uint64(time.Now().Unix())
+}
+
+// MockClock only moves when it is set or advanced; used in tests and dev nodes
+type MockClock struct {
+	sync.Mutex
+	now uint64
+}
+
+func NewMockClock(t uint64) *MockClock {
+	return &MockClock{now: t}
+}
+
+func (m *MockClock) Now() uint64 {
+	m.Lock()
+	defer m.Unlock()
+	return m.now
+}
+
+func (m *MockClock) Set(t uint64) {
+	m.Lock()
+	defer m.Unlock()
+	m.now = t
+}
+
+// Advance moves the clock forward by d seconds and returns the new time
+func (m *MockClock) Advance(d uint64) uint64 {
+	m.Lock()
+	defer m.Unlock()
+	m.now += d
+	return m.now
+}
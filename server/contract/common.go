@@ -3,8 +3,8 @@ package contract
 import (
 	"errors"
 	"math/big"
-	"time"
 
+	"github.com/memoio/go-settlement/server/types"
 	"github.com/memoio/go-settlement/utils"
 )
 
@@ -27,6 +27,9 @@ var (
 	ErrBalanceNotEnough = errors.New("balance is insufficient")
 	ErrPermission       = errors.New("permission is not right")
 	ErrNonce            = errors.New("nonce is not right")
+	ErrOutOfGas         = errors.New("out of gas")
+	ErrSign             = errors.New("signature is not right")
+	ErrQuorum           = errors.New("keeper signatures are not enough")
 )
 
 // default value
@@ -77,44 +80,63 @@ const (
 	StorePrice uint64 = 100 * GWei // per MB*day
 )
 
-var (
-	globalMap map[utils.Address]interface{}
-	gtime     uint64
-	realTime  bool
-)
+// State owns the contracts and clock of one settlement chain
+type State struct {
+	contracts map[utils.Address]interface{}
+	dirty     map[utils.Address]struct{} // written since last Clean
+	snap      *Snapshot                  // open snapshot, see Snapshot
+	clock     Clock
+	events    []*types.Event
+	gasLimit  uint64
+	gasUsed   uint64
+	chainID   uint64        // in digests signed by roles
+	feeToken  utils.Address // token of gas fee, nil is primary token of roleMgr
+}
 
-func init() {
-	globalMap = make(map[utils.Address]interface{})
-	gtime = uint64(time.Now().Unix())
-	realTime = true
+// NewState creates an empty chain state; clk is the real clock if nil
+func NewState(clk Clock) *State {
+	if clk == nil {
+		clk = NewRealClock()
+	}
+
+	return &State{
+		contracts: make(map[utils.Address]interface{}),
+		dirty:     make(map[utils.Address]struct{}),
+		clock:     clk,
+		chainID:   utils.DefaultChainID,
+	}
 }
 
-func SetRealTime(flag bool) {
-	realTime = flag
+// SetChainID binds signatures checked by contracts to chain id
+func (s *State) SetChainID(id uint64) {
+	s.chainID = id
 }
 
-func SetTime(t uint64) {
-	if t > 0 {
-		gtime = t
-	}
+func (s *State) ChainID() uint64 {
+	return s.chainID
+}
 
-	gtime = uint64(time.Now().Unix())
+// SetClock replaces the clock and returns the old one
+func (s *State) SetClock(clk Clock) Clock {
+	old := s.clock
+	s.clock = clk
+	return old
 }
 
-func GetTime() uint64 {
-	if realTime {
-		return uint64(time.Now().Unix())
-	}
+func (s *State) GetClock() Clock {
+	return s.clock
+}
 
-	return gtime
+func (s *State) GetTime() uint64 {
+	return s.clock.Now()
 }
 
-func GetMap() map[utils.Address]interface{} {
-	return globalMap
+func (s *State) GetMap() map[utils.Address]interface{} {
+	return s.contracts
 }
 
-func getErcToken(addr utils.Address) (ErcToken, error) {
-	ri, ok := globalMap[addr]
+func (s *State) GetErcToken(addr utils.Address) (ErcToken, error) {
+	ri, ok := s.contracts[addr]
 	if ok {
 		r, ok := ri.(ErcToken)
 		if ok {
@@ -125,8 +147,8 @@ func getErcToken(addr utils.Address) (ErcToken, error) {
 	return nil, ErrEmpty
 }
 
-func GetPledgePool(addr utils.Address) (PledgePool, error) {
-	pi, ok := globalMap[addr]
+func (s *State) GetPledgePool(addr utils.Address) (PledgePool, error) {
+	pi, ok := s.contracts[addr]
 	if ok {
 		r, ok := pi.(PledgePool)
 		if ok {
@@ -137,8 +159,8 @@ func GetPledgePool(addr utils.Address) (PledgePool, error) {
 	return nil, ErrEmpty
 }
 
-func getRoleMgr(addr utils.Address) (RoleMgr, error) {
-	ri, ok := globalMap[addr]
+func (s *State) GetRoleMgr(addr utils.Address) (RoleMgr, error) {
+	ri, ok := s.contracts[addr]
 	if ok {
 		r, ok := ri.(RoleMgr)
 		if ok {
@@ -149,8 +171,8 @@ func getRoleMgr(addr utils.Address) (RoleMgr, error) {
 	return nil, ErrEmpty
 }
 
-func GetFsMgr(addr utils.Address) (FsMgr, error) {
-	ri, ok := globalMap[addr]
+func (s *State) GetFsMgr(addr utils.Address) (FsMgr, error) {
+	ri, ok := s.contracts[addr]
 	if ok {
 		r, ok := ri.(FsMgr)
 		if ok {
@@ -161,11 +183,35 @@ func GetFsMgr(addr utils.Address) (FsMgr, error) {
 	return nil, ErrEmpty
 }
 
+// GetOwnable returns contract at addr, whose owner can be changed; pledge
+// pool and fs contracts are not, they are owned by their roleMgr
+func (s *State) GetOwnable(addr utils.Address) (Ownable, error) {
+	ci, ok := s.contracts[addr]
+	if ok {
+		c, ok := ci.(Ownable)
+		if ok {
+			return c, nil
+		}
+	}
+
+	return nil, ErrEmpty
+}
+
 type info interface {
 	GetContractAddress() utils.Address
 	GetOwnerAddress() utils.Address
 }
 
+// Ownable changes owner in two steps: owner names new owner, who then accepts;
+// so a wrong address never takes over the contract
+type Ownable interface {
+	// by owner; nil newOwner cancels
+	TransferOwnership(caller, newOwner utils.Address) error
+	// by new owner
+	AcceptOwnership(caller utils.Address) error
+	GetOwnerInfo(caller utils.Address) *OwnerInfo
+}
+
 // ErcToken is
 // 参考：https://zhuanlan.zhihu.com/p/391837660
 type ErcToken interface {
@@ -185,6 +231,7 @@ type ErcToken interface {
 
 	// 额外的辅助接口
 	info
+	Ownable
 }
 
 // PledgePool is for stake and withdraw
@@ -199,12 +246,18 @@ type PledgePool interface {
 	info
 }
 
-// RoleMgr can be admin by multiple signatures, non-destroy
-// now, single sign
+// RoleMgr is admined by M of N admins, non-destroy; ops of admin are
+// called directly only if threshold is 1, else by proposals
 type RoleMgr interface {
 	// called by admin, 注册erc20代币地址
 	RegisterToken(caller, taddr utils.Address) error
 
+	// by admin; proposer approves it too
+	Propose(caller utils.Address, op uint8, paras *ProposalParas) (uint64, error)
+	ApproveProposal(caller utils.Address, id uint64) error
+	// by admin, after threshold approvals
+	ExecuteProposal(caller utils.Address, id uint64) error
+
 	// 注册地址，获取序号; meta
 	Register(caller, addr utils.Address, sign []byte) error
 
@@ -217,10 +270,19 @@ type RoleMgr interface {
 
 	// 创建组，called by admin
 	CreateGroup(caller utils.Address, level uint16) error
+	// 设置增发表，called by admin
+	SetMintInfo(caller utils.Address, mint []*MintInfo) error
 	// auth by keepers
 	SetReady(caller utils.Address, gIndex uint64, ksigns [][]byte) error
+	// remap index to newAddr, signed by both; pending if change delay is set
+	ChangeAddress(caller utils.Address, index uint64, newAddr utils.Address, oldSig, newSig []byte) error
+	// after change delay, by old or new address
+	ConfirmAddress(caller utils.Address, index uint64) error
+	// before confirmed, by old address
+	CancelAddress(caller utils.Address, index uint64) error
+
 	// 向组中添加keeper, called by keeper and auth by admin
-	AddKeeperToGroup(caller utils.Address, index, gIndex uint64, asign []byte) error
+	AddKeeperToGroup(caller utils.Address, index, gIndex uint64, asigns [][]byte) error
 	// 向组中添加provider, called by provider
 	AddProviderToGroup(caller utils.Address, index, gIndex uint64) error
 
@@ -252,13 +314,18 @@ type RoleMgr interface {
 
 	GetPledgeAddress(caller utils.Address) utils.Address
 	GetPledge(caller utils.Address) (*big.Int, *big.Int)
+	GetMintInfo(caller utils.Address) []*MintInfo
 	GetAllTokens(caller utils.Address) []utils.Address
 	GetAllAddrs(caller utils.Address) []utils.Address
 	GetAllGroups(caller utils.Address) []*GroupInfo
 
 	GetFoundation(caller utils.Address) utils.Address
+	GetAdmins(caller utils.Address) *AdminInfo
+	GetProposal(caller utils.Address, id uint64) (*Proposal, error)
+	GetAddrChange(caller utils.Address, index uint64) (*AddrChange, error)
 
 	info
+	Ownable
 	// stop service? not allowed
 	//KeeperQuit()
 	// stop service? allowed ?
@@ -3,6 +3,7 @@ package contract
 import (
 	"math/big"
 
+	"github.com/memoio/go-settlement/server/types"
 	"github.com/memoio/go-settlement/utils"
 )
 
@@ -14,20 +15,24 @@ type twoKey struct {
 }
 
 type ercToken struct {
-	local       utils.Address // contract utils.Address
-	admin       utils.Address // owner
-	totalSupply *big.Int
-	money       map[utils.Address]*big.Int
-	allowed     map[twoKey]*big.Int
+	state *State
+
+	local        utils.Address // contract utils.Address
+	admin        utils.Address // owner
+	pendingAdmin utils.Address
+	totalSupply  *big.Int
+	money        map[utils.Address]*big.Int
+	allowed      map[twoKey]*big.Int
 }
 
 // NewErcToken create
-func NewErcToken(caller utils.Address) ErcToken {
+func NewErcToken(s *State, caller utils.Address) ErcToken {
 	// verify
 	// get local utils.Address
 	local := utils.GetContractAddress(caller, []byte("ErcToken"))
 
 	et := &ercToken{
+		state:       s,
 		admin:       caller,
 		local:       local,
 		money:       make(map[utils.Address]*big.Int),
@@ -37,7 +42,10 @@ func NewErcToken(caller utils.Address) ErcToken {
 
 	et.money[caller] = new(big.Int).Set(et.totalSupply)
 
-	globalMap[local] = et
+	// no error here, node checks gas after call
+	s.UseGas(GasCreate)
+
+	s.add(local, et)
 	return et
 }
 
@@ -70,6 +78,13 @@ func (e *ercToken) Allowance(caller, tokenOwner, spender utils.Address) *big.Int
 }
 
 func (e *ercToken) Transfer(caller, to utils.Address, value *big.Int) error {
+	e.state.touch(e.local)
+
+	err := e.state.UseGas(GasTransfer)
+	if err != nil {
+		return err
+	}
+
 	// verify to is not zero
 	// verify value > 0
 	if value.Cmp(zero) < 0 {
@@ -96,11 +111,21 @@ func (e *ercToken) Transfer(caller, to utils.Address, value *big.Int) error {
 	}
 	valto.Add(valto, value)
 
+	e.state.emit(e.local, types.EventTransfer, nil, &types.TransferEvent{
+		From:  caller,
+		To:    to,
+		Value: value,
+	})
+
 	return nil
 }
 
 // 用于合约账户将erc token转入合约账户中
 func (e *ercToken) Approve(caller, spender utils.Address, value *big.Int) {
+	e.state.touch(e.local)
+
+	e.state.UseGas(GasTransfer)
+
 	if value.Cmp(zero) > 0 {
 		tKey := twoKey{
 			owner:   caller,
@@ -113,10 +138,23 @@ func (e *ercToken) Approve(caller, spender utils.Address, value *big.Int) {
 			allo = new(big.Int).Set(value)
 			e.allowed[tKey] = allo
 		}
+
+		e.state.emit(e.local, types.EventApproval, nil, &types.ApprovalEvent{
+			Owner:   caller,
+			Spender: spender,
+			Value:   value,
+		})
 	}
 }
 
 func (e *ercToken) TransferFrom(caller, from, to utils.Address, value *big.Int) error {
+	e.state.touch(e.local)
+
+	err := e.state.UseGas(GasTransfer)
+	if err != nil {
+		return err
+	}
+
 	// verify from and to is not zero address
 	// verify value > 0
 	if value.Cmp(zero) < 0 {
@@ -158,11 +196,24 @@ func (e *ercToken) TransferFrom(caller, from, to utils.Address, value *big.Int)
 	}
 	valto.Add(valto, value)
 
+	e.state.emit(e.local, types.EventTransfer, nil, &types.TransferEvent{
+		From:  from,
+		To:    to,
+		Value: value,
+	})
+
 	return nil
 }
 
 // 增发
 func (e *ercToken) MintToken(caller, target utils.Address, mintedAmount *big.Int) error {
+	e.state.touch(e.local)
+
+	err := e.state.UseGas(GasTransfer)
+	if err != nil {
+		return err
+	}
+
 	if caller != e.admin {
 		return ErrPermission
 	}
@@ -177,11 +228,23 @@ func (e *ercToken) MintToken(caller, target utils.Address, mintedAmount *big.Int
 
 	e.totalSupply.Add(e.totalSupply, mintedAmount)
 
+	e.state.emit(e.local, types.EventTransfer, nil, &types.TransferEvent{
+		To:    target,
+		Value: mintedAmount,
+	})
+
 	return nil
 }
 
 // 销毁
 func (e *ercToken) Burn(caller utils.Address, burnAmount *big.Int) error {
+	e.state.touch(e.local)
+
+	err := e.state.UseGas(GasTransfer)
+	if err != nil {
+		return err
+	}
+
 	if caller != e.admin {
 		return ErrPermission
 	}
@@ -198,6 +261,12 @@ func (e *ercToken) Burn(caller utils.Address, burnAmount *big.Int) error {
 	bal.Sub(bal, burnAmount)
 
 	e.totalSupply.Sub(e.totalSupply, burnAmount)
+
+	e.state.emit(e.local, types.EventTransfer, nil, &types.TransferEvent{
+		From:  caller,
+		Value: burnAmount,
+	})
+
 	return nil
 }
 
@@ -207,13 +276,37 @@ func (e *ercToken) AirDrop(caller utils.Address, addrs []utils.Address, money *b
 		return ErrPermission
 	}
 
+	// all or nothing, caller reverts on error
 	for _, addr := range addrs {
-		e.Transfer(e.admin, addr, money)
+		err := e.Transfer(e.admin, addr, money)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// move is Transfer of fee by node, it uses no gas and emits no event
+func (e *ercToken) move(from, to utils.Address, value *big.Int) error {
+	e.state.touch(e.local)
+
+	val, ok := e.money[from]
+	if !ok || val.Cmp(value) < 0 {
+		return ErrBalanceNotEnough
+	}
+	val.Sub(val, value)
+
+	valto, ok := e.money[to]
+	if !ok {
+		valto = big.NewInt(0)
+		e.money[to] = valto
+	}
+	valto.Add(valto, value)
+
+	return nil
+}
+
 func (e *ercToken) GetContractAddress() utils.Address {
 	return e.local
 }
@@ -223,8 +316,8 @@ func (e *ercToken) GetOwnerAddress() utils.Address {
 }
 
 // 获取taddr对应的erc20上local地址的余额
-func getBalance(taddr, query utils.Address) *big.Int {
-	eti, ok := globalMap[taddr]
+func (s *State) getBalance(taddr, query utils.Address) *big.Int {
+	eti, ok := s.contracts[taddr]
 	if !ok {
 		return big.NewInt(0)
 	}
@@ -237,8 +330,8 @@ func getBalance(taddr, query utils.Address) *big.Int {
 }
 
 // taddr对应的erc20上从from到to
-func sendBalance(taddr, caller, to utils.Address, money *big.Int) error {
-	eti, ok := globalMap[taddr]
+func (s *State) sendBalance(taddr, caller, to utils.Address, money *big.Int) error {
+	eti, ok := s.contracts[taddr]
 	if !ok {
 		return ErrEmpty
 	}
@@ -250,8 +343,22 @@ func sendBalance(taddr, caller, to utils.Address, money *big.Int) error {
 	return et.Transfer(caller, to, money)
 }
 
-func sendBalanceFrom(taddr, caller, from, to utils.Address, money *big.Int) error {
-	eti, ok := globalMap[taddr]
+// moveFee moves fee of taddr from one to another, without gas or event
+func (s *State) moveFee(taddr, from, to utils.Address, money *big.Int) error {
+	eti, ok := s.contracts[taddr]
+	if !ok {
+		return ErrEmpty
+	}
+
+	et, ok := eti.(*ercToken)
+	if !ok {
+		return ErrMisType
+	}
+	return et.move(from, to, money)
+}
+
+func (s *State) sendBalanceFrom(taddr, caller, from, to utils.Address, money *big.Int) error {
+	eti, ok := s.contracts[taddr]
 	if !ok {
 		return ErrEmpty
 	}
@@ -263,8 +370,8 @@ func sendBalanceFrom(taddr, caller, from, to utils.Address, money *big.Int) erro
 	return et.TransferFrom(caller, from, to, money)
 }
 
-func approve(taddr, caller, spender utils.Address, money *big.Int) error {
-	eti, ok := globalMap[taddr]
+func (s *State) approve(taddr, caller, spender utils.Address, money *big.Int) error {
+	eti, ok := s.contracts[taddr]
 	if !ok {
 		return ErrEmpty
 	}
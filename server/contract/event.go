@@ -0,0 +1,30 @@
+package contract
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// emit records typed event ev of contract addr; indexes are roles involved
+func (s *State) emit(addr utils.Address, typ string, indexes []uint64, ev interface{}) {
+	data, err := cbor.Marshal(ev)
+	if err != nil {
+		log.Error("encode event fail: ", err)
+		return
+	}
+
+	s.events = append(s.events, &types.Event{
+		Contract: addr,
+		Type:     typ,
+		Indexes:  indexes,
+		Data:     data,
+	})
+}
+
+// TakeEvents returns events emitted since last take
+func (s *State) TakeEvents() []*types.Event {
+	evs := s.events
+	s.events = nil
+	return evs
+}
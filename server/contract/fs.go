@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"strconv"
 
+	"github.com/memoio/go-settlement/server/types"
 	"github.com/memoio/go-settlement/utils"
 	"github.com/minio/blake2b-simd"
 )
@@ -111,8 +112,8 @@ func (s *Settlement) sub(start, end, size uint64, sprice *big.Int) {
 	s.Size -= size
 }
 
-// Calc ends called by withdraw
-func (s *Settlement) calc(pay, lost *big.Int) (*big.Int, error) {
+// Calc ends called by withdraw at ntime
+func (s *Settlement) calc(ntime uint64, pay, lost *big.Int) (*big.Int, error) {
 	res := new(big.Int)
 	// has paid
 	if s.HasPaid.Cmp(pay) > 0 {
@@ -124,7 +125,6 @@ func (s *Settlement) calc(pay, lost *big.Int) (*big.Int, error) {
 	}
 	s.Lost.Set(lost)
 
-	ntime := GetTime()
 	if s.Time < ntime {
 		hp := new(big.Int).SetUint64(ntime - s.Time)
 		hp.Mul(hp, s.Price)
@@ -155,6 +155,8 @@ type multiKey struct {
 var _ FsMgr = (*fsMgr)(nil)
 
 type fsMgr struct {
+	state *State
+
 	local utils.Address // contract of this mgr
 	owner utils.Address // owner
 
@@ -184,8 +186,13 @@ type fsMgr struct {
 }
 
 // NewFsMgr creates an instance; caller == rAddr?
-func NewFsMgr(caller utils.Address, founder, gIndex uint64) (FsMgr, error) {
-	rm, err := getRoleMgr(caller)
+func NewFsMgr(s *State, caller utils.Address, founder, gIndex uint64) (FsMgr, error) {
+	err := s.UseGas(GasCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	rm, err := s.GetRoleMgr(caller)
 	if err != nil {
 		return nil, err
 	}
@@ -208,6 +215,7 @@ func NewFsMgr(caller utils.Address, founder, gIndex uint64) (FsMgr, error) {
 	}
 
 	fm := &fsMgr{
+		state:      s,
 		local:      local,
 		owner:      caller,
 		foundation: founder,
@@ -223,7 +231,7 @@ func NewFsMgr(caller utils.Address, founder, gIndex uint64) (FsMgr, error) {
 
 		keepers:    gi.Keepers,
 		period:     1,
-		lastTime:   GetTime(),
+		lastTime:   s.GetTime(),
 		tAcc:       make(map[uint32]*big.Int),
 		totalCount: 0,
 		count:      make(map[uint64]uint64),
@@ -239,7 +247,7 @@ func NewFsMgr(caller utils.Address, founder, gIndex uint64) (FsMgr, error) {
 		fm.totalCount++
 	}
 
-	globalMap[local] = fm
+	s.add(local, fm)
 
 	return fm, nil
 }
@@ -253,6 +261,8 @@ func (f *fsMgr) GetOwnerAddress() utils.Address {
 }
 
 func (f *fsMgr) AddKeeper(caller utils.Address, kindex uint64) error {
+	f.state.touch(f.local)
+
 	if caller != f.owner {
 		return ErrPermission
 	}
@@ -287,6 +297,8 @@ func (f *fsMgr) getFsInfo(user uint64) (*fsInfo, error) {
 }
 
 func (f *fsMgr) CreateFs(caller utils.Address, user uint64) error {
+	f.state.touch(f.local)
+
 	// call by roleMgr
 	if caller != f.owner {
 		return ErrPermission
@@ -322,6 +334,8 @@ func (f *fsMgr) CreateFs(caller utils.Address, user uint64) error {
 }
 
 func (f *fsMgr) AddOrder(caller utils.Address, kindex, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int) error {
+	f.state.touch(f.local)
+
 	if caller != f.owner {
 		return ErrPermission
 	}
@@ -444,10 +458,24 @@ func (f *fsMgr) AddOrder(caller utils.Address, kindex, user, proIndex, start, en
 		f.totalCount++
 	}
 
+	f.state.emit(f.local, types.EventOrderAdded, []uint64{kindex, user, proIndex}, &types.OrderEvent{
+		Keeper:     kindex,
+		User:       user,
+		Provider:   proIndex,
+		Start:      start,
+		End:        end,
+		Size:       size,
+		Nonce:      nonce,
+		TokenIndex: tokenIndex,
+		Price:      sprice,
+	})
+
 	return nil
 }
 
 func (f *fsMgr) SubOrder(caller utils.Address, kindex, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int) error {
+	f.state.touch(f.local)
+
 	if caller != f.owner {
 		return ErrPermission
 	}
@@ -466,7 +494,7 @@ func (f *fsMgr) SubOrder(caller utils.Address, kindex, user, proIndex, start, en
 	}
 
 	// time.N
-	if GetTime() < end {
+	if f.state.GetTime() < end {
 		return ErrRes
 	}
 
@@ -528,6 +556,18 @@ func (f *fsMgr) SubOrder(caller utils.Address, kindex, user, proIndex, start, en
 		f.totalCount++
 	}
 
+	f.state.emit(f.local, types.EventOrderSubtracted, []uint64{kindex, user, proIndex}, &types.OrderEvent{
+		Keeper:     kindex,
+		User:       user,
+		Provider:   proIndex,
+		Start:      start,
+		End:        end,
+		Size:       size,
+		Nonce:      nonce,
+		TokenIndex: tokenIndex,
+		Price:      sprice,
+	})
+
 	return nil
 }
 
@@ -560,7 +600,7 @@ func (f *fsMgr) GetBalance(caller utils.Address, index uint64, tIndex uint32) (*
 	se, ok := f.proInfo[mk]
 	if ok {
 		canPay := new(big.Int).Set(se.CanPay)
-		nt := GetTime()
+		nt := f.state.GetTime()
 		tmp := new(big.Int).SetUint64(nt - se.Time)
 		tmp.Mul(tmp, se.Price)
 
@@ -580,7 +620,7 @@ func (f *fsMgr) GetBalance(caller utils.Address, index uint64, tIndex uint32) (*
 
 	kc, ok := f.count[index]
 	if ok {
-		ntime := GetTime()
+		ntime := f.state.GetTime()
 		ti := f.tAcc[tIndex]
 		per := new(big.Int).Div(ti, new(big.Int).SetUint64(f.totalCount))
 		pro := new(big.Int).Mul(per, new(big.Int).SetUint64(kc))
@@ -596,11 +636,13 @@ func (f *fsMgr) GetBalance(caller utils.Address, index uint64, tIndex uint32) (*
 
 // 充值
 func (f *fsMgr) Recharge(caller, addr utils.Address, index uint64, tokenIndex uint32, money *big.Int) error {
+	f.state.touch(f.local)
+
 	if caller != f.owner {
 		return ErrPermission
 	}
 
-	rm, err := getRoleMgr(f.owner)
+	rm, err := f.state.GetRoleMgr(f.owner)
 	if err != nil {
 		return err
 	}
@@ -617,7 +659,7 @@ func (f *fsMgr) Recharge(caller, addr utils.Address, index uint64, tokenIndex ui
 		f.tokens = append(f.tokens, tokenIndex)
 	}
 
-	err = sendBalanceFrom(tAddr, f.local, addr, f.local, new(big.Int).Set(money))
+	err = f.state.sendBalanceFrom(tAddr, f.local, addr, f.local, new(big.Int).Set(money))
 	if err != nil {
 		return err
 	}
@@ -639,11 +681,13 @@ func (f *fsMgr) Recharge(caller, addr utils.Address, index uint64, tokenIndex ui
 }
 
 func (f *fsMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint32, amount *big.Int) error {
+	f.state.touch(f.local)
+
 	if amount.Cmp(zero) < 0 {
 		return ErrInput
 	}
 
-	rm, err := getRoleMgr(f.owner)
+	rm, err := f.state.GetRoleMgr(f.owner)
 	if err != nil {
 		return err
 	}
@@ -654,7 +698,7 @@ func (f *fsMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint32,
 	}
 
 	if ki.RoleType == RoleKeeper {
-		ntime := GetTime()
+		ntime := f.state.GetTime()
 		if ntime-f.lastTime > f.period {
 			if f.totalCount <= 0 {
 				return ErrRes
@@ -682,6 +726,12 @@ func (f *fsMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint32,
 							f.balance[nk] = pro
 						}
 						ti.Sub(ti, pro)
+
+						f.state.emit(f.local, types.EventKeeperRewarded, []uint64{kindex}, &types.KeeperRewardedEvent{
+							Keeper:     kindex,
+							TokenIndex: tindex,
+							Amount:     pro,
+						})
 					} else {
 						f.count[kindex] = 1
 					}
@@ -713,15 +763,24 @@ func (f *fsMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint32,
 		return err
 	}
 
-	err = sendBalance(tAddr, f.local, addr, amount)
+	err = f.state.sendBalance(tAddr, f.local, addr, amount)
 	if err != nil {
 		return err
 	}
 	bal.Sub(bal, amount)
+
+	f.state.emit(f.local, types.EventWithdrawn, []uint64{index}, &types.WithdrawnEvent{
+		Index:      index,
+		TokenIndex: tokenIndex,
+		Amount:     amount,
+	})
+
 	return nil
 }
 
 func (f *fsMgr) ProWithdraw(caller utils.Address, proIndex uint64, tokenIndex uint32, pay, lost *big.Int) error {
+	f.state.touch(f.local)
+
 	// verify ksign?
 	pKey := multiKey{
 		roleIndex:  proIndex,
@@ -734,7 +793,7 @@ func (f *fsMgr) ProWithdraw(caller utils.Address, proIndex uint64, tokenIndex ui
 	}
 
 	// pay to provider
-	thisPay, err := se.calc(pay, lost)
+	thisPay, err := se.calc(f.state.GetTime(), pay, lost)
 	if err != nil {
 		return err
 	}
@@ -759,7 +818,7 @@ func (f *fsMgr) ProWithdraw(caller utils.Address, proIndex uint64, tokenIndex ui
 	}
 
 	// get instance by address
-	rm, err := getRoleMgr(f.owner)
+	rm, err := f.state.GetRoleMgr(f.owner)
 	if err != nil {
 		return err
 	}
@@ -774,17 +833,25 @@ func (f *fsMgr) ProWithdraw(caller utils.Address, proIndex uint64, tokenIndex ui
 		return err
 	}
 
-	err = sendBalance(tAddr, f.local, proAddr, thisPay)
+	err = f.state.sendBalance(tAddr, f.local, proAddr, thisPay)
 	if err != nil {
 		return err
 	}
 
 	pb.Sub(pb, thisPay)
 
+	f.state.emit(f.local, types.EventProviderPaid, []uint64{proIndex}, &types.ProviderPaidEvent{
+		Provider:   proIndex,
+		TokenIndex: tokenIndex,
+		Amount:     thisPay,
+	})
+
 	return nil
 }
 
 func (f *fsMgr) AddRepair(caller utils.Address, kindex, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int) error {
+	f.state.touch(f.local)
+
 	if caller != f.owner {
 		return ErrPermission
 	}
@@ -875,10 +942,25 @@ func (f *fsMgr) AddRepair(caller utils.Address, kindex, proIndex, newPro, start,
 		f.totalCount++
 	}
 
+	f.state.emit(f.local, types.EventRepair, []uint64{kindex, proIndex, newPro}, &types.RepairEvent{
+		Keeper:      kindex,
+		Provider:    proIndex,
+		NewProvider: newPro,
+		Start:       start,
+		End:         end,
+		Size:        size,
+		Nonce:       nonce,
+		TokenIndex:  tokenIndex,
+		Price:       sprice,
+		Added:       true,
+	})
+
 	return nil
 }
 
 func (f *fsMgr) SubRepair(caller utils.Address, kindex, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int) error {
+	f.state.touch(f.local)
+
 	if caller != f.owner {
 		return ErrPermission
 	}
@@ -897,7 +979,7 @@ func (f *fsMgr) SubRepair(caller utils.Address, kindex, proIndex, newPro, start,
 	}
 
 	// time.N
-	if GetTime() < end {
+	if f.state.GetTime() < end {
 		return ErrRes
 	}
 
@@ -956,5 +1038,18 @@ func (f *fsMgr) SubRepair(caller utils.Address, kindex, proIndex, newPro, start,
 		f.totalCount++
 	}
 
+	f.state.emit(f.local, types.EventRepair, []uint64{kindex, proIndex, newPro}, &types.RepairEvent{
+		Keeper:      kindex,
+		Provider:    proIndex,
+		NewProvider: newPro,
+		Start:       start,
+		End:         end,
+		Size:        size,
+		Nonce:       nonce,
+		TokenIndex:  tokenIndex,
+		Price:       sprice,
+		Added:       false,
+	})
+
 	return nil
 }
@@ -0,0 +1,32 @@
+package contract
+
+// gas of contract operations
+const (
+	GasCall     uint64 = 21000 // intrinsic gas of each call
+	GasByte     uint64 = 16    // each byte of call params
+	GasCreate   uint64 = 50000 // create a contract
+	GasWrite    uint64 = 20000 // update a role, group, order or pledge
+	GasTransfer uint64 = 5000  // move or approve token
+
+	// DefaultGasLimit is used when caller gives none
+	DefaultGasLimit uint64 = 1000000
+)
+
+// StartGas begins metering of a call; limit 0 means no limit
+func (s *State) StartGas(limit uint64) {
+	s.gasLimit = limit
+	s.gasUsed = 0
+}
+
+func (s *State) GasUsed() uint64 {
+	return s.gasUsed
+}
+
+// UseGas charges g; ErrOutOfGas is returned once limit is exceeded
+func (s *State) UseGas(g uint64) error {
+	s.gasUsed += g
+	if s.gasLimit > 0 && s.gasUsed > s.gasLimit {
+		return ErrOutOfGas
+	}
+	return nil
+}
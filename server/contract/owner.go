@@ -0,0 +1,128 @@
+package contract
+
+import (
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// OwnerInfo is owner of a contract and who is named to take it over
+type OwnerInfo struct {
+	Owner   utils.Address
+	Pending utils.Address // nil if no transfer is going on
+}
+
+// transferOwner names newOwner as pending owner of contract local;
+// nil newOwner cancels a pending transfer
+func (s *State) transferOwner(local, owner, caller, newOwner utils.Address, pending *utils.Address) error {
+	s.touch(local)
+
+	err := s.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	if caller != owner {
+		return ErrPermission
+	}
+
+	if newOwner == owner {
+		return ErrInput
+	}
+
+	*pending = newOwner
+
+	s.emit(local, types.EventOwnershipPending, nil, &types.OwnershipEvent{
+		Owner:    owner,
+		NewOwner: newOwner,
+	})
+
+	return nil
+}
+
+// acceptOwner makes pending owner the owner of contract local
+func (s *State) acceptOwner(local, caller utils.Address, owner, pending *utils.Address) error {
+	s.touch(local)
+
+	err := s.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	if *pending == utils.NilAddress || caller != *pending {
+		return ErrPermission
+	}
+
+	s.emit(local, types.EventOwnershipTransferred, nil, &types.OwnershipEvent{
+		Owner:    *owner,
+		NewOwner: caller,
+	})
+
+	*owner = caller
+	*pending = utils.NilAddress
+
+	return nil
+}
+
+func (e *ercToken) TransferOwnership(caller, newOwner utils.Address) error {
+	return e.state.transferOwner(e.local, e.admin, caller, newOwner, &e.pendingAdmin)
+}
+
+// AcceptOwnership moves no token, balance of old admin stays with it
+func (e *ercToken) AcceptOwnership(caller utils.Address) error {
+	return e.state.acceptOwner(e.local, caller, &e.admin, &e.pendingAdmin)
+}
+
+func (e *ercToken) GetOwnerInfo(caller utils.Address) *OwnerInfo {
+	return &OwnerInfo{Owner: e.admin, Pending: e.pendingAdmin}
+}
+
+// TransferOwnership of roleMgr is called directly only if threshold is 1,
+// else by proposal of OpTransferOwnership
+func (r *roleMgr) TransferOwnership(caller, newOwner utils.Address) error {
+	if r.threshold > 1 {
+		return ErrPermission
+	}
+	return r.state.transferOwner(r.local, r.admin, caller, newOwner, &r.pendingAdmin)
+}
+
+// AcceptOwnership replaces old admin by new one in admin set too
+func (r *roleMgr) AcceptOwnership(caller utils.Address) error {
+	r.state.touch(r.local)
+
+	old := r.admin
+	if caller == r.pendingAdmin && caller != utils.NilAddress {
+		err := r.replaceAdmin(old, caller)
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.state.acceptOwner(r.local, caller, &r.admin, &r.pendingAdmin)
+}
+
+func (r *roleMgr) replaceAdmin(old, addr utils.Address) error {
+	for i, a := range r.admins {
+		if a != old {
+			continue
+		}
+
+		if !r.isAdmin(addr) {
+			r.admins[i] = addr
+			return nil
+		}
+
+		// addr is admin already, drop old one
+		if len(r.admins)-1 < int(r.threshold) {
+			return ErrInput
+		}
+		r.admins = append(r.admins[:i:i], r.admins[i+1:]...)
+		return nil
+	}
+
+	// old is removed from admins
+	return nil
+}
+
+func (r *roleMgr) GetOwnerInfo(caller utils.Address) *OwnerInfo {
+	return &OwnerInfo{Owner: r.admin, Pending: r.pendingAdmin}
+}
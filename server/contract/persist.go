@@ -0,0 +1,737 @@
+package contract
+
+import (
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// key prefix of contracts in datastore
+const contractPrefix = "contract/"
+
+// contract types in datastore
+const (
+	typeErcToken uint8 = iota + 1
+	typeRoleMgr
+	typePledgeMgr
+	typeFsMgr
+)
+
+// canonical encoding, same state always has same bytes
+var encMode cbor.EncMode
+
+func init() {
+	em, err := cbor.CanonicalEncOptions().EncMode()
+	if err != nil {
+		panic(err)
+	}
+	encMode = em
+}
+
+type contractState struct {
+	Type uint8
+	Data []byte
+}
+
+// stateKey is multiKey in datastore
+type stateKey struct {
+	RoleIndex  uint64
+	TokenIndex uint32
+}
+
+func toStateKey(mk multiKey) stateKey {
+	return stateKey{
+		RoleIndex:  mk.roleIndex,
+		TokenIndex: mk.tokenIndex,
+	}
+}
+
+func (s stateKey) multiKey() multiKey {
+	return multiKey{
+		roleIndex:  s.RoleIndex,
+		tokenIndex: s.TokenIndex,
+	}
+}
+
+func contractKey(addr utils.Address) []byte {
+	return append([]byte(contractPrefix), addr[:]...)
+}
+
+// add puts new contract c at addr
+func (s *State) add(addr utils.Address, c interface{}) {
+	s.touch(addr)
+	s.contracts[addr] = c
+}
+
+// touch marks contract at addr written, and copies it for open snapshot;
+// each method which may change a contract calls it first
+func (s *State) touch(addr utils.Address) {
+	s.copyOnWrite(addr)
+	s.dirty[addr] = struct{}{}
+}
+
+// Save puts all contracts into batch
+func (s *State) Save(b store.Batch) error {
+	for addr, ci := range s.contracts {
+		val, err := encodeContract(ci)
+		if err != nil {
+			return err
+		}
+
+		b.Put(contractKey(addr), val)
+	}
+
+	return nil
+}
+
+// SaveDirty puts contracts written since last Clean into batch;
+// a written contract which is gone is deleted
+func (s *State) SaveDirty(b store.Batch) error {
+	for addr := range s.dirty {
+		ci, ok := s.contracts[addr]
+		if !ok {
+			b.Delete(contractKey(addr))
+			continue
+		}
+
+		val, err := encodeContract(ci)
+		if err != nil {
+			return err
+		}
+
+		b.Put(contractKey(addr), val)
+	}
+
+	return nil
+}
+
+// Clean forgets written contracts, after SaveDirty is committed
+func (s *State) Clean() {
+	s.dirty = make(map[utils.Address]struct{})
+}
+
+// Root is hash of all contracts, same state always has same root
+func (s *State) Root() (utils.Hash, error) {
+	cm := make(map[utils.Address][]byte, len(s.contracts))
+	for addr, ci := range s.contracts {
+		val, err := encodeContract(ci)
+		if err != nil {
+			return utils.NilHash, err
+		}
+		cm[addr] = val
+	}
+
+	// keys are sorted by canonical encoding
+	buf, err := encMode.Marshal(cm)
+	if err != nil {
+		return utils.NilHash, err
+	}
+
+	return utils.HashOf(buf), nil
+}
+
+func encodeContract(ci interface{}) ([]byte, error) {
+	var typ uint8
+	var data []byte
+	var err error
+	switch c := ci.(type) {
+	case *ercToken:
+		typ = typeErcToken
+		data, err = c.encode()
+	case *roleMgr:
+		typ = typeRoleMgr
+		data, err = c.encode()
+	case *pledgeMgr:
+		typ = typePledgeMgr
+		data, err = c.encode()
+	case *fsMgr:
+		typ = typeFsMgr
+		data, err = c.encode()
+	default:
+		return nil, ErrMisType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return encMode.Marshal(&contractState{Type: typ, Data: data})
+}
+
+// Load reads all contracts from ds into s
+func (s *State) Load(ds store.KVStore) error {
+	return ds.Iter([]byte(contractPrefix), func(key, value []byte) error {
+		cs := new(contractState)
+		err := cbor.Unmarshal(value, cs)
+		if err != nil {
+			return err
+		}
+
+		var local utils.Address
+		switch cs.Type {
+		case typeErcToken:
+			e := &ercToken{state: s}
+			err = e.decode(cs.Data)
+			local = e.local
+			s.contracts[local] = e
+		case typeRoleMgr:
+			r := &roleMgr{state: s}
+			err = r.decode(cs.Data)
+			local = r.local
+			s.contracts[local] = r
+		case typePledgeMgr:
+			p := &pledgeMgr{state: s}
+			err = p.decode(cs.Data)
+			local = p.local
+			s.contracts[local] = p
+		case typeFsMgr:
+			f := &fsMgr{state: s}
+			err = f.decode(cs.Data)
+			local = f.local
+			s.contracts[local] = f
+		default:
+			return ErrMisType
+		}
+		if err != nil {
+			return err
+		}
+
+		log.Debug("load contract: ", local)
+
+		return nil
+	})
+}
+
+// erc token
+
+type ercTokenState struct {
+	Local        utils.Address
+	Admin        utils.Address
+	PendingAdmin utils.Address
+	TotalSupply  *big.Int
+	Money        map[utils.Address]*big.Int
+	Allowed      map[utils.Address]map[utils.Address]*big.Int // owner->spender->value
+}
+
+func (e *ercToken) encode() ([]byte, error) {
+	es := &ercTokenState{
+		Local:        e.local,
+		Admin:        e.admin,
+		PendingAdmin: e.pendingAdmin,
+		TotalSupply:  e.totalSupply,
+		Money:        e.money,
+		Allowed:      make(map[utils.Address]map[utils.Address]*big.Int),
+	}
+
+	for tk, val := range e.allowed {
+		sm, ok := es.Allowed[tk.owner]
+		if !ok {
+			sm = make(map[utils.Address]*big.Int)
+			es.Allowed[tk.owner] = sm
+		}
+		sm[tk.spender] = val
+	}
+
+	return encMode.Marshal(es)
+}
+
+func (e *ercToken) decode(b []byte) error {
+	es := new(ercTokenState)
+	err := cbor.Unmarshal(b, es)
+	if err != nil {
+		return err
+	}
+
+	e.local = es.Local
+	e.admin = es.Admin
+	e.pendingAdmin = es.PendingAdmin
+	e.totalSupply = es.TotalSupply
+	e.money = es.Money
+	if e.money == nil {
+		e.money = make(map[utils.Address]*big.Int)
+	}
+	e.allowed = make(map[twoKey]*big.Int)
+	for owner, sm := range es.Allowed {
+		for spender, val := range sm {
+			e.allowed[twoKey{owner: owner, spender: spender}] = val
+		}
+	}
+
+	return nil
+}
+
+// role mgr
+
+type roleMgrState struct {
+	Local        utils.Address
+	Admin        utils.Address
+	PendingAdmin utils.Address
+	Pledge       utils.Address
+	Foundation   utils.Address
+
+	Admins    []utils.Address
+	Threshold uint16
+	Proposals []*Proposal
+
+	Addrs  []utils.Address
+	Info   map[utils.Address]*BaseInfo
+	Groups []*GroupInfo
+	Tokens []utils.Address
+	TInfo  map[utils.Address]*tokenInfo
+
+	PledgeKeeper *big.Int
+	PledgePro    *big.Int
+	TotalPledge  *big.Int
+
+	MintLevel int
+	Mint      []*MintInfo
+	LastMint  uint64
+	Start     uint64
+	Size      *big.Int
+	Price     *big.Int
+	SpaceTime *big.Int
+	TotalPaid *big.Int
+	TotalPay  *big.Int
+
+	SubPMap map[uint64]*big.Int
+	SubSMap map[uint64]*big.Int
+
+	ChangeDelay uint64
+	Changes     map[uint64]*AddrChange
+}
+
+func (r *roleMgr) encode() ([]byte, error) {
+	rs := &roleMgrState{
+		Local:        r.local,
+		Admin:        r.admin,
+		PendingAdmin: r.pendingAdmin,
+		Pledge:       r.pledge,
+		Foundation:   r.foundation,
+
+		Admins:    r.admins,
+		Threshold: r.threshold,
+		Proposals: r.proposals,
+
+		Addrs:  r.addrs,
+		Info:   r.info,
+		Groups: r.groups,
+		Tokens: r.tokens,
+		TInfo:  r.tInfo,
+
+		PledgeKeeper: r.pledgeKeeper,
+		PledgePro:    r.pledgePro,
+		TotalPledge:  r.totalPledge,
+
+		MintLevel: r.mintLevel,
+		Mint:      r.mint,
+		LastMint:  r.lastMint,
+		Start:     r.start,
+		Size:      r.size,
+		Price:     r.price,
+		SpaceTime: r.spaceTime,
+		TotalPaid: r.totalPaid,
+		TotalPay:  r.totalPay,
+
+		SubPMap: r.subPMap,
+		SubSMap: r.subSMap,
+
+		ChangeDelay: r.changeDelay,
+		Changes:     r.changes,
+	}
+
+	return encMode.Marshal(rs)
+}
+
+func (r *roleMgr) decode(b []byte) error {
+	rs := new(roleMgrState)
+	err := cbor.Unmarshal(b, rs)
+	if err != nil {
+		return err
+	}
+
+	r.local = rs.Local
+	r.admin = rs.Admin
+	r.pendingAdmin = rs.PendingAdmin
+	r.pledge = rs.Pledge
+	r.foundation = rs.Foundation
+
+	r.admins = rs.Admins
+	r.threshold = rs.Threshold
+	r.proposals = rs.Proposals
+	if len(r.admins) == 0 {
+		// state before admin set
+		r.admins = []utils.Address{r.admin}
+		r.threshold = 1
+	}
+
+	r.addrs = rs.Addrs
+	r.info = rs.Info
+	r.groups = rs.Groups
+	r.tokens = rs.Tokens
+	r.tInfo = rs.TInfo
+
+	r.pledgeKeeper = rs.PledgeKeeper
+	r.pledgePro = rs.PledgePro
+	r.totalPledge = rs.TotalPledge
+
+	r.mintLevel = rs.MintLevel
+	r.mint = rs.Mint
+	r.lastMint = rs.LastMint
+	r.start = rs.Start
+	r.size = rs.Size
+	r.price = rs.Price
+	r.spaceTime = rs.SpaceTime
+	r.totalPaid = rs.TotalPaid
+	r.totalPay = rs.TotalPay
+
+	r.subPMap = rs.SubPMap
+	r.subSMap = rs.SubSMap
+
+	r.changeDelay = rs.ChangeDelay
+	r.changes = rs.Changes
+
+	if r.info == nil {
+		r.info = make(map[utils.Address]*BaseInfo)
+	}
+	if r.tInfo == nil {
+		r.tInfo = make(map[utils.Address]*tokenInfo)
+	}
+	if r.subPMap == nil {
+		r.subPMap = make(map[uint64]*big.Int)
+	}
+	if r.subSMap == nil {
+		r.subSMap = make(map[uint64]*big.Int)
+	}
+	if r.changes == nil {
+		r.changes = make(map[uint64]*AddrChange)
+	}
+
+	return nil
+}
+
+// pledge mgr
+
+type rewardState struct {
+	RewardAccum *big.Int
+	LastReward  *big.Int
+}
+
+func toRewardState(ri *rewardInfo) *rewardState {
+	return &rewardState{
+		RewardAccum: ri.rewardAccum,
+		LastReward:  ri.lastReward,
+	}
+}
+
+func (rs *rewardState) rewardInfo() *rewardInfo {
+	return &rewardInfo{
+		rewardAccum: rs.RewardAccum,
+		lastReward:  rs.LastReward,
+	}
+}
+
+type pledgeMgrState struct {
+	Owner       utils.Address
+	Local       utils.Address
+	Token       uint32
+	Tokens      []utils.Address
+	TotalPledge *big.Int
+	Amount      map[stateKey]*rewardState
+	TInfo       map[uint32]*rewardState
+}
+
+func (p *pledgeMgr) encode() ([]byte, error) {
+	ps := &pledgeMgrState{
+		Owner:       p.owner,
+		Local:       p.local,
+		Token:       p.token,
+		Tokens:      p.tokens,
+		TotalPledge: p.totalPledge,
+		Amount:      make(map[stateKey]*rewardState, len(p.amount)),
+		TInfo:       make(map[uint32]*rewardState, len(p.tInfo)),
+	}
+
+	for mk, ri := range p.amount {
+		ps.Amount[toStateKey(mk)] = toRewardState(ri)
+	}
+
+	for ti, ri := range p.tInfo {
+		ps.TInfo[ti] = toRewardState(ri)
+	}
+
+	return encMode.Marshal(ps)
+}
+
+func (p *pledgeMgr) decode(b []byte) error {
+	ps := new(pledgeMgrState)
+	err := cbor.Unmarshal(b, ps)
+	if err != nil {
+		return err
+	}
+
+	p.owner = ps.Owner
+	p.local = ps.Local
+	p.token = ps.Token
+	p.tokens = ps.Tokens
+	p.totalPledge = ps.TotalPledge
+	p.amount = make(map[multiKey]*rewardInfo, len(ps.Amount))
+	p.tInfo = make(map[uint32]*rewardInfo, len(ps.TInfo))
+
+	for sk, rs := range ps.Amount {
+		p.amount[sk.multiKey()] = rs.rewardInfo()
+	}
+
+	for ti, rs := range ps.TInfo {
+		p.tInfo[ti] = rs.rewardInfo()
+	}
+
+	return nil
+}
+
+// fs mgr
+
+type storeInfoState struct {
+	Time  uint64
+	Size  uint64
+	Price *big.Int
+}
+
+type channelInfoState struct {
+	Amount *big.Int
+	Nonce  uint64
+	Expire uint64
+}
+
+type aggOrderState struct {
+	Nonce    uint64
+	SubNonce uint64
+	SInfo    map[uint32]*storeInfoState
+	Channel  map[uint32]*channelInfoState
+}
+
+type fsInfoState struct {
+	IsActive   bool
+	TokenIndex uint32
+	Providers  []uint64
+	Ao         map[uint64]*aggOrderState
+}
+
+func toFsInfoState(fi *fsInfo) *fsInfoState {
+	fs := &fsInfoState{
+		IsActive:   fi.isActive,
+		TokenIndex: fi.tokenIndex,
+		Providers:  fi.providers,
+		Ao:         make(map[uint64]*aggOrderState, len(fi.ao)),
+	}
+
+	for pi, ao := range fi.ao {
+		as := &aggOrderState{
+			Nonce:    ao.nonce,
+			SubNonce: ao.subNonce,
+			SInfo:    make(map[uint32]*storeInfoState, len(ao.sInfo)),
+		}
+
+		for ti, si := range ao.sInfo {
+			as.SInfo[ti] = &storeInfoState{
+				Time:  si.time,
+				Size:  si.size,
+				Price: si.price,
+			}
+		}
+
+		if ao.channel != nil {
+			as.Channel = make(map[uint32]*channelInfoState, len(ao.channel))
+			for ti, ci := range ao.channel {
+				as.Channel[ti] = &channelInfoState{
+					Amount: ci.amount,
+					Nonce:  ci.nonce,
+					Expire: ci.expire,
+				}
+			}
+		}
+
+		fs.Ao[pi] = as
+	}
+
+	return fs
+}
+
+func (fs *fsInfoState) fsInfo() *fsInfo {
+	fi := &fsInfo{
+		isActive:   fs.IsActive,
+		tokenIndex: fs.TokenIndex,
+		providers:  fs.Providers,
+		ao:         make(map[uint64]*aggOrder, len(fs.Ao)),
+	}
+
+	if fi.providers == nil {
+		fi.providers = make([]uint64, 0, 1)
+	}
+
+	for pi, as := range fs.Ao {
+		ao := &aggOrder{
+			nonce:    as.Nonce,
+			subNonce: as.SubNonce,
+			sInfo:    make(map[uint32]*storeInfo, len(as.SInfo)),
+		}
+
+		for ti, ss := range as.SInfo {
+			ao.sInfo[ti] = &storeInfo{
+				time:  ss.Time,
+				size:  ss.Size,
+				price: ss.Price,
+			}
+		}
+
+		if as.Channel != nil {
+			ao.channel = make(map[uint32]*channelInfo, len(as.Channel))
+			for ti, cs := range as.Channel {
+				ao.channel[ti] = &channelInfo{
+					amount: cs.Amount,
+					nonce:  cs.Nonce,
+					expire: cs.Expire,
+				}
+			}
+		}
+
+		fi.ao[pi] = ao
+	}
+
+	return fi
+}
+
+type fsMgrState struct {
+	Local utils.Address
+	Owner utils.Address
+
+	ManageRate int
+	TaxRate    int
+	GIndex     uint64
+	Foundation uint64
+
+	Balance map[stateKey]*big.Int
+	Penalty map[stateKey]*big.Int
+
+	Users    []uint64
+	Fs       map[uint64]*fsInfoState
+	RepairFs *fsInfoState
+
+	Keepers    []uint64
+	Period     uint64
+	LastTime   uint64
+	TAcc       map[uint32]*big.Int
+	TotalCount uint64
+	Count      map[uint64]uint64
+
+	Providers []uint64
+	ProInfo   map[stateKey]*Settlement
+
+	Tokens []uint32
+}
+
+func (f *fsMgr) encode() ([]byte, error) {
+	fs := &fsMgrState{
+		Local: f.local,
+		Owner: f.owner,
+
+		ManageRate: f.manageRate,
+		TaxRate:    f.taxRate,
+		GIndex:     f.gIndex,
+		Foundation: f.foundation,
+
+		Balance: make(map[stateKey]*big.Int, len(f.balance)),
+		Penalty: make(map[stateKey]*big.Int, len(f.penalty)),
+
+		Users:    f.users,
+		Fs:       make(map[uint64]*fsInfoState, len(f.fs)),
+		RepairFs: toFsInfoState(f.repairFs),
+
+		Keepers:    f.keepers,
+		Period:     f.period,
+		LastTime:   f.lastTime,
+		TAcc:       f.tAcc,
+		TotalCount: f.totalCount,
+		Count:      f.count,
+
+		Providers: f.providers,
+		ProInfo:   make(map[stateKey]*Settlement, len(f.proInfo)),
+
+		Tokens: f.tokens,
+	}
+
+	for mk, val := range f.balance {
+		fs.Balance[toStateKey(mk)] = val
+	}
+
+	for mk, val := range f.penalty {
+		fs.Penalty[toStateKey(mk)] = val
+	}
+
+	for ui, fi := range f.fs {
+		fs.Fs[ui] = toFsInfoState(fi)
+	}
+
+	for mk, se := range f.proInfo {
+		fs.ProInfo[toStateKey(mk)] = se
+	}
+
+	return encMode.Marshal(fs)
+}
+
+func (f *fsMgr) decode(b []byte) error {
+	fs := new(fsMgrState)
+	err := cbor.Unmarshal(b, fs)
+	if err != nil {
+		return err
+	}
+
+	f.local = fs.Local
+	f.owner = fs.Owner
+
+	f.manageRate = fs.ManageRate
+	f.taxRate = fs.TaxRate
+	f.gIndex = fs.GIndex
+	f.foundation = fs.Foundation
+
+	f.balance = make(map[multiKey]*big.Int, len(fs.Balance))
+	for sk, val := range fs.Balance {
+		f.balance[sk.multiKey()] = val
+	}
+
+	f.penalty = make(map[multiKey]*big.Int, len(fs.Penalty))
+	for sk, val := range fs.Penalty {
+		f.penalty[sk.multiKey()] = val
+	}
+
+	f.users = fs.Users
+	f.fs = make(map[uint64]*fsInfo, len(fs.Fs))
+	for ui, fis := range fs.Fs {
+		f.fs[ui] = fis.fsInfo()
+	}
+	f.repairFs = fs.RepairFs.fsInfo()
+
+	f.keepers = fs.Keepers
+	f.period = fs.Period
+	f.lastTime = fs.LastTime
+	f.tAcc = fs.TAcc
+	f.totalCount = fs.TotalCount
+	f.count = fs.Count
+
+	f.providers = fs.Providers
+	f.proInfo = make(map[multiKey]*Settlement, len(fs.ProInfo))
+	for sk, se := range fs.ProInfo {
+		f.proInfo[sk.multiKey()] = se
+	}
+
+	f.tokens = fs.Tokens
+
+	if f.tAcc == nil {
+		f.tAcc = make(map[uint32]*big.Int)
+	}
+	if f.count == nil {
+		f.count = make(map[uint64]uint64)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package contract
 import (
 	"math/big"
 
+	"github.com/memoio/go-settlement/server/types"
 	"github.com/memoio/go-settlement/utils"
 )
 
@@ -14,6 +15,8 @@ type rewardInfo struct {
 var _ PledgePool = (*pledgeMgr)(nil)
 
 type pledgeMgr struct {
+	state *State
+
 	owner       utils.Address
 	local       utils.Address            // contract utils.Address
 	token       uint32                   // largest token
@@ -23,11 +26,12 @@ type pledgeMgr struct {
 	tInfo       map[uint32]*rewardInfo
 }
 
-func NewPledgeMgr(caller, ptoken utils.Address) *pledgeMgr {
+func NewPledgeMgr(s *State, caller, ptoken utils.Address) *pledgeMgr {
 
 	local := utils.GetContractAddress(caller, []byte("PledgePool"))
 
 	pm := &pledgeMgr{
+		state:       s,
 		owner:       caller,
 		local:       local,
 		totalPledge: new(big.Int),
@@ -37,14 +41,16 @@ func NewPledgeMgr(caller, ptoken utils.Address) *pledgeMgr {
 		amount:      make(map[multiKey]*rewardInfo),
 	}
 
-	bal := getBalance(ptoken, local)
+	bal := s.getBalance(ptoken, local)
 	pm.tInfo[0] = &rewardInfo{
 		rewardAccum: big.NewInt(0),
 		lastReward:  bal,
 	}
 	pm.tokens = append(pm.tokens, ptoken)
 
-	globalMap[local] = pm
+	s.UseGas(GasCreate)
+
+	s.add(local, pm)
 
 	return pm
 }
@@ -60,7 +66,7 @@ func (p *pledgeMgr) GetOwnerAddress() utils.Address {
 func (p *pledgeMgr) GetPledge(caller utils.Address) []*big.Int {
 	res := make([]*big.Int, len(p.tokens))
 	for i, taddr := range p.tokens {
-		res[i] = new(big.Int).Set(getBalance(taddr, p.local))
+		res[i] = new(big.Int).Set(p.state.getBalance(taddr, p.local))
 	}
 	return res
 }
@@ -85,7 +91,7 @@ func (p *pledgeMgr) GetBalance(caller utils.Address, index uint64) []*big.Int {
 		ti := p.tInfo[uint32(i)]
 
 		val := new(big.Int).Set(ti.rewardAccum)
-		bal := getBalance(taddr, p.local)
+		bal := p.state.getBalance(taddr, p.local)
 		bal.Sub(bal, ti.lastReward)
 		if bal.Cmp(zero) > 0 && totalPledge.Cmp(zero) > 0 {
 			bal.Div(bal, totalPledge)
@@ -114,6 +120,8 @@ func (p *pledgeMgr) GetBalance(caller utils.Address, index uint64) []*big.Int {
 
 // by owner
 func (p *pledgeMgr) AddToken(caller, tAddr utils.Address, tokenIndex uint32) error {
+	p.state.touch(p.local)
+
 	if caller != p.owner {
 		return ErrPermission
 	}
@@ -122,7 +130,7 @@ func (p *pledgeMgr) AddToken(caller, tAddr utils.Address, tokenIndex uint32) err
 		return ErrInput
 	}
 
-	bal := getBalance(tAddr, p.local)
+	bal := p.state.getBalance(tAddr, p.local)
 	ti := &rewardInfo{
 		rewardAccum: big.NewInt(0),
 		lastReward:  bal,
@@ -136,6 +144,8 @@ func (p *pledgeMgr) AddToken(caller, tAddr utils.Address, tokenIndex uint32) err
 }
 
 func (p *pledgeMgr) Pledge(caller, addr utils.Address, index uint64, money *big.Int) error {
+	p.state.touch(p.local)
+
 	if caller != p.owner {
 		return ErrPermission
 	}
@@ -181,7 +191,7 @@ func (p *pledgeMgr) Pledge(caller, addr utils.Address, index uint64, money *big.
 			p.amount[mki] = rew
 		}
 
-		bal := getBalance(taddr, p.local)
+		bal := p.state.getBalance(taddr, p.local)
 		tv := new(big.Int).Sub(bal, ti.lastReward)
 		if tv.Cmp(zero) > 0 && totalPledge.Cmp(zero) > 0 {
 			tv.Div(tv, totalPledge)
@@ -196,7 +206,7 @@ func (p *pledgeMgr) Pledge(caller, addr utils.Address, index uint64, money *big.
 		rew.rewardAccum = new(big.Int).Set(ti.rewardAccum) // 更新acc
 	}
 
-	err := sendBalanceFrom(p.tokens[0], p.local, addr, p.local, money)
+	err := p.state.sendBalanceFrom(p.tokens[0], p.local, addr, p.local, money)
 	if err != nil {
 		return err
 	}
@@ -208,11 +218,18 @@ func (p *pledgeMgr) Pledge(caller, addr utils.Address, index uint64, money *big.
 	p.totalPledge.Sub(p.totalPledge, amount)
 	p.totalPledge.Add(p.totalPledge, p0.lastReward)
 
+	p.state.emit(p.local, types.EventPledged, []uint64{index}, &types.PledgedEvent{
+		Index:  index,
+		Amount: money,
+	})
+
 	return nil
 }
 
 // Withdraw tokens
 func (p *pledgeMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint32, money, lock *big.Int) error {
+	p.state.touch(p.local)
+
 	if money.Cmp(zero) < 0 {
 		return ErrInput
 	}
@@ -221,7 +238,7 @@ func (p *pledgeMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint
 		return ErrInput
 	}
 
-	rm, err := getRoleMgr(p.owner)
+	rm, err := p.state.GetRoleMgr(p.owner)
 	if err != nil {
 		return err
 	}
@@ -254,7 +271,7 @@ func (p *pledgeMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint
 			// update tokenInfo
 			ti := p.tInfo[uint32(i)]
 
-			bal := getBalance(taddr, p.local)
+			bal := p.state.getBalance(taddr, p.local)
 			tv := new(big.Int).Sub(bal, ti.lastReward)
 			if tv.Cmp(zero) > 0 && totalPledge.Cmp(zero) > 0 {
 				tv.Div(tv, totalPledge)
@@ -303,7 +320,7 @@ func (p *pledgeMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint
 
 	if rw.Cmp(zero) > 0 {
 		tAddr := p.tokens[tokenIndex]
-		err = sendBalance(tAddr, p.local, addr, rw)
+		err = p.state.sendBalance(tAddr, p.local, addr, rw)
 		if err != nil {
 			return err
 		}
@@ -314,6 +331,11 @@ func (p *pledgeMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint
 		// update value
 		pi.lastReward.Sub(pi.lastReward, rw)
 
+		p.state.emit(p.local, types.EventWithdrawn, []uint64{index}, &types.WithdrawnEvent{
+			Index:      index,
+			TokenIndex: tokenIndex,
+			Amount:     rw,
+		})
 	}
 
 	if tokenIndex == 0 {
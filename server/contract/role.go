@@ -3,6 +3,8 @@ package contract
 import (
 	"math/big"
 
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/server/types"
 	"github.com/memoio/go-settlement/utils"
 )
 
@@ -48,8 +50,15 @@ type MintInfo struct {
 var _ RoleMgr = (*roleMgr)(nil)
 
 type roleMgr struct {
-	local utils.Address // contract of this mgr
-	admin utils.Address // owner
+	state *State
+
+	local        utils.Address // contract of this mgr
+	admin        utils.Address // owner
+	pendingAdmin utils.Address
+
+	admins    []utils.Address // admin set, creator is first
+	threshold uint16          // approvals needed by admin ops
+	proposals []*Proposal
 
 	pledge     utils.Address // pledge pool
 	foundation utils.Address // foundation address
@@ -57,6 +66,9 @@ type roleMgr struct {
 	addrs []utils.Address // all
 	info  map[utils.Address]*BaseInfo
 
+	changeDelay uint64                 // of address change, zero is at once
+	changes     map[uint64]*AddrChange // pending address changes
+
 	// manage group
 	groups []*GroupInfo
 
@@ -82,8 +94,8 @@ type roleMgr struct {
 	subSMap map[uint64]*big.Int
 }
 
-// NewRoleMgr can be admin by mutiple signatures
-func NewRoleMgr(caller, foundation, primaryToken utils.Address, kPledge, pPledge *big.Int) RoleMgr {
+// NewRoleMgr is admined by caller, more admins and threshold are set by proposals
+func NewRoleMgr(s *State, caller, foundation, primaryToken utils.Address, kPledge, pPledge *big.Int) RoleMgr {
 	// generate local utils.Address from
 	local := utils.GetContractAddress(caller, []byte("RoleMgr"))
 
@@ -113,13 +125,19 @@ func NewRoleMgr(caller, foundation, primaryToken utils.Address, kPledge, pPledge
 	}
 
 	rm := &roleMgr{
-		admin:      caller,
-		local:      local,
+		state: s,
+		admin: caller,
+		local: local,
+
+		admins:     []utils.Address{caller},
+		threshold:  1,
 		foundation: foundation,
 
-		addrs:  make([]utils.Address, 0, 128),
-		info:   make(map[utils.Address]*BaseInfo),
-		groups: make([]*GroupInfo, 0, 1),
+		addrs: make([]utils.Address, 0, 128),
+		info:  make(map[utils.Address]*BaseInfo),
+
+		changes: make(map[uint64]*AddrChange),
+		groups:  make([]*GroupInfo, 0, 1),
 
 		tokens: make([]utils.Address, 0, 1),
 		tInfo:  make(map[utils.Address]*tokenInfo),
@@ -129,8 +147,8 @@ func NewRoleMgr(caller, foundation, primaryToken utils.Address, kPledge, pPledge
 		totalPledge:  big.NewInt(0),
 
 		mint:      mi,
-		start:     GetTime(),
-		lastMint:  GetTime(),
+		start:     s.GetTime(),
+		lastMint:  s.GetTime(),
 		mintLevel: 0,
 		size:      big.NewInt(0),
 		price:     big.NewInt(0),
@@ -157,10 +175,12 @@ func NewRoleMgr(caller, foundation, primaryToken utils.Address, kPledge, pPledge
 	rm.addrs = append(rm.addrs, foundation)
 	rm.info[foundation] = bi
 
-	pp := NewPledgeMgr(rm.local, primaryToken)
+	pp := NewPledgeMgr(s, rm.local, primaryToken)
 	rm.pledge = pp.GetContractAddress()
 
-	globalMap[local] = rm
+	s.UseGas(GasCreate)
+
+	s.add(local, rm)
 	return rm
 }
 
@@ -255,7 +275,22 @@ func (r *roleMgr) getInfo(index uint64) (*BaseInfo, error) {
 }
 
 func (r *roleMgr) RegisterToken(caller, taddr utils.Address) error {
-	// verify sign
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	err = r.checkAdmin(caller)
+	if err != nil {
+		return err
+	}
+
+	return r.registerToken(taddr)
+}
+
+func (r *roleMgr) registerToken(taddr utils.Address) error {
 	// chek existence
 	_, ok := r.tInfo[taddr]
 	if ok {
@@ -263,7 +298,7 @@ func (r *roleMgr) RegisterToken(caller, taddr utils.Address) error {
 		return ErrExist
 	}
 
-	pp, err := GetPledgePool(r.pledge)
+	pp, err := r.state.GetPledgePool(r.pledge)
 	if err != nil {
 		return err
 	}
@@ -279,7 +314,23 @@ func (r *roleMgr) RegisterToken(caller, taddr utils.Address) error {
 }
 
 func (r *roleMgr) Register(caller, addr utils.Address, signature []byte) error {
-	// verify sign
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	h, err := RegisterDigest(r.state.chainID, r.local, addr)
+	if err != nil {
+		return err
+	}
+
+	err = checkSign(caller, addr, h, signature)
+	if err != nil {
+		return err
+	}
+
 	// chek existence
 	_, ok := r.info[addr]
 	if ok {
@@ -294,10 +345,22 @@ func (r *roleMgr) Register(caller, addr utils.Address, signature []byte) error {
 	r.addrs = append(r.addrs, addr)
 	r.info[addr] = bi
 
+	r.state.emit(r.local, types.EventRegistered, []uint64{bi.Index}, &types.RegisteredEvent{
+		Index: bi.Index,
+		Addr:  addr,
+	})
+
 	return nil
 }
 
 func (r *roleMgr) RegisterKeeper(caller utils.Address, index uint64, blsKey, signature []byte) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	bi, err := r.getInfo(index)
 	if err != nil {
 		return err
@@ -308,7 +371,22 @@ func (r *roleMgr) RegisterKeeper(caller utils.Address, index uint64, blsKey, sig
 		return ErrRoleType
 	}
 
-	pp, err := GetPledgePool(r.pledge)
+	h, err := KeeperDigest(r.state.chainID, r.local, index, blsKey)
+	if err != nil {
+		return err
+	}
+
+	err = checkSign(caller, r.addrs[index], h, signature)
+	if err != nil {
+		return err
+	}
+
+	pk, err := checkBlsKey(blsKey)
+	if err != nil {
+		return err
+	}
+
+	pp, err := r.state.GetPledgePool(r.pledge)
 	if err != nil {
 		return err
 	}
@@ -323,13 +401,21 @@ func (r *roleMgr) RegisterKeeper(caller utils.Address, index uint64, blsKey, sig
 	}
 
 	bi.RoleType = RoleKeeper
-	bi.Extra = blsKey
+	bi.Extra = pk
+
+	r.emitRegistered(index, bi)
 
 	return nil
 }
 
 func (r *roleMgr) RegisterProvider(caller utils.Address, index uint64, signature []byte) error {
-	// verify sign
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	bi, err := r.getInfo(index)
 	if err != nil {
 		return err
@@ -340,7 +426,17 @@ func (r *roleMgr) RegisterProvider(caller utils.Address, index uint64, signature
 		return ErrRoleType
 	}
 
-	pp, err := GetPledgePool(r.pledge)
+	h, err := ProviderDigest(r.state.chainID, r.local, index)
+	if err != nil {
+		return err
+	}
+
+	err = checkSign(caller, r.addrs[index], h, signature)
+	if err != nil {
+		return err
+	}
+
+	pp, err := r.state.GetPledgePool(r.pledge)
 	if err != nil {
 		return err
 	}
@@ -356,10 +452,19 @@ func (r *roleMgr) RegisterProvider(caller utils.Address, index uint64, signature
 
 	bi.RoleType = RoleProvider
 
+	r.emitRegistered(index, bi)
+
 	return nil
 }
 
 func (r *roleMgr) RegisterUser(caller utils.Address, index, gIndex uint64, blsKey []byte) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	// verify sign
 
 	bi, err := r.getInfo(index)
@@ -382,7 +487,12 @@ func (r *roleMgr) RegisterUser(caller utils.Address, index, gIndex uint64, blsKe
 		return ErrPermission
 	}
 
-	fm, err := GetFsMgr(gi.FsAddr)
+	fm, err := r.state.GetFsMgr(gi.FsAddr)
+	if err != nil {
+		return err
+	}
+
+	pk, err := checkBlsKey(blsKey)
 	if err != nil {
 		return err
 	}
@@ -394,11 +504,22 @@ func (r *roleMgr) RegisterUser(caller utils.Address, index, gIndex uint64, blsKe
 
 	bi.RoleType = RoleUser
 	bi.GIndex = gIndex
-	bi.Extra = blsKey
+	bi.Extra = pk
+
+	r.emitRegistered(index, bi)
 
 	return nil
 }
 
+func (r *roleMgr) emitRegistered(index uint64, bi *BaseInfo) {
+	r.state.emit(r.local, types.EventRegistered, []uint64{index}, &types.RegisteredEvent{
+		Index:    index,
+		Addr:     r.addrs[index],
+		RoleType: bi.RoleType,
+		GIndex:   bi.GIndex,
+	})
+}
+
 // group related
 func (r *roleMgr) GetGroupInfo(caller utils.Address, index uint64) (*GroupInfo, error) {
 	if index >= uint64(len(r.groups)) {
@@ -423,10 +544,22 @@ func (r *roleMgr) getGroupInfo(index uint64) (*GroupInfo, error) {
 
 // CreateGroup
 func (r *roleMgr) CreateGroup(caller utils.Address, level uint16) error {
-	if caller != r.admin {
-		return ErrPermission
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
 	}
 
+	err = r.checkAdmin(caller)
+	if err != nil {
+		return err
+	}
+
+	return r.createGroup(level)
+}
+
+func (r *roleMgr) createGroup(level uint16) error {
 	gIndex := len(r.groups)
 
 	gi := &GroupInfo{
@@ -439,17 +572,30 @@ func (r *roleMgr) CreateGroup(caller utils.Address, level uint16) error {
 
 	r.groups = append(r.groups, gi)
 
-	fs, err := NewFsMgr(r.local, 0, uint64(gIndex))
+	fs, err := NewFsMgr(r.state, r.local, 0, uint64(gIndex))
 	if err != nil {
 		return err
 	}
 
 	gi.FsAddr = fs.GetContractAddress()
 
+	r.state.emit(r.local, types.EventGroupCreated, nil, &types.GroupCreatedEvent{
+		GIndex: uint64(gIndex),
+		Level:  level,
+		FsAddr: gi.FsAddr,
+	})
+
 	return nil
 }
 
 func (r *roleMgr) SetReady(caller utils.Address, gIndex uint64, ksigns [][]byte) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	if len(r.groups) <= int(gIndex) {
 		return ErrInput
 	}
@@ -459,23 +605,34 @@ func (r *roleMgr) SetReady(caller utils.Address, gIndex uint64, ksigns [][]byte)
 		return ErrPermission
 	}
 
-	if gi.IsActive {
+	// group is active once it has Level keepers, who sign it ready once
+	if gi.IsReady {
 		return ErrPermission
 	}
 
-	if len(ksigns) < int(gi.Level) {
-		return ErrPermission
+	h, err := ReadyDigest(r.state.chainID, r.local, gIndex)
+	if err != nil {
+		return err
 	}
 
-	// verfiy ksigns
+	err = r.checkKeepers(gi, h, ksigns)
+	if err != nil {
+		return err
+	}
 
 	gi.IsReady = true
 
 	return nil
 }
 
-func (r *roleMgr) AddKeeperToGroup(caller utils.Address, index, gIndex uint64, asign []byte) error {
-	// verify asign
+func (r *roleMgr) AddKeeperToGroup(caller utils.Address, index, gIndex uint64, asigns [][]byte) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	if len(r.groups) <= int(gIndex) {
 		return ErrInput
 	}
@@ -499,7 +656,18 @@ func (r *roleMgr) AddKeeperToGroup(caller utils.Address, index, gIndex uint64, a
 		return ErrPermission
 	}
 
-	fsMgr, err := GetFsMgr(gi.FsAddr)
+	// auth by threshold of admins
+	h, err := AddKeeperDigest(r.state.chainID, r.local, index, gIndex)
+	if err != nil {
+		return err
+	}
+
+	err = r.checkAdmins(caller, h, asigns)
+	if err != nil {
+		return err
+	}
+
+	fsMgr, err := r.state.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		return err
 	}
@@ -521,6 +689,13 @@ func (r *roleMgr) AddKeeperToGroup(caller utils.Address, index, gIndex uint64, a
 }
 
 func (r *roleMgr) AddProviderToGroup(caller utils.Address, index, gIndex uint64) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	// verify sign by addr[index]
 	if len(r.groups) <= int(gIndex) {
 		return ErrInput
@@ -558,18 +733,77 @@ func (r *roleMgr) GetPledge(caller utils.Address) (*big.Int, *big.Int) {
 	return r.pledgeKeeper, r.pledgePro
 }
 
-func (r *roleMgr) SetPledgeMoney(caller utils.Address, kPledge, pPledge *big.Int, signature []byte) error {
-	// verify sign(hash(kPLedge,pPledge))
+func (r *roleMgr) SetPledgeMoney(caller utils.Address, kPledge, pPledge *big.Int) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	err = r.checkAdmin(caller)
+	if err != nil {
+		return err
+	}
+
+	r.setPledgeMoney(kPledge, pPledge)
+	return nil
+}
+
+func (r *roleMgr) setPledgeMoney(kPledge, pPledge *big.Int) {
+	r.pledgeKeeper = new(big.Int).Set(kPledge)
+	r.pledgePro = new(big.Int).Set(pPledge)
+}
+
+func (r *roleMgr) GetMintInfo(caller utils.Address) []*MintInfo {
+	return r.mint
+}
+
+// SetMintInfo replaces mint table, levels reached are kept
+func (r *roleMgr) SetMintInfo(caller utils.Address, mint []*MintInfo) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	err = r.checkAdmin(caller)
+	if err != nil {
+		return err
+	}
+
+	if len(mint) <= r.mintLevel {
+		return ErrInput
+	}
+
+	mi := make([]*MintInfo, len(mint))
+	for i, m := range mint {
+		if m == nil {
+			return ErrInput
+		}
+		nm := *m
+		mi[i] = &nm
+	}
 
-	r.pledgeKeeper = kPledge
-	r.pledgePro = pPledge
+	r.mint = mi
 	return nil
 }
 
 // 质押，非流动性
 func (r *roleMgr) Pledge(caller utils.Address, index uint64, money *big.Int) error {
-	// verify sign
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
 
+	return r.pledgeFor(caller, index, money, r.checkAdmin(caller) == nil)
+}
+
+// pledgeFor pledges money for index; air drop by admin is paid by roleMgr
+func (r *roleMgr) pledgeFor(caller utils.Address, index uint64, money *big.Int, air bool) error {
 	bi, err := r.getInfo(index)
 	if err != nil {
 		return err
@@ -579,16 +813,15 @@ func (r *roleMgr) Pledge(caller utils.Address, index uint64, money *big.Int) err
 		return ErrPermission
 	}
 
-	pp, err := GetPledgePool(r.pledge)
+	pp, err := r.state.GetPledgePool(r.pledge)
 	if err != nil {
 		return err
 	}
 
 	addr := r.addrs[index]
-	if caller == r.admin {
-		// air drop
+	if air {
 		addr = r.local
-		pt, err := getErcToken(r.tokens[0])
+		pt, err := r.state.GetErcToken(r.tokens[0])
 		if err != nil {
 			return err
 		}
@@ -601,6 +834,13 @@ func (r *roleMgr) Pledge(caller utils.Address, index uint64, money *big.Int) err
 }
 
 func (r *roleMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint32, money *big.Int) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	if tokenIndex >= uint32(len(r.tokens)) {
 		return ErrInput
 	}
@@ -614,7 +854,7 @@ func (r *roleMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint32
 		return ErrPermission
 	}
 
-	pp, err := GetPledgePool(r.pledge)
+	pp, err := r.state.GetPledgePool(r.pledge)
 	if err != nil {
 		return err
 	}
@@ -630,6 +870,18 @@ func (r *roleMgr) Withdraw(caller utils.Address, index uint64, tokenIndex uint32
 }
 
 func (r *roleMgr) Recharge(caller utils.Address, index uint64, tokenIndex uint32, money *big.Int) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	return r.rechargeFor(caller, index, tokenIndex, money, r.checkAdmin(caller) == nil)
+}
+
+// rechargeFor recharges money to fs of index; air drop by admin is paid by roleMgr
+func (r *roleMgr) rechargeFor(caller utils.Address, index uint64, tokenIndex uint32, money *big.Int, air bool) error {
 	if tokenIndex >= uint32(len(r.tokens)) {
 		return ErrInput
 	}
@@ -644,16 +896,15 @@ func (r *roleMgr) Recharge(caller utils.Address, index uint64, tokenIndex uint32
 		return err
 	}
 
-	fm, err := GetFsMgr(gi.FsAddr)
+	fm, err := r.state.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		return err
 	}
 
 	addr := r.addrs[index]
-	if caller == r.admin {
-		// air drop
+	if air {
 		addr = r.local
-		pt, err := getErcToken(r.tokens[tokenIndex])
+		pt, err := r.state.GetErcToken(r.tokens[tokenIndex])
 		if err != nil {
 			return err
 		}
@@ -666,6 +917,13 @@ func (r *roleMgr) Recharge(caller utils.Address, index uint64, tokenIndex uint32
 }
 
 func (r *roleMgr) ProWithdraw(caller utils.Address, proIndex uint64, tokenIndex uint32, pay, lost *big.Int, ksigns [][]byte) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	if tokenIndex >= uint32(len(r.tokens)) {
 		return ErrInput
 	}
@@ -680,7 +938,22 @@ func (r *roleMgr) ProWithdraw(caller utils.Address, proIndex uint64, tokenIndex
 		return err
 	}
 
-	fm, err := GetFsMgr(gi.FsAddr)
+	h, err := ProWithdrawDigest(r.state.chainID, r.local, &message.ParasProWithdraw{
+		Index:      proIndex,
+		TokenIndex: tokenIndex,
+		Amount:     pay,
+		Lost:       lost,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = r.checkKeepers(gi, h, ksigns)
+	if err != nil {
+		return err
+	}
+
+	fm, err := r.state.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		return err
 	}
@@ -706,6 +979,13 @@ func (r *roleMgr) ProWithdraw(caller utils.Address, proIndex uint64, tokenIndex
 }
 
 func (r *roleMgr) WithdrawFromFs(caller utils.Address, index uint64, tokenIndex uint32, amount *big.Int) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	if tokenIndex >= uint32(len(r.tokens)) {
 		return ErrInput
 	}
@@ -720,7 +1000,7 @@ func (r *roleMgr) WithdrawFromFs(caller utils.Address, index uint64, tokenIndex
 		return err
 	}
 
-	fm, err := GetFsMgr(gi.FsAddr)
+	fm, err := r.state.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		return err
 	}
@@ -730,6 +1010,13 @@ func (r *roleMgr) WithdrawFromFs(caller utils.Address, index uint64, tokenIndex
 
 // order ops
 func (r *roleMgr) AddOrder(caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	log.Info("AddOrder")
 
 	// check params
@@ -747,9 +1034,6 @@ func (r *roleMgr) AddOrder(caller utils.Address, user, proIndex, start, end, siz
 		return ErrInput
 	}
 
-	// verify ksigns
-	// verify usign
-	// verify psign
 	ui, err := r.getInfo(user)
 	if err != nil {
 		return err
@@ -779,7 +1063,12 @@ func (r *roleMgr) AddOrder(caller utils.Address, user, proIndex, start, end, siz
 		return err
 	}
 
-	fm, err := GetFsMgr(gi.FsAddr)
+	err = r.checkOrder(caller, utils.DomainAddOrder, gi, user, proIndex, start, end, size, nonce, tokenIndex, sprice, usign, psign, ksigns)
+	if err != nil {
+		return err
+	}
+
+	fm, err := r.state.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		return err
 	}
@@ -811,7 +1100,7 @@ func (r *roleMgr) AddOrder(caller utils.Address, user, proIndex, start, end, siz
 		}
 
 		// todo: edge case ok?
-		ntime := GetTime()
+		ntime := r.state.GetTime()
 		if ntime-r.lastMint > 86400 {
 			ntime = r.lastMint + 86400
 		}
@@ -874,7 +1163,7 @@ func (r *roleMgr) AddOrder(caller utils.Address, user, proIndex, start, end, siz
 		reward := new(big.Int).Mul(paid, new(big.Int).SetUint64(uint64(r.mint[r.mintLevel].Ratio)))
 		reward.Div(reward, new(big.Int).SetUint64(100))
 
-		err = sendBalance(r.tokens[0], r.local, r.pledge, reward)
+		err = r.state.sendBalance(r.tokens[0], r.local, r.pledge, reward)
 		if err != nil {
 			return err
 		}
@@ -889,6 +1178,13 @@ func (r *roleMgr) AddOrder(caller utils.Address, user, proIndex, start, end, siz
 }
 
 func (r *roleMgr) SubOrder(caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	ui, err := r.getInfo(user)
 	if err != nil {
 		return err
@@ -922,7 +1218,12 @@ func (r *roleMgr) SubOrder(caller utils.Address, user, proIndex, start, end, siz
 		kindex = ki.Index
 	}
 
-	fm, err := GetFsMgr(gi.FsAddr)
+	err = r.checkOrder(caller, utils.DomainSubOrder, gi, user, proIndex, start, end, size, nonce, tokenIndex, sprice, usign, psign, ksigns)
+	if err != nil {
+		return err
+	}
+
+	fm, err := r.state.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		return err
 	}
@@ -940,10 +1241,14 @@ func (r *roleMgr) SubOrder(caller utils.Address, user, proIndex, start, end, siz
 
 // order ops
 func (r *roleMgr) AddRepair(caller utils.Address, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) error {
-	log.Info("AddOrder")
-	// verify ksigns
-	// verify psign
+	r.state.touch(r.local)
 
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
+	log.Info("AddOrder")
 	pi, err := r.getInfo(proIndex)
 	if err != nil {
 		return err
@@ -977,7 +1282,12 @@ func (r *roleMgr) AddRepair(caller utils.Address, proIndex, newPro, start, end,
 		return err
 	}
 
-	fm, err := GetFsMgr(gi.FsAddr)
+	err = r.checkRepair(caller, utils.DomainAddRepair, gi, proIndex, newPro, start, end, size, nonce, tokenIndex, sprice, psign, ksigns)
+	if err != nil {
+		return err
+	}
+
+	fm, err := r.state.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		return err
 	}
@@ -1003,6 +1313,13 @@ func (r *roleMgr) AddRepair(caller utils.Address, proIndex, newPro, start, end,
 }
 
 func (r *roleMgr) SubRepair(caller utils.Address, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) error {
+	r.state.touch(r.local)
+
+	err := r.state.UseGas(GasWrite)
+	if err != nil {
+		return err
+	}
+
 	pi, err := r.getInfo(newPro)
 	if err != nil {
 		return err
@@ -1023,7 +1340,12 @@ func (r *roleMgr) SubRepair(caller utils.Address, proIndex, newPro, start, end,
 		kindex = ki.Index
 	}
 
-	fm, err := GetFsMgr(gi.FsAddr)
+	err = r.checkRepair(caller, utils.DomainSubRepair, gi, proIndex, newPro, start, end, size, nonce, tokenIndex, sprice, psign, ksigns)
+	if err != nil {
+		return err
+	}
+
+	fm, err := r.state.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		return err
 	}
@@ -13,6 +13,9 @@ import (
 	"github.com/memoio/go-settlement/utils"
 )
 
+// state shared by tests in this package
+var testState = NewState(nil)
+
 func TestReflect(t *testing.T) {
 	bi := &GroupInfo{
 		IsActive: true,
@@ -79,9 +82,9 @@ func testErc(t *testing.T) utils.Address {
 	}
 	adminAddr := utils.ToAddress(adminkey.PubKey)
 
-	et := NewErcToken(adminAddr)
+	et := NewErcToken(testState, adminAddr)
 
-	t.Log("et token adminAddr:", et.GetOwnerAddress(), "local:", et.GetContractAddress(), "value:", getBalance(et.GetContractAddress(), adminAddr))
+	t.Log("et token adminAddr:", et.GetOwnerAddress(), "local:", et.GetContractAddress(), "value:", testState.getBalance(et.GetContractAddress(), adminAddr))
 
 	userkey, err := utils.GenerateKey(rand.Reader)
 	if err != nil {
@@ -89,12 +92,12 @@ func testErc(t *testing.T) utils.Address {
 	}
 
 	userAddr := utils.ToAddress(userkey.PubKey)
-	err = sendBalance(et.GetContractAddress(), adminAddr, userAddr, big.NewInt(100000000))
+	err = testState.sendBalance(et.GetContractAddress(), adminAddr, userAddr, big.NewInt(100000000))
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if getBalance(et.GetContractAddress(), userAddr).Cmp(big.NewInt(100000000)) != 0 {
+	if testState.getBalance(et.GetContractAddress(), userAddr).Cmp(big.NewInt(100000000)) != 0 {
 		t.Fatal("balance is not right")
 	}
 
@@ -105,26 +108,26 @@ func testErc(t *testing.T) utils.Address {
 
 	userAddr2 := utils.ToAddress(userkey2.PubKey)
 
-	err = sendBalanceFrom(et.GetContractAddress(), userAddr2, userAddr, userAddr2, big.NewInt(20000000))
+	err = testState.sendBalanceFrom(et.GetContractAddress(), userAddr2, userAddr, userAddr2, big.NewInt(20000000))
 	if err == nil {
 		t.Fatal("should fail")
 	}
 
-	approve(et.GetContractAddress(), userAddr, userAddr2, big.NewInt(20000000))
-	err = sendBalanceFrom(et.GetContractAddress(), userAddr2, userAddr, userAddr2, big.NewInt(20000000))
+	testState.approve(et.GetContractAddress(), userAddr, userAddr2, big.NewInt(20000000))
+	err = testState.sendBalanceFrom(et.GetContractAddress(), userAddr2, userAddr, userAddr2, big.NewInt(20000000))
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if getBalance(et.GetContractAddress(), userAddr).Cmp(big.NewInt(80000000)) != 0 {
+	if testState.getBalance(et.GetContractAddress(), userAddr).Cmp(big.NewInt(80000000)) != 0 {
 		t.Fatal("balance is not right")
 	}
 
-	if getBalance(et.GetContractAddress(), userAddr2).Cmp(big.NewInt(20000000)) != 0 {
+	if testState.getBalance(et.GetContractAddress(), userAddr2).Cmp(big.NewInt(20000000)) != 0 {
 		t.Fatal("balance is not right")
 	}
 
-	t.Log(getBalance(et.GetContractAddress(), userAddr), getBalance(et.GetContractAddress(), userAddr2))
+	t.Log(testState.getBalance(et.GetContractAddress(), userAddr), testState.getBalance(et.GetContractAddress(), userAddr2))
 
 	return et.GetContractAddress()
 }
@@ -137,30 +140,30 @@ func testCreateRoleMgr(t *testing.T, tAddr utils.Address) utils.Address {
 
 	adminAddr := utils.ToAddress(adminkey.PubKey)
 
-	et, err := getErcToken(tAddr)
+	et, err := testState.GetErcToken(tAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	rm := NewRoleMgr(adminAddr, adminAddr, et.GetContractAddress(), big.NewInt(123450), big.NewInt(12345))
+	rm := NewRoleMgr(testState, adminAddr, adminAddr, et.GetContractAddress(), big.NewInt(123450), big.NewInt(12345))
 
-	err = sendBalance(tAddr, et.GetOwnerAddress(), rm.GetContractAddress(), big.NewInt(Token))
+	err = testState.sendBalance(tAddr, et.GetOwnerAddress(), rm.GetContractAddress(), big.NewInt(Token))
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Log("roleMgr has bal:", getBalance(tAddr, rm.GetContractAddress()))
+	t.Log("roleMgr has bal:", testState.getBalance(tAddr, rm.GetContractAddress()))
 
 	return rm.GetContractAddress()
 }
 
 func testAddToken(t *testing.T, rAddr, tAddr utils.Address) {
-	et, err := getErcToken(tAddr)
+	et, err := testState.GetErcToken(tAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -183,7 +186,7 @@ func testAddToken(t *testing.T, rAddr, tAddr utils.Address) {
 }
 
 func testPledge(t *testing.T, rAddr utils.Address, amount *big.Int) uint64 {
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -194,7 +197,7 @@ func testPledge(t *testing.T, rAddr utils.Address, amount *big.Int) uint64 {
 		t.Log("wrong token")
 	}
 
-	pt, err := getErcToken(ts[0])
+	pt, err := testState.GetErcToken(ts[0])
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -233,18 +236,18 @@ func testPledge(t *testing.T, rAddr utils.Address, amount *big.Int) uint64 {
 
 	pt.Approve(userAddr, rm.GetPledgeAddress(userAddr), amount)
 
-	t.Log("pledge has:", getBalance(ts[0], rm.GetPledgeAddress(userAddr)))
+	t.Log("pledge has:", testState.getBalance(ts[0], rm.GetPledgeAddress(userAddr)))
 
 	err = rm.Pledge(userAddr, ui.Index, amount)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Log("after pledge has:", getBalance(ts[0], rm.GetPledgeAddress(userAddr)))
+	t.Log("after pledge has:", testState.getBalance(ts[0], rm.GetPledgeAddress(userAddr)))
 
 	pAddr := rm.GetPledgeAddress(userAddr)
 
-	pp, err := GetPledgePool(pAddr)
+	pp, err := testState.GetPledgePool(pAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -262,14 +265,14 @@ func testPledge(t *testing.T, rAddr utils.Address, amount *big.Int) uint64 {
 }
 
 func testWithdrawPledge(t *testing.T, rAddr utils.Address, Index uint64, tIndex uint32, send bool) {
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	ts := rm.GetAllTokens(rm.GetOwnerAddress())
 
-	et, err := getErcToken(ts[tIndex])
+	et, err := testState.GetErcToken(ts[tIndex])
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -288,7 +291,7 @@ func testWithdrawPledge(t *testing.T, rAddr utils.Address, Index uint64, tIndex
 
 	pAddr := rm.GetPledgeAddress(userAddr)
 
-	pp, err := GetPledgePool(pAddr)
+	pp, err := testState.GetPledgePool(pAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -338,7 +341,7 @@ func testWithdrawPledge(t *testing.T, rAddr utils.Address, Index uint64, tIndex
 
 func testCreateKeeper(t *testing.T, rAddr utils.Address) uint64 {
 
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -346,7 +349,13 @@ func testCreateKeeper(t *testing.T, rAddr utils.Address) uint64 {
 	kPledge, _ := rm.GetPledge(rAddr)
 	Index := testPledge(t, rAddr, new(big.Int).Mul(kPledge, big.NewInt(10)))
 
-	err = rm.RegisterKeeper(rm.GetOwnerAddress(), Index, nil, nil)
+	// role registers itself, its call is the signature
+	_, addr, err := rm.GetInfo(rAddr, Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rm.RegisterKeeper(addr, Index, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -355,7 +364,7 @@ func testCreateKeeper(t *testing.T, rAddr utils.Address) uint64 {
 }
 
 func testCreateProvider(t *testing.T, rAddr utils.Address) uint64 {
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -363,7 +372,13 @@ func testCreateProvider(t *testing.T, rAddr utils.Address) uint64 {
 	_, pPledge := rm.GetPledge(rAddr)
 	Index := testPledge(t, rAddr, new(big.Int).Mul(pPledge, big.NewInt(10)))
 
-	err = rm.RegisterProvider(rm.GetOwnerAddress(), Index, nil)
+	// role registers itself, its call is the signature
+	_, addr, err := rm.GetInfo(rAddr, Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rm.RegisterProvider(addr, Index, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -372,7 +387,7 @@ func testCreateProvider(t *testing.T, rAddr utils.Address) uint64 {
 }
 
 func testCreateGroup(t *testing.T, rAddr utils.Address) uint64 {
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -389,7 +404,7 @@ func testCreateGroup(t *testing.T, rAddr utils.Address) uint64 {
 
 func testAddKeeper(t *testing.T, rAddr utils.Address, gIndex uint64) uint64 {
 	kindex := testCreateKeeper(t, rAddr)
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -415,7 +430,7 @@ func testAddKeeper(t *testing.T, rAddr utils.Address, gIndex uint64) uint64 {
 
 func testAddProvider(t *testing.T, rAddr utils.Address, gIndex uint64) uint64 {
 	pindex := testCreateProvider(t, rAddr)
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -442,7 +457,7 @@ func testAddProvider(t *testing.T, rAddr utils.Address, gIndex uint64) uint64 {
 func testCreateUser(t *testing.T, rAddr utils.Address, gIndex uint64) uint64 {
 	uindex := testPledge(t, rAddr, big.NewInt(4000))
 
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -458,7 +473,7 @@ func testCreateUser(t *testing.T, rAddr utils.Address, gIndex uint64) uint64 {
 		t.Log("wrong token")
 	}
 
-	pt, err := getErcToken(ts[0])
+	pt, err := testState.GetErcToken(ts[0])
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -485,7 +500,7 @@ func testCreateUser(t *testing.T, rAddr utils.Address, gIndex uint64) uint64 {
 	pt.Transfer(pt.GetOwnerAddress(), userAddr, big.NewInt(1000000000000))
 	pt.Approve(userAddr, gi.FsAddr, big.NewInt(1000000000000))
 
-	fm, err := GetFsMgr(gi.FsAddr)
+	fm, err := testState.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -504,7 +519,7 @@ func testCreateUser(t *testing.T, rAddr utils.Address, gIndex uint64) uint64 {
 }
 
 func testAddOrder(t *testing.T, rAddr utils.Address, kIndex, userIndex, proIndex, start, end, size, nonce uint64) {
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -518,7 +533,7 @@ func testAddOrder(t *testing.T, rAddr utils.Address, kIndex, userIndex, proIndex
 	if err != nil {
 		t.Fatal(err)
 	}
-	fm, err := GetFsMgr(gi.FsAddr)
+	fm, err := testState.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -557,7 +572,7 @@ func testAddOrder(t *testing.T, rAddr utils.Address, kIndex, userIndex, proIndex
 }
 
 func testSubOrder(t *testing.T, rAddr utils.Address, kIndex, userIndex, proIndex, start, end, size, nonce uint64) {
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -574,7 +589,7 @@ func testSubOrder(t *testing.T, rAddr utils.Address, kIndex, userIndex, proIndex
 }
 
 func testProWithdraw(t *testing.T, rAddr utils.Address, proIndex uint64, amount, lost *big.Int) {
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -585,7 +600,7 @@ func testProWithdraw(t *testing.T, rAddr utils.Address, proIndex uint64, amount,
 		t.Log("wrong token")
 	}
 
-	pt, err := getErcToken(ts[0])
+	pt, err := testState.GetErcToken(ts[0])
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -601,7 +616,7 @@ func testProWithdraw(t *testing.T, rAddr utils.Address, proIndex uint64, amount,
 	if err != nil {
 		t.Fatal(err)
 	}
-	fm, err := GetFsMgr(gi.FsAddr)
+	fm, err := testState.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -632,7 +647,7 @@ func testProWithdraw(t *testing.T, rAddr utils.Address, proIndex uint64, amount,
 }
 
 func testFsWithdraw(t *testing.T, rAddr utils.Address, kIndex uint64, amount *big.Int) {
-	rm, err := getRoleMgr(rAddr)
+	rm, err := testState.GetRoleMgr(rAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -643,7 +658,7 @@ func testFsWithdraw(t *testing.T, rAddr utils.Address, kIndex uint64, amount *bi
 		t.Log("wrong token")
 	}
 
-	pt, err := getErcToken(ts[0])
+	pt, err := testState.GetErcToken(ts[0])
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -659,7 +674,7 @@ func testFsWithdraw(t *testing.T, rAddr utils.Address, kIndex uint64, amount *bi
 	if err != nil {
 		t.Fatal(err)
 	}
-	fm, err := GetFsMgr(gi.FsAddr)
+	fm, err := testState.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		t.Fatal(err)
 	}
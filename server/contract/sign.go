@@ -0,0 +1,217 @@
+package contract
+
+import (
+	"math/big"
+
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// digests signed by roles, rm is address of roleMgr
+
+func RegisterDigest(chainID uint64, rm, addr utils.Address) (utils.Hash, error) {
+	return utils.Digest(utils.DomainRegister, chainID, rm, addr)
+}
+
+func KeeperDigest(chainID uint64, rm utils.Address, index uint64, blsKey []byte) (utils.Hash, error) {
+	return utils.Digest(utils.DomainKeeper, chainID, rm, index, blsKey)
+}
+
+func ProviderDigest(chainID uint64, rm utils.Address, index uint64) (utils.Hash, error) {
+	return utils.Digest(utils.DomainProvider, chainID, rm, index)
+}
+
+func AddKeeperDigest(chainID uint64, rm utils.Address, index, gIndex uint64) (utils.Hash, error) {
+	return utils.Digest(utils.DomainAddKeeper, chainID, rm, index, gIndex)
+}
+
+func ReadyDigest(chainID uint64, rm utils.Address, gIndex uint64) (utils.Hash, error) {
+	return utils.Digest(utils.DomainReady, chainID, rm, gIndex)
+}
+
+// OrderDigest is signed by user, provider and keepers; tag is DomainAddOrder or DomainSubOrder
+func OrderDigest(tag string, chainID uint64, rm utils.Address, p *message.ParasOrder) (utils.Hash, error) {
+	return utils.Digest(tag, chainID, rm, p)
+}
+
+// RepairDigest is signed by new provider and keepers; tag is DomainAddRepair or DomainSubRepair.
+// User of p is the old provider, Provider of p is the new one
+func RepairDigest(tag string, chainID uint64, rm utils.Address, p *message.ParasOrder) (utils.Hash, error) {
+	return utils.Digest(tag, chainID, rm, p)
+}
+
+// ProWithdrawDigest is signed by keepers
+func ProWithdrawDigest(chainID uint64, rm utils.Address, p *message.ParasProWithdraw) (utils.Hash, error) {
+	return utils.Digest(utils.DomainProWithdraw, chainID, rm, p)
+}
+
+// ChangeAddressDigest is signed by both old and new address of index
+func ChangeAddressDigest(chainID uint64, rm utils.Address, index uint64, newAddr utils.Address) (utils.Hash, error) {
+	return utils.Digest(utils.DomainChangeAddress, chainID, rm, index, newAddr)
+}
+
+// checkSign verifies sig of addr on h; empty sig is accepted from addr
+// itself, as the call is signed by caller
+func checkSign(caller, addr utils.Address, h utils.Hash, sig []byte) error {
+	if len(sig) == 0 {
+		if caller == addr {
+			return nil
+		}
+		return ErrSign
+	}
+
+	if !utils.Verify(addr, h[:], sig) {
+		return ErrSign
+	}
+
+	return nil
+}
+
+// KeeperAggSign packs aggregated BLS sig of keepers and bitmap of signers
+// as ksigns; bit i, from low bit of first byte, is i-th keeper of group
+func KeeperAggSign(sig, bitmap []byte) [][]byte {
+	ks := make([]byte, 0, len(sig)+len(bitmap))
+	ks = append(ks, sig...)
+	ks = append(ks, bitmap...)
+	return [][]byte{ks}
+}
+
+// checkBlsKey checks blsKey given at registration, which is BLS public key
+// followed by its proof of possession, and returns the public key;
+// empty blsKey means the role has no BLS key
+func checkBlsKey(blsKey []byte) ([]byte, error) {
+	if len(blsKey) == 0 {
+		return nil, nil
+	}
+
+	if len(blsKey) != utils.BlsPubKeyLength+utils.BlsSignatureLength {
+		return nil, utils.ErrBlsKey
+	}
+
+	pk := blsKey[:utils.BlsPubKeyLength]
+	err := utils.BlsCheckKey(pk, blsKey[utils.BlsPubKeyLength:])
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), pk...), nil
+}
+
+// checkKeepers verifies ksigns[i] by i-th keeper of gi on h, empty one is
+// skipped, or one aggregated BLS sig packed by KeeperAggSign;
+// at least Level keepers of gi must have signed
+func (r *roleMgr) checkKeepers(gi *GroupInfo, h utils.Hash, ksigns [][]byte) error {
+	if len(ksigns) == 1 && len(ksigns[0]) > utils.BlsSignatureLength {
+		return r.checkAggKeepers(gi, h, ksigns[0])
+	}
+
+	if len(ksigns) > len(gi.Keepers) {
+		return ErrSign
+	}
+
+	signed := 0
+	for i, ks := range ksigns {
+		if len(ks) == 0 {
+			continue
+		}
+
+		if !utils.Verify(r.addrs[gi.Keepers[i]], h[:], ks) {
+			return ErrSign
+		}
+		signed++
+	}
+
+	if signed < int(gi.Level) {
+		return ErrQuorum
+	}
+
+	return nil
+}
+
+// checkAggKeepers verifies aggregated sig of keepers in bitmap of ks on h
+func (r *roleMgr) checkAggKeepers(gi *GroupInfo, h utils.Hash, ks []byte) error {
+	sig, bitmap := ks[:utils.BlsSignatureLength], ks[utils.BlsSignatureLength:]
+	if len(bitmap) > (len(gi.Keepers)+7)/8 {
+		return ErrSign
+	}
+
+	pks := make([][]byte, 0, len(gi.Keepers))
+	for i := 0; i < len(bitmap)*8; i++ {
+		if bitmap[i/8]&(1<<(i%8)) == 0 {
+			continue
+		}
+
+		if i >= len(gi.Keepers) {
+			return ErrSign
+		}
+
+		ki, ok := r.info[r.addrs[gi.Keepers[i]]]
+		if !ok || len(ki.Extra) != utils.BlsPubKeyLength {
+			return ErrSign
+		}
+		pks = append(pks, ki.Extra)
+	}
+
+	if len(pks) < int(gi.Level) {
+		return ErrQuorum
+	}
+
+	if !utils.BlsVerifyAggregate(pks, h[:], sig) {
+		return ErrSign
+	}
+
+	return nil
+}
+
+// checkOrder verifies usign, psign and ksigns of an order op of tag
+func (r *roleMgr) checkOrder(caller utils.Address, tag string, gi *GroupInfo, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error {
+	h, err := OrderDigest(tag, r.state.chainID, r.local, &message.ParasOrder{
+		User:       user,
+		Provider:   proIndex,
+		Start:      start,
+		End:        end,
+		Size:       size,
+		Nonce:      nonce,
+		TokenIndex: tokenIndex,
+		Price:      sprice,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkSign(caller, r.addrs[user], h, usign)
+	if err != nil {
+		return err
+	}
+
+	err = checkSign(caller, r.addrs[proIndex], h, psign)
+	if err != nil {
+		return err
+	}
+
+	return r.checkKeepers(gi, h, ksigns)
+}
+
+// checkRepair verifies psign of new provider and ksigns of a repair op of tag
+func (r *roleMgr) checkRepair(caller utils.Address, tag string, gi *GroupInfo, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) error {
+	h, err := RepairDigest(tag, r.state.chainID, r.local, &message.ParasOrder{
+		User:       proIndex,
+		Provider:   newPro,
+		Start:      start,
+		End:        end,
+		Size:       size,
+		Nonce:      nonce,
+		TokenIndex: tokenIndex,
+		Price:      sprice,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = checkSign(caller, r.addrs[newPro], h, psign)
+	if err != nil {
+		return err
+	}
+
+	return r.checkKeepers(gi, h, ksigns)
+}
@@ -0,0 +1,232 @@
+package contract
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestCheckOrder(t *testing.T) {
+	r := &roleMgr{
+		state: NewState(nil),
+		local: utils.GetContractAddress(utils.NilAddress, []byte("RoleMgr")),
+	}
+
+	keys := make([]*utils.Key, 5)
+	for i := range keys {
+		k, err := utils.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = k
+		r.addrs = append(r.addrs, utils.ToAddress(k.PubKey))
+	}
+
+	// 0 is user, 1 is provider, others are keepers
+	gi := &GroupInfo{
+		Level:   2,
+		Keepers: []uint64{2, 3, 4},
+	}
+	sprice := big.NewInt(600)
+
+	h, err := OrderDigest(utils.DomainAddOrder, r.state.chainID, r.local, &message.ParasOrder{
+		User:     0,
+		Provider: 1,
+		Start:    100,
+		End:      86400,
+		Size:     300,
+		Price:    sprice,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sigs := make([][]byte, len(keys))
+	for i, k := range keys {
+		sigs[i], err = utils.Sign(k.SecretKey, h[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(usign, psign []byte, ksigns [][]byte) error {
+		return r.checkOrder(r.addrs[2], utils.DomainAddOrder, gi, 0, 1, 100, 86400, 300, 0, 0, sprice, usign, psign, ksigns)
+	}
+
+	err = check(sigs[0], sigs[1], [][]byte{sigs[2], nil, sigs[4]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = check(nil, sigs[1], [][]byte{sigs[2], sigs[3]})
+	if err != ErrSign {
+		t.Fatal("order without usign should fail")
+	}
+
+	err = check(sigs[1], sigs[1], [][]byte{sigs[2], sigs[3]})
+	if err != ErrSign {
+		t.Fatal("usign of provider should fail")
+	}
+
+	err = check(sigs[0], sigs[1], [][]byte{sigs[2]})
+	if err != ErrQuorum {
+		t.Fatal("one keeper is not enough for level 2")
+	}
+
+	// same keeper signs at other place
+	err = check(sigs[0], sigs[1], [][]byte{sigs[2], sigs[2]})
+	if err != ErrSign {
+		t.Fatal("ksign at place of other keeper should fail")
+	}
+
+	// sub order has other digest
+	err = r.checkOrder(r.addrs[2], utils.DomainSubOrder, gi, 0, 1, 100, 86400, 300, 0, 0, sprice, sigs[0], sigs[1], [][]byte{sigs[2], sigs[3]})
+	if err != ErrSign {
+		t.Fatal("sign of add order should not work for sub order")
+	}
+}
+
+func TestKeeperAggSign(t *testing.T) {
+	r := &roleMgr{
+		state: NewState(nil),
+		info:  make(map[utils.Address]*BaseInfo),
+	}
+
+	gi := &GroupInfo{
+		Level: 2,
+	}
+
+	bkeys := make([]*utils.BlsKey, 3)
+	for i := range bkeys {
+		bk, err := utils.GenerateBlsKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bkeys[i] = bk
+
+		pop, err := utils.BlsProve(bk.SecretKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pk, err := checkBlsKey(append(append([]byte(nil), bk.PubKey...), pop...))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := utils.BytesToAddress([]byte{byte(i + 1)})
+		r.addrs = append(r.addrs, addr)
+		r.info[addr] = &BaseInfo{Index: uint64(i), Extra: pk}
+		gi.Keepers = append(gi.Keepers, uint64(i))
+	}
+
+	_, err := checkBlsKey(append(append([]byte(nil), bkeys[0].PubKey...), make([]byte, utils.BlsSignatureLength)...))
+	if err == nil {
+		t.Fatal("bls key without proof should fail")
+	}
+
+	h := utils.HashOf([]byte("ready"))
+	sigs := make([][]byte, len(bkeys))
+	for i, bk := range bkeys {
+		sig, err := utils.BlsSign(bk.SecretKey, h[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs[i] = sig
+	}
+
+	// keeper 0 and 2 sign
+	asig, err := utils.BlsAggregate([][]byte{sigs[0], sigs[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.checkKeepers(gi, h, KeeperAggSign(asig, []byte{0b101}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = r.checkKeepers(gi, h, KeeperAggSign(asig, []byte{0b011}))
+	if err != ErrSign {
+		t.Fatal("bitmap of other keepers should fail")
+	}
+
+	err = r.checkKeepers(gi, h, KeeperAggSign(sigs[0], []byte{0b001}))
+	if err != ErrQuorum {
+		t.Fatal("one keeper is not enough for level 2")
+	}
+
+	err = r.checkKeepers(gi, h, KeeperAggSign(asig, []byte{0b1101}))
+	if err != ErrSign {
+		t.Fatal("bit out of group should fail")
+	}
+
+	err = r.checkKeepers(gi, utils.HashOf([]byte("other")), KeeperAggSign(asig, []byte{0b101}))
+	if err != ErrSign {
+		t.Fatal("aggregate of other digest should fail")
+	}
+}
+
+func TestSetReadyAgg(t *testing.T) {
+	r := &roleMgr{
+		state: NewState(nil),
+		local: utils.GetContractAddress(utils.NilAddress, []byte("RoleMgr")),
+		info:  make(map[utils.Address]*BaseInfo),
+	}
+
+	gi := &GroupInfo{
+		Level: 2,
+	}
+	r.groups = append(r.groups, gi)
+
+	bkeys := make([]*utils.BlsKey, 3)
+	for i := range bkeys {
+		bk, err := utils.GenerateBlsKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bkeys[i] = bk
+
+		addr := utils.BytesToAddress([]byte{byte(i + 1)})
+		r.addrs = append(r.addrs, addr)
+		r.info[addr] = &BaseInfo{Index: uint64(i), Extra: bk.PubKey}
+		gi.Keepers = append(gi.Keepers, uint64(i))
+	}
+
+	h, err := ReadyDigest(r.state.chainID, r.local, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sigs := make([][]byte, len(bkeys))
+	for i, bk := range bkeys {
+		sigs[i], err = utils.BlsSign(bk.SecretKey, h[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// one aggregated sig of keeper 1 and 2 meets level 2
+	err = r.SetReady(r.addrs[0], 0, KeeperAggSign(sigs[1], []byte{0b010}))
+	if err != ErrQuorum {
+		t.Fatal("one keeper is not enough for level 2: ", err)
+	}
+	if gi.IsReady {
+		t.Fatal("group is ready without quorum")
+	}
+
+	asig, err := utils.BlsAggregate([][]byte{sigs[1], sigs[2]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.SetReady(r.addrs[0], 0, KeeperAggSign(asig, []byte{0b110}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gi.IsReady {
+		t.Fatal("group is not ready")
+	}
+}
@@ -0,0 +1,114 @@
+package contract
+
+import (
+	"github.com/memoio/go-settlement/utils"
+)
+
+// codec is implemented by all contracts, see persist.go
+type codec interface {
+	encode() ([]byte, error)
+	decode(b []byte) error
+}
+
+// Snapshot keeps encoded contracts of a state, used to revert a failed call;
+// a contract is copied when it is first touched after Snapshot
+type Snapshot struct {
+	contracts map[utils.Address][]byte
+	created   map[utils.Address]struct{}
+	events    int
+	err       error // first copy which fails
+}
+
+// Snapshot starts copy on write of contracts in s; one snapshot is open
+// at a time, until it is reverted or discarded
+func (s *State) Snapshot() (*Snapshot, error) {
+	snap := &Snapshot{
+		contracts: make(map[utils.Address][]byte),
+		created:   make(map[utils.Address]struct{}),
+		events:    len(s.events),
+	}
+	s.snap = snap
+
+	return snap, nil
+}
+
+// copyOnWrite keeps contract at addr in open snapshot before its first
+// change; called by touch
+func (s *State) copyOnWrite(addr utils.Address) {
+	snap := s.snap
+	if snap == nil {
+		return
+	}
+
+	_, ok := snap.contracts[addr]
+	if ok {
+		return
+	}
+	_, ok = snap.created[addr]
+	if ok {
+		return
+	}
+
+	ci, ok := s.contracts[addr]
+	if !ok {
+		// created after snapshot
+		snap.created[addr] = struct{}{}
+		return
+	}
+
+	c, ok := ci.(codec)
+	if !ok {
+		if snap.err == nil {
+			snap.err = ErrMisType
+		}
+		return
+	}
+
+	data, err := c.encode()
+	if err != nil {
+		if snap.err == nil {
+			snap.err = err
+		}
+		return
+	}
+	snap.contracts[addr] = data
+}
+
+// Revert restores contracts changed since snap in place, so references to
+// contracts stay valid; contracts created after snap are removed.
+func (s *State) Revert(snap *Snapshot) error {
+	s.Discard(snap)
+
+	if snap.err != nil {
+		return snap.err
+	}
+
+	for addr := range snap.created {
+		delete(s.contracts, addr)
+	}
+
+	for addr, data := range snap.contracts {
+		c, ok := s.contracts[addr].(codec)
+		if !ok {
+			return ErrMisType
+		}
+
+		err := c.decode(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(s.events) > snap.events {
+		s.events = s.events[:snap.events]
+	}
+
+	return nil
+}
+
+// Discard closes snap, changes after it are kept
+func (s *State) Discard(snap *Snapshot) {
+	if s.snap == snap {
+		s.snap = nil
+	}
+}
@@ -0,0 +1,72 @@
+package contract
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestSnapshot(t *testing.T) {
+	s := NewState(NewMockClock(1600000000))
+
+	// address of token is made from its admin
+	admin := utils.BytesToAddress([]byte("admin"))
+	admin2 := utils.BytesToAddress([]byte("admin2"))
+	to := utils.BytesToAddress([]byte("to"))
+	e1 := NewErcToken(s, admin)
+	e2 := NewErcToken(s, admin2)
+
+	snap, err := s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = e1.Transfer(admin, to, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e3 := NewErcToken(s, utils.BytesToAddress([]byte("admin3")))
+
+	// only written contract is copied
+	if len(snap.contracts) != 1 || len(snap.created) != 1 {
+		t.Fatal("snapshot copies untouched contracts: ", len(snap.contracts), len(snap.created))
+	}
+
+	err = s.Revert(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e1.BalanceOf(to, to).Sign() != 0 {
+		t.Fatal("transfer is not reverted")
+	}
+	if _, ok := s.contracts[e3.GetContractAddress()]; ok {
+		t.Fatal("contract created after snapshot is not removed")
+	}
+	if _, ok := s.contracts[e2.GetContractAddress()]; !ok {
+		t.Fatal("untouched contract is removed")
+	}
+
+	// closed snapshot copies nothing
+	err = e1.Transfer(admin, to, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.contracts) != 1 || e1.BalanceOf(to, to).Cmp(big.NewInt(10)) != 0 {
+		t.Fatal("write after revert is wrong")
+	}
+
+	snap, err = s.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e2.Transfer(admin2, to, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Discard(snap)
+	if s.snap != nil || e2.BalanceOf(to, to).Cmp(big.NewInt(10)) != 0 {
+		t.Fatal("discard is wrong")
+	}
+}
@@ -3,6 +3,7 @@ package contract
 import (
 	"math/big"
 
+	"github.com/memoio/go-settlement/server/types"
 	"github.com/memoio/go-settlement/utils"
 )
 
@@ -10,3 +11,97 @@ type txFee struct {
 	tAddr  utils.Address
 	amount *big.Int
 }
+
+// SetFeeToken sets token of gas fee, it is registered in roleMgr;
+// nil is primary token of roleMgr
+func (s *State) SetFeeToken(tAddr utils.Address) {
+	s.feeToken = tAddr
+}
+
+// getTxFee returns fee of gas at price in fee token of roleMgr rAddr,
+// and foundation which gets the fee
+func (s *State) getTxFee(rAddr utils.Address, gas uint64, price *big.Int) (*txFee, utils.Address, error) {
+	rm, err := s.GetRoleMgr(rAddr)
+	if err != nil {
+		return nil, utils.NilAddress, err
+	}
+
+	ts := rm.GetAllTokens(rAddr)
+	if len(ts) < 1 {
+		return nil, utils.NilAddress, ErrEmpty
+	}
+
+	tAddr := ts[0]
+	if s.feeToken != utils.NilAddress {
+		tAddr = utils.NilAddress
+		for _, t := range ts {
+			if t == s.feeToken {
+				tAddr = t
+				break
+			}
+		}
+		if tAddr == utils.NilAddress {
+			return nil, utils.NilAddress, ErrEmpty
+		}
+	}
+
+	tf := &txFee{
+		tAddr:  tAddr,
+		amount: new(big.Int).Mul(new(big.Int).SetUint64(gas), price),
+	}
+
+	return tf, rm.GetFoundation(rAddr), nil
+}
+
+// CanPay checks caller has fee of gas at price
+func (s *State) CanPay(rAddr, caller utils.Address, gas uint64, price *big.Int) error {
+	tf, _, err := s.getTxFee(rAddr, gas, price)
+	if err != nil {
+		return err
+	}
+
+	if s.getBalance(tf.tAddr, caller).Cmp(tf.amount) < 0 {
+		return ErrBalanceNotEnough
+	}
+
+	return nil
+}
+
+// Prepay moves fee of gas at price from caller to foundation; fee moves
+// use no gas and are not Transfer events
+func (s *State) Prepay(rAddr, caller utils.Address, gas uint64, price *big.Int) error {
+	tf, foundation, err := s.getTxFee(rAddr, gas, price)
+	if err != nil {
+		return err
+	}
+
+	if tf.amount.Sign() == 0 {
+		return nil
+	}
+
+	return s.moveFee(tf.tAddr, caller, foundation, tf.amount)
+}
+
+// Refund returns fee of gas limit not used from foundation to caller,
+// and emits Fee of used gas
+func (s *State) Refund(rAddr, caller utils.Address, limit, used uint64, price *big.Int) error {
+	tf, foundation, err := s.getTxFee(rAddr, limit-used, price)
+	if err != nil {
+		return err
+	}
+
+	if tf.amount.Sign() > 0 {
+		err = s.moveFee(tf.tAddr, foundation, caller, tf.amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.emit(tf.tAddr, types.EventFee, nil, &types.FeeEvent{
+		Payer:      caller,
+		Foundation: foundation,
+		Value:      new(big.Int).Mul(new(big.Int).SetUint64(used), price),
+	})
+
+	return nil
+}
@@ -16,6 +16,13 @@ type CommonAPI struct {
 	APISecret *APIAlg
 }
 
+// NewCommonAPI signs and verifies api tokens by secret
+func NewCommonAPI(secret []byte) *CommonAPI {
+	return &CommonAPI{
+		APISecret: (*APIAlg)(jwt.NewHS256(secret)),
+	}
+}
+
 type jwtPayload struct {
 	Allow []auth.Permission
 }
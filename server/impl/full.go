@@ -1,6 +1,8 @@
 package impl
 
 import (
+	"context"
+
 	"github.com/memoio/go-settlement/server/impl/common"
 	"github.com/memoio/go-settlement/server/impl/node"
 
@@ -14,9 +16,11 @@ type FullNodeAPI struct {
 	node.ChainAPI
 }
 
-func New() *FullNodeAPI {
-	n := node.NewNode()
-	com := new(common.CommonAPI)
+// New serves n, which produces blocks until ctx is done;
+// api tokens are signed by secret
+func New(ctx context.Context, n *node.Node, secret []byte) *FullNodeAPI {
+	go n.Run(ctx)
+	com := common.NewCommonAPI(secret)
 
 	return &FullNodeAPI{com, n}
 }
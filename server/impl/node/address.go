@@ -0,0 +1,75 @@
+package node
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// ChangeAddress remaps index to newAddr; oldSig and newSig are of
+// contract.ChangeAddressDigest by old and new address
+func (n *Node) ChangeAddress(uid uint64, sig []byte, caller utils.Address, index uint64, newAddr utils.Address, oldSig, newSig []byte) (utils.Hash, error) {
+	_, tx, err := n.submit("ChangeAddress", uid, sig, caller, &changeAddressParams{
+		Index:   index,
+		NewAddr: newAddr,
+		OldSig:  oldSig,
+		NewSig:  newSig,
+	})
+	return tx, err
+}
+
+func (n *Node) execChangeAddress(c *Call) ([]byte, error) {
+	p := new(changeAddressParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, n.rm.ChangeAddress(c.Caller, p.Index, p.NewAddr, p.OldSig, p.NewSig)
+}
+
+func (n *Node) ConfirmAddress(uid uint64, sig []byte, caller utils.Address, index uint64) (utils.Hash, error) {
+	_, tx, err := n.submit("ConfirmAddress", uid, sig, caller, &addrIndexParams{
+		Index: index,
+	})
+	return tx, err
+}
+
+func (n *Node) execConfirmAddress(c *Call) ([]byte, error) {
+	p := new(addrIndexParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, n.rm.ConfirmAddress(c.Caller, p.Index)
+}
+
+func (n *Node) CancelAddress(uid uint64, sig []byte, caller utils.Address, index uint64) (utils.Hash, error) {
+	_, tx, err := n.submit("CancelAddress", uid, sig, caller, &addrIndexParams{
+		Index: index,
+	})
+	return tx, err
+}
+
+func (n *Node) execCancelAddress(c *Call) ([]byte, error) {
+	p := new(addrIndexParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, n.rm.CancelAddress(c.Caller, p.Index)
+}
+
+// GetAddrChange returns pending address change of index
+func (n *Node) GetAddrChange(caller utils.Address, index uint64) (*contract.AddrChange, error) {
+	n.RLock()
+	defer n.RUnlock()
+
+	if n.rm == nil {
+		return nil, ErrRes
+	}
+
+	return n.rm.GetAddrChange(caller, index)
+}
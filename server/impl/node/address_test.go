@@ -0,0 +1,191 @@
+package node
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func testChangeAddress(t *testing.T, n *Node, index uint64, oldAddr, newAddr utils.Address) error {
+	h, err := contract.ChangeAddressDigest(utils.DefaultChainID, n.rm.GetContractAddress(), index, newAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newSig := signMsg(t, newAddr, h[:])
+
+	uid := n.GetNonce(oldAddr, oldAddr)
+	_, err = n.ChangeAddress(uid, sign(t, oldAddr, "ChangeAddress", uid, index, newAddr, nil, newSig), oldAddr, index, newAddr, nil, newSig)
+	return err
+}
+
+func TestChangeAddress(t *testing.T) {
+	n, err := NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	index := testPledge(t, n, admin, big.NewInt(1000))
+	oldAddr, err := n.GetAddr(admin, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// new address must sign
+	newAddr := testNewKey(t)
+	uid := n.GetNonce(oldAddr, oldAddr)
+	_, err = n.ChangeAddress(uid, sign(t, oldAddr, "ChangeAddress", uid, index, newAddr, nil, nil), oldAddr, index, newAddr, nil, nil)
+	if err != contract.ErrSign {
+		t.Fatal("change without sig of new address should fail: ", err)
+	}
+
+	// no delay, at once
+	err = testChangeAddress(t, n, index, oldAddr, newAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ni, err := n.GetIndex(admin, newAddr)
+	if err != nil || ni != index {
+		t.Fatal("index is not remapped: ", ni, err)
+	}
+	_, err = n.GetIndex(admin, oldAddr)
+	if err == nil {
+		t.Fatal("old address should be dropped")
+	}
+
+	// pledge goes with index
+	testWithdrawPledge(t, n, admin, index, 0, big.NewInt(100), false)
+
+	// registered address can not be taken
+	other := testPledge(t, n, admin, big.NewInt(1000))
+	otherAddr, err := n.GetAddr(admin, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testChangeAddress(t, n, index, newAddr, otherAddr)
+	if err != contract.ErrExist {
+		t.Fatal("change to registered address should fail: ", err)
+	}
+
+	// with delay, old address cancels
+	id := testPropose(t, n, admin, contract.OpSetChangeDelay, &contract.ProposalParas{Delay: contract.Day})
+	err = testExecute(t, n, admin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thief := testNewKey(t)
+	err = testChangeAddress(t, n, index, newAddr, thief)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ac, err := n.GetAddrChange(admin, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ac.NewAddr != thief {
+		t.Fatal("pending change is wrong: ", ac)
+	}
+
+	uid = n.GetNonce(thief, thief)
+	_, err = n.ConfirmAddress(uid, sign(t, thief, "ConfirmAddress", uid, index), thief, index)
+	if err != contract.ErrPermission {
+		t.Fatal("confirm before delay should fail: ", err)
+	}
+
+	uid = n.GetNonce(newAddr, newAddr)
+	_, err = n.CancelAddress(uid, sign(t, newAddr, "CancelAddress", uid, index), newAddr, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = n.GetAddrChange(admin, index)
+	if err != contract.ErrEmpty {
+		t.Fatal("change should be canceled: ", err)
+	}
+
+	// with delay, confirmed after it
+	last := testNewKey(t)
+	err = testChangeAddress(t, n, index, newAddr, last)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testAdvanceTime(t, n, admin, contract.Day)
+
+	uid = n.GetNonce(last, last)
+	_, err = n.ConfirmAddress(uid, sign(t, last, "ConfirmAddress", uid, index), last, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, err := n.GetAddr(admin, index)
+	if err != nil || addr != last {
+		t.Fatal("index is not remapped after delay: ", addr, err)
+	}
+}
+
+func TestChangeFoundation(t *testing.T) {
+	n, err := NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	user := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	foundation, err := n.GetAddr(admin, 0)
+	if err != nil || foundation != n.GetFoundation(admin) {
+		t.Fatal("foundation is not index 0: ", foundation, err)
+	}
+
+	newFoundation := testNewKey(t)
+	err = testChangeAddress(t, n, 0, foundation, newFoundation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.GetFoundation(admin) != newFoundation {
+		t.Fatal("foundation is not changed with index 0")
+	}
+
+	uid := n.GetNonce(admin, admin)
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, user, big.NewInt(1e12)), taddr, admin, user, big.NewInt(1e12))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// fees go to new foundation
+	n.SetGasPrice(big.NewInt(2))
+	fbal := n.BalanceOf(taddr, user, foundation)
+	nbal := n.BalanceOf(taddr, user, newFoundation)
+
+	uid = n.GetNonce(user, user)
+	tx, err := n.Transfer(uid, sign(t, user, "Transfer", uid, taddr, admin, big.NewInt(10)), taddr, user, admin, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := n.GetReceipt(user, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nbal.Add(nbal, new(big.Int).SetUint64(r.GasUsed*2))
+	if n.BalanceOf(taddr, user, newFoundation).Cmp(nbal) != 0 {
+		t.Fatal("fee is not paid to new foundation")
+	}
+	if n.BalanceOf(taddr, user, foundation).Cmp(fbal) != 0 {
+		t.Fatal("fee is paid to old foundation")
+	}
+}
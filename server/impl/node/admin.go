@@ -0,0 +1,110 @@
+package node
+
+import (
+	"encoding/binary"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// Propose an admin op of roleMgr, returns id of the proposal
+func (n *Node) Propose(uid uint64, sig []byte, caller utils.Address, op uint8, paras *contract.ProposalParas) (*types.ProposeResult, error) {
+	if paras == nil {
+		paras = new(contract.ProposalParas)
+	}
+
+	ret, tx, err := n.submit("Propose", uid, sig, caller, &proposeParams{
+		Op:    op,
+		Paras: *paras,
+	})
+	if tx == utils.NilHash {
+		return nil, err
+	}
+
+	res := &types.ProposeResult{
+		TxHash: tx,
+	}
+	if len(ret) == 8 {
+		res.ID = binary.BigEndian.Uint64(ret)
+	} else if err == nil {
+		err = ErrRes
+	}
+
+	return res, err
+}
+
+func (n *Node) execPropose(c *Call) ([]byte, error) {
+	p := new(proposeParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := n.rm.Propose(c.Caller, p.Op, &p.Paras)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]byte, 8)
+	binary.BigEndian.PutUint64(ret, id)
+	return ret, nil
+}
+
+func (n *Node) ApproveProposal(uid uint64, sig []byte, caller utils.Address, id uint64) (utils.Hash, error) {
+	_, tx, err := n.submit("ApproveProposal", uid, sig, caller, &proposalParams{
+		ID: id,
+	})
+	return tx, err
+}
+
+func (n *Node) execApproveProposal(c *Call) ([]byte, error) {
+	p := new(proposalParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, n.rm.ApproveProposal(c.Caller, p.ID)
+}
+
+func (n *Node) ExecuteProposal(uid uint64, sig []byte, caller utils.Address, id uint64) (utils.Hash, error) {
+	_, tx, err := n.submit("ExecuteProposal", uid, sig, caller, &proposalParams{
+		ID: id,
+	})
+	return tx, err
+}
+
+func (n *Node) execExecuteProposal(c *Call) ([]byte, error) {
+	p := new(proposalParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, n.rm.ExecuteProposal(c.Caller, p.ID)
+}
+
+// GetAdmins returns admins of roleMgr and approvals needed by admin ops
+func (n *Node) GetAdmins(caller utils.Address) (*contract.AdminInfo, error) {
+	n.RLock()
+	defer n.RUnlock()
+
+	if n.rm == nil {
+		return nil, ErrRes
+	}
+
+	return n.rm.GetAdmins(caller), nil
+}
+
+func (n *Node) GetProposal(caller utils.Address, id uint64) (*contract.Proposal, error) {
+	n.RLock()
+	defer n.RUnlock()
+
+	if n.rm == nil {
+		return nil, ErrRes
+	}
+
+	return n.rm.GetProposal(caller, id)
+}
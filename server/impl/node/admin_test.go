@@ -0,0 +1,175 @@
+package node
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func testPropose(t *testing.T, n *Node, admin utils.Address, op uint8, paras *contract.ProposalParas) uint64 {
+	uid := n.GetNonce(admin, admin)
+	res, err := n.Propose(uid, sign(t, admin, "Propose", uid, op, paras), admin, op, paras)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := n.GetReceipt(admin, res.TxHash)
+	if err != nil || r.Status != types.ReceiptSuccess {
+		t.Fatal("receipt of propose is wrong: ", err)
+	}
+	return res.ID
+}
+
+func testExecute(t *testing.T, n *Node, admin utils.Address, id uint64) error {
+	uid := n.GetNonce(admin, admin)
+	_, err := n.ExecuteProposal(uid, sign(t, admin, "ExecuteProposal", uid, id), admin, id)
+	return err
+}
+
+func TestAdmin(t *testing.T) {
+	n := testNewNode(t)
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	admin2 := testNewKey(t)
+	admin3 := testNewKey(t)
+
+	// one admin executes alone
+	for _, a := range []utils.Address{admin2, admin3} {
+		id := testPropose(t, n, admin, contract.OpAddAdmin, &contract.ProposalParas{Addr: a})
+		err := testExecute(t, n, admin, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	id := testPropose(t, n, admin, contract.OpSetThreshold, &contract.ProposalParas{Threshold: 2})
+	err := testExecute(t, n, admin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ai, err := n.GetAdmins(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ai.Admins) != 3 || ai.Threshold != 2 {
+		t.Fatal("admin set is wrong: ", ai)
+	}
+
+	// direct call of admin op needs proposal now
+	uid := n.GetNonce(admin, admin)
+	_, err = n.CreateGroup(uid, sign(t, admin, "CreateGroup", uid, 7), admin, 7)
+	if err != contract.ErrPermission {
+		t.Fatal("direct admin op should fail with threshold 2: ", err)
+	}
+
+	gs := len(n.GetAllGroups(admin))
+	id = testPropose(t, n, admin2, contract.OpCreateGroup, &contract.ProposalParas{Level: 7})
+	err = testExecute(t, n, admin2, id)
+	if err != contract.ErrPermission {
+		t.Fatal("proposal should wait for approvals")
+	}
+
+	outsider := testNewKey(t)
+	uid = n.GetNonce(admin, outsider)
+	_, err = n.ApproveProposal(uid, sign(t, outsider, "ApproveProposal", uid, id), outsider, id)
+	if err != contract.ErrPermission {
+		t.Fatal("approve by non admin should fail")
+	}
+
+	uid = n.GetNonce(admin, admin3)
+	_, err = n.ApproveProposal(uid, sign(t, admin3, "ApproveProposal", uid, id), admin3, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = testExecute(t, n, admin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.GetAllGroups(admin)) != gs+1 {
+		t.Fatal("group is not created by proposal")
+	}
+
+	// keeper joins group by sigs of threshold admins
+	kindex := testCreateKeeper(t, n, admin)
+	gIndex := uint64(gs)
+	uid = n.GetNonce(admin, admin)
+	_, err = n.AddKeeperToGroup(uid, sign(t, admin, "AddKeeperToGroup", uid, kindex, gIndex, nil), admin, kindex, gIndex, nil)
+	if err != contract.ErrPermission {
+		t.Fatal("add keeper by one admin should fail with threshold 2: ", err)
+	}
+
+	h, err := contract.AddKeeperDigest(utils.DefaultChainID, n.rm.GetContractAddress(), kindex, gIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	asigns := [][]byte{nil, signMsg(t, admin2, h[:])}
+	uid = n.GetNonce(admin, admin)
+	_, err = n.AddKeeperToGroup(uid, sign(t, admin, "AddKeeperToGroup", uid, kindex, gIndex, asigns), admin, kindex, gIndex, asigns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gi, err := n.GetGroupInfo(admin, gIndex)
+	if err != nil || len(gi.Keepers) != 1 || gi.Keepers[0] != kindex {
+		t.Fatal("keeper is not added by admin sigs: ", err)
+	}
+
+	p, err := n.GetProposal(admin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Executed || len(p.Approvals) != 2 {
+		t.Fatal("proposal is wrong: ", p)
+	}
+
+	err = testExecute(t, n, admin, id)
+	if err != contract.ErrPermission {
+		t.Fatal("proposal runs once")
+	}
+
+	// failed op leaves proposal open
+	id = testPropose(t, n, admin, contract.OpSetPledgeMoney, &contract.ProposalParas{Money: big.NewInt(1)})
+	uid = n.GetNonce(admin, admin2)
+	_, err = n.ApproveProposal(uid, sign(t, admin2, "ApproveProposal", uid, id), admin2, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testExecute(t, n, admin, id)
+	if err != contract.ErrInput {
+		t.Fatal("set pledge money without provider pledge should fail")
+	}
+	p, err = n.GetProposal(admin, id)
+	if err != nil || p.Executed {
+		t.Fatal("failed proposal should be open")
+	}
+
+	// can not remove below threshold
+	id = testPropose(t, n, admin, contract.OpRemoveAdmin, &contract.ProposalParas{Addr: admin3})
+	uid = n.GetNonce(admin, admin2)
+	_, err = n.ApproveProposal(uid, sign(t, admin2, "ApproveProposal", uid, id), admin2, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testExecute(t, n, admin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id = testPropose(t, n, admin, contract.OpRemoveAdmin, &contract.ProposalParas{Addr: admin2})
+	uid = n.GetNonce(admin, admin2)
+	_, err = n.ApproveProposal(uid, sign(t, admin2, "ApproveProposal", uid, id), admin2, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testExecute(t, n, admin, id)
+	if err != contract.ErrInput {
+		t.Fatal("admins should not be less than threshold")
+	}
+}
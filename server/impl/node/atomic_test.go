@@ -0,0 +1,87 @@
+package node
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestRevert(t *testing.T) {
+	n, err := NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	uAddr := testNewKey(t)
+	uid := n.GetNonce(admin, admin)
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, uAddr, big.NewInt(1000)), taddr, admin, uAddr, big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid = n.GetNonce(admin, uAddr)
+	_, err = n.Register(uid, sign(t, uAddr, "Register", uid, uAddr, nil), uAddr, uAddr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uindex, err := n.GetIndex(uAddr, uAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(before map[string][]byte) {
+		after := encodeContracts(t, n)
+		if len(before) != len(after) {
+			t.Fatal("contract number changes after failed call")
+		}
+		for key, val := range before {
+			if !bytes.Equal(val, after[key]) {
+				t.Fatal("contract changes after failed call: ", utils.BytesToAddress([]byte(key)))
+			}
+		}
+	}
+
+	// pledge updates accumulators before it fails on allowance
+	before := encodeContracts(t, n)
+	uid = n.GetNonce(admin, uAddr)
+	_, err = n.Pledge(uid, sign(t, uAddr, "Pledge", uid, uindex, big.NewInt(100)), uAddr, uindex, big.NewInt(100))
+	if err == nil {
+		t.Fatal("pledge without approve should fail")
+	}
+	check(before)
+
+	// air drop fails on the last address
+	before = encodeContracts(t, n)
+	bal := n.BalanceOf(taddr, admin, admin)
+	half := new(big.Int).Div(bal, big.NewInt(2))
+	half.Add(half, big.NewInt(1))
+	uid = n.GetNonce(admin, admin)
+	_, err = n.AirDrop(uid, sign(t, admin, "AirDrop", uid, taddr, []utils.Address{founder, uAddr}, half), taddr, admin, []utils.Address{founder, uAddr}, half)
+	if err == nil {
+		t.Fatal("air drop more than balance should fail")
+	}
+	check(before)
+
+	if n.BalanceOf(taddr, founder, founder).Sign() != 0 {
+		t.Fatal("air drop is not reverted")
+	}
+
+	// node keeps working on reverted contracts
+	uid = n.GetNonce(admin, admin)
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, founder, big.NewInt(10)), taddr, admin, founder, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.BalanceOf(taddr, founder, founder).Cmp(big.NewInt(10)) != 0 {
+		t.Fatal("transfer after revert fails")
+	}
+}
@@ -0,0 +1,169 @@
+package node
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// BlockInterval is max seconds a block stays open
+const BlockInterval uint64 = 1
+
+var (
+	blockPrefix     = []byte("block/")     // height -> block
+	blockHashPrefix = []byte("blockhash/") // hash -> height
+)
+
+func blockKey(height uint64) []byte {
+	key := make([]byte, len(blockPrefix)+8)
+	copy(key, blockPrefix)
+	binary.BigEndian.PutUint64(key[len(blockPrefix):], height)
+	return key
+}
+
+func blockHashKey(h utils.Hash) []byte {
+	return append(append([]byte(nil), blockHashPrefix...), h[:]...)
+}
+
+func loadBlock(ds store.KVStore, height uint64) (*types.Block, error) {
+	val, err := ds.Get(blockKey(height))
+	if err != nil {
+		return nil, err
+	}
+
+	b := new(types.Block)
+	err = cbor.Unmarshal(val, b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// openBlock returns the block which takes next call at time t;
+// pending block is sealed if it is too old. Called with lock held.
+func (n *Node) openBlock(t uint64) *types.Block {
+	if n.pending != nil && t >= n.pending.Time+BlockInterval {
+		n.sealBlock()
+	}
+
+	if n.pending == nil {
+		if t < n.head.Time {
+			t = n.head.Time
+		}
+
+		n.pending = &types.Block{
+			BlockHeader: types.BlockHeader{
+				Height:  n.head.Height + 1,
+				Time:    t,
+				Parent:  n.head.Hash(),
+				ChainID: n.chainID,
+			},
+		}
+	}
+
+	return n.pending
+}
+
+// sealBlock makes pending block the head; called with lock held
+func (n *Node) sealBlock() {
+	if n.pending == nil {
+		return
+	}
+
+	b := n.pending
+	b.TxRoot = types.TxRoot(b.Txs)
+
+	val, err := cbor.Marshal(b)
+	if err != nil {
+		log.Error("seal block fail: ", err)
+		return
+	}
+
+	height := make([]byte, 8)
+	binary.BigEndian.PutUint64(height, b.Height)
+
+	bt := n.ds.NewBatch()
+	bt.Put(blockKey(b.Height), val)
+	bt.Put(blockHashKey(b.Hash()), height)
+	err = bt.Commit()
+	if err != nil {
+		log.Error("seal block fail: ", err)
+		return
+	}
+
+	log.Debugf("seal block %d %s with %d txs", b.Height, b.Hash(), len(b.Txs))
+
+	n.head = b
+	n.pending = nil
+
+	n.subs.pubHead(b)
+}
+
+// Run seals pending block in time until ctx is done
+func (n *Node) Run(ctx context.Context) {
+	tc := time.NewTicker(time.Second)
+	defer tc.Stop()
+
+	for {
+		select {
+		case <-tc.C:
+			n.Lock()
+			if n.pending != nil && n.clock.Now() >= n.pending.Time+BlockInterval {
+				n.sealBlock()
+				err := n.persist()
+				if err != nil {
+					log.Warn("persist sealed block fail: ", err)
+				}
+			}
+			n.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (n *Node) ChainHead(caller utils.Address) (*types.Block, error) {
+	n.RLock()
+	defer n.RUnlock()
+
+	return n.head, nil
+}
+
+func (n *Node) GetBlockByHeight(caller utils.Address, height uint64) (*types.Block, error) {
+	n.RLock()
+	defer n.RUnlock()
+
+	if height > n.head.Height {
+		return nil, ErrBlock
+	}
+
+	return loadBlock(n.ds, height)
+}
+
+func (n *Node) GetBlockByHash(caller utils.Address, h utils.Hash) (*types.Block, error) {
+	n.RLock()
+	defer n.RUnlock()
+
+	if h == n.head.Hash() {
+		return n.head, nil
+	}
+
+	val, err := n.ds.Get(blockHashKey(h))
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, ErrBlock
+		}
+		return nil, err
+	}
+
+	if len(val) != 8 {
+		return nil, ErrRes
+	}
+
+	return loadBlock(n.ds, binary.BigEndian.Uint64(val))
+}
@@ -0,0 +1,99 @@
+package node
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestBlock(t *testing.T) {
+	ds := store.NewMemStore()
+	nt := uint64(1600000000)
+	n, err := NewNode(ds, contract.NewMockClock(nt))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+
+	head, err := n.ChainHead(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Height != 0 || head.Hash() != types.Genesis(utils.DefaultChainID).Hash() {
+		t.Fatal("head of new node should be genesis")
+	}
+
+	// calls at same time are in one block
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+	uid := n.GetNonce(admin, admin)
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, founder, big.NewInt(10)), taddr, admin, founder, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, _ = n.ChainHead(admin)
+	if head.Height != 0 {
+		t.Fatal("block is sealed too early")
+	}
+
+	testAdvanceTime(t, n, admin, 10)
+
+	b1, _ := n.ChainHead(admin)
+	if b1.Height != 1 || b1.Time != nt || len(b1.Txs) != 5 {
+		t.Fatal("block 1 is wrong: ", b1.Height, b1.Time, len(b1.Txs))
+	}
+	if b1.Parent != types.Genesis(utils.DefaultChainID).Hash() || b1.TxRoot != types.TxRoot(b1.Txs) {
+		t.Fatal("block 1 header is wrong")
+	}
+
+	// next call opens block at new time
+	uid = n.GetNonce(admin, admin)
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, founder, big.NewInt(10)), taddr, admin, founder, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAdvanceTime(t, n, admin, 10)
+
+	b2, _ := n.ChainHead(admin)
+	if b2.Height != 2 || b2.Time != nt+10 || b2.Parent != b1.Hash() {
+		t.Fatal("block 2 is wrong")
+	}
+
+	b, err := n.GetBlockByHeight(admin, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Hash() != b1.Hash() {
+		t.Fatal("get block by height is wrong")
+	}
+
+	b, err = n.GetBlockByHash(admin, b2.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Height != 2 {
+		t.Fatal("get block by hash is wrong")
+	}
+
+	_, err = n.GetBlockByHeight(admin, 3)
+	if err != ErrBlock {
+		t.Fatal("block 3 should not exist")
+	}
+
+	// head is kept after restart
+	nn, err := NewNode(ds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, _ = nn.ChainHead(admin)
+	if head.Hash() != b2.Hash() {
+		t.Fatal("head is not restored")
+	}
+}
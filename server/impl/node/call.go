@@ -0,0 +1,357 @@
+package node
+
+import (
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// Call is one signed call accepted by node
+type Call struct {
+	Method string
+	Uid    uint64 // nonce of caller
+	Sig    []byte
+	Caller utils.Address
+	Params []byte // cbor array of method params
+	Time   uint64 // contract time when executed, same as its block
+	Height uint64 // height of its block
+
+	GasLimit uint64   // 0 means no limit, as in old journal
+	GasPrice *big.Int // fee per gas in primary token; nil means free
+
+	Msg []byte // cbor of message.Message signed by caller, if pushed as message
+}
+
+// Hash identifies the call, it does not cover its block
+func (c *Call) Hash() utils.Hash {
+	buf, err := cbor.Marshal([]interface{}{c.Method, c.Uid, c.Sig, c.Caller, c.Params, c.GasLimit, c.GasPrice, c.Msg})
+	if err != nil {
+		panic(err)
+	}
+	return utils.HashOf(buf)
+}
+
+// digest is what caller signs: digest of the message if pushed,
+// else of method, chain ID, nonce and params
+func (c *Call) digest(chainID uint64) []byte {
+	method, params := c.Method, c.Params
+	if len(c.Msg) > 0 {
+		method, params = message.MethodPush, c.Msg
+	}
+
+	h, err := utils.CallDigest(method, chainID, c.Uid, params)
+	if err != nil {
+		return nil
+	}
+	return h[:]
+}
+
+type handler func(n *Node, c *Call) ([]byte, error)
+
+// handlers maps method name to its execution
+var handlers = map[string]handler{
+	"AdvanceTime": (*Node).execAdvanceTime,
+
+	"CreateErcToken": (*Node).execCreateErcToken,
+	"Approve":        (*Node).execApprove,
+	"Transfer":       (*Node).execTransfer,
+	"TransferFrom":   (*Node).execTransferFrom,
+	"MintToken":      (*Node).execMintToken,
+	"Burn":           (*Node).execBurn,
+	"AirDrop":        (*Node).execAirDrop,
+
+	"CreateRoleMgr":      (*Node).execCreateRoleMgr,
+	"Register":           (*Node).execRegister,
+	"RegisterToken":      (*Node).execRegisterToken,
+	"RegisterKeeper":     (*Node).execRegisterKeeper,
+	"RegisterProvider":   (*Node).execRegisterProvider,
+	"RegisterUser":       (*Node).execRegisterUser,
+	"Pledge":             (*Node).execPledge,
+	"Withdraw":           (*Node).execWithdraw,
+	"CreateGroup":        (*Node).execCreateGroup,
+	"AddKeeperToGroup":   (*Node).execAddKeeperToGroup,
+	"AddProviderToGroup": (*Node).execAddProviderToGroup,
+	"SetReady":           (*Node).execSetReady,
+	"Recharge":           (*Node).execRecharge,
+	"ProWithdraw":        (*Node).execProWithdraw,
+	"WithdrawFromFs":     (*Node).execWithdrawFromFs,
+	"AddOrder":           (*Node).execAddOrder,
+	"SubOrder":           (*Node).execSubOrder,
+	"AddRepair":          (*Node).execAddRepair,
+	"SubRepair":          (*Node).execSubRepair,
+	"Propose":            (*Node).execPropose,
+	"ApproveProposal":    (*Node).execApproveProposal,
+	"ExecuteProposal":    (*Node).execExecuteProposal,
+	"TransferOwnership":  (*Node).execTransferOwnership,
+	"AcceptOwnership":    (*Node).execAcceptOwnership,
+	"ChangeAddress":      (*Node).execChangeAddress,
+	"ConfirmAddress":     (*Node).execConfirmAddress,
+	"CancelAddress":      (*Node).execCancelAddress,
+}
+
+// noQueue has methods which return a value, or act after they are
+// executed, so a call of a future nonce is rejected instead of waiting
+// in mpool
+var noQueue = map[string]bool{
+	"AdvanceTime":    true,
+	"CreateErcToken": true,
+	"CreateRoleMgr":  true,
+	"Propose":        true,
+}
+
+// needRoleMgr has methods which run on role mgr, a call of them is rejected
+// before it is journaled if role mgr is not created
+var needRoleMgr = map[string]bool{
+	"Register":           true,
+	"RegisterToken":      true,
+	"RegisterKeeper":     true,
+	"RegisterProvider":   true,
+	"RegisterUser":       true,
+	"Pledge":             true,
+	"Withdraw":           true,
+	"CreateGroup":        true,
+	"AddKeeperToGroup":   true,
+	"AddProviderToGroup": true,
+	"SetReady":           true,
+	"Recharge":           true,
+	"ProWithdraw":        true,
+	"WithdrawFromFs":     true,
+	"AddOrder":           true,
+	"SubOrder":           true,
+	"AddRepair":          true,
+	"SubRepair":          true,
+	"Propose":            true,
+	"ApproveProposal":    true,
+	"ExecuteProposal":    true,
+	"ChangeAddress":      true,
+	"ConfirmAddress":     true,
+	"CancelAddress":      true,
+}
+
+// submit checks nonce and sig of caller, journals the call and executes it;
+// tx hash is nil if the call is not accepted, and nonce is not used then.
+// call of a future nonce waits in mpool and has no result
+func (n *Node) submit(method string, uid uint64, sig []byte, caller utils.Address, params interface{}) ([]byte, utils.Hash, error) {
+	return n.submitGas(method, uid, sig, caller, params, contract.DefaultGasLimit, n.GetGasPrice(caller))
+}
+
+// submitGas is submit with gas limit and price of caller
+func (n *Node) submitGas(method string, uid uint64, sig []byte, caller utils.Address, params interface{}, gasLimit uint64, gasPrice *big.Int) ([]byte, utils.Hash, error) {
+	pb, err := utils.CanonicalMarshal(params)
+	if err != nil {
+		return nil, utils.NilHash, err
+	}
+
+	return n.submitCall(&Call{
+		Method:   method,
+		Uid:      uid,
+		Sig:      sig,
+		Caller:   caller,
+		Params:   pb,
+		GasLimit: gasLimit,
+		GasPrice: gasPrice,
+	})
+}
+
+// submitCall is submit of c; call of a future nonce is kept in mpool,
+// and its tx hash is returned without result. ErrPersist is returned with
+// tx hash if the call is executed but its state is not written
+func (n *Node) submitCall(c *Call) (ret []byte, tx utils.Hash, err error) {
+	n.Lock()
+	defer n.Unlock()
+	defer func() {
+		perr := n.persist()
+		if perr != nil {
+			err = perr
+		}
+	}()
+
+	n.count++
+
+	nonce := n.nonceMap[c.Caller]
+	if c.Uid < nonce {
+		return nil, utils.NilHash, ErrNonce
+	}
+	if c.Uid-nonce >= MaxNonceGap {
+		return nil, utils.NilHash, ErrNonceGap
+	}
+
+	ok := utils.Verify(c.Caller, c.digest(n.chainID), c.Sig)
+	if !ok {
+		return nil, utils.NilHash, ErrRes
+	}
+
+	// reject before nonce is used, if call can not pay for itself
+	if c.GasLimit < intrinsicGas(c.Params) {
+		return nil, utils.NilHash, ErrGas
+	}
+
+	if c.Uid > nonce {
+		if noQueue[c.Method] {
+			return nil, utils.NilHash, ErrNonceFuture
+		}
+		err = n.mpool.add(c)
+		if err != nil {
+			return nil, utils.NilHash, err
+		}
+		return nil, c.Hash(), nil
+	}
+
+	err = n.canRun(c)
+	if err != nil {
+		return nil, utils.NilHash, err
+	}
+
+	ret, tx, err = n.execCall(c)
+
+	// gap is filled, run queued calls of caller
+	n.execPending(c.Caller)
+
+	return ret, tx, err
+}
+
+// canRun checks c can be executed now, before it is journaled;
+// called with lock held
+func (n *Node) canRun(c *Call) error {
+	if n.rm == nil && needRoleMgr[c.Method] {
+		return ErrNoRoleMgr
+	}
+
+	return n.canPay(c)
+}
+
+// canPay checks caller has fee of c; called with lock held
+func (n *Node) canPay(c *Call) error {
+	if c.GasPrice != nil && c.GasPrice.Sign() > 0 && n.rm != nil {
+		err := n.state.CanPay(n.rm.GetContractAddress(), c.Caller, c.GasLimit, c.GasPrice)
+		if err != nil {
+			return ErrFee
+		}
+	}
+
+	return nil
+}
+
+// execCall puts c in open block, journals and executes it;
+// tx hash is nil if c is not journaled. called with lock held
+func (n *Node) execCall(c *Call) ([]byte, utils.Hash, error) {
+	b := n.openBlock(n.clock.Now())
+	c.Time = b.Time
+	c.Height = b.Height
+
+	// write ahead, so the call can be replayed if we crash below
+	err := n.appendJournal(c)
+	if err != nil {
+		log.Error("journal call fail: ", err)
+		return nil, utils.NilHash, err
+	}
+	n.nonceMap[c.Caller] = c.Uid + 1
+
+	ret, used, err := n.apply(c)
+	n.applied = n.journal
+	tx := n.addTx(b, c, used, err)
+
+	return ret, tx, err
+}
+
+// intrinsicGas is charged before a call runs
+func intrinsicGas(params []byte) uint64 {
+	return contract.GasCall + contract.GasByte*uint64(len(params))
+}
+
+// apply executes c at its time and returns gas used; called with lock held
+func (n *Node) apply(c *Call) ([]byte, uint64, error) {
+	h, ok := handlers[c.Method]
+	if !ok {
+		return nil, 0, ErrMethod
+	}
+
+	// pin contract time, so replay gets the same result
+	old := n.state.SetClock(contract.NewMockClock(c.Time))
+	defer n.state.SetClock(old)
+
+	// fee of whole limit is paid first, failed call pays too
+	var rAddr utils.Address
+	paid := c.GasPrice != nil && c.GasPrice.Sign() > 0 && n.rm != nil
+	if paid {
+		rAddr = n.rm.GetContractAddress()
+		err := n.state.Prepay(rAddr, c.Caller, c.GasLimit, c.GasPrice)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	snap, err := n.state.Snapshot()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n.state.StartGas(c.GasLimit)
+	err = n.state.UseGas(intrinsicGas(c.Params))
+	var ret []byte
+	if err == nil {
+		ret, err = n.run(h, c)
+	}
+	used := n.state.GasUsed()
+	if c.GasLimit > 0 && used > c.GasLimit {
+		// contract may ignore error of gas
+		used = c.GasLimit
+		ret, err = nil, contract.ErrOutOfGas
+	}
+	n.state.StartGas(0)
+
+	if err != nil {
+		// failed call leaves no change
+		rerr := n.state.Revert(snap)
+		if rerr != nil {
+			log.Error("revert fail: ", rerr)
+			return nil, used, err
+		}
+		n.refresh()
+	} else {
+		n.state.Discard(snap)
+	}
+
+	if paid {
+		rerr := n.state.Refund(rAddr, c.Caller, c.GasLimit, used, c.GasPrice)
+		if rerr != nil {
+			log.Error("refund gas fail: ", rerr)
+		}
+	}
+
+	return ret, used, err
+}
+
+// run executes c by h; a panic of h fails c, so that it is reverted and
+// replay of journal does not stop at it
+func (n *Node) run(h handler, c *Call) (ret []byte, err error) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			log.Errorf("call %s of %s panics: %v", c.Method, c.Caller, r)
+			ret, err = nil, ErrPanic
+		}
+	}()
+
+	return h(n, c)
+}
+
+// refresh drops contracts which are reverted from node; called with lock held
+func (n *Node) refresh() {
+	if n.rm != nil {
+		_, err := n.state.GetRoleMgr(n.rm.GetContractAddress())
+		if err != nil {
+			n.rm = nil
+		}
+	}
+
+	for taddr := range n.ercMap {
+		_, err := n.state.GetErcToken(taddr)
+		if err != nil {
+			delete(n.ercMap, taddr)
+		}
+	}
+}
@@ -1,50 +1,97 @@
 package node
 
 import (
+	"context"
 	"errors"
 	"math/big"
 
 	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/server/types"
 	"github.com/memoio/go-settlement/utils"
 )
 
 var log = utils.Logger("node")
 
 var (
-	ErrRes   = errors.New("error result in node")
-	ErrNonce = errors.New("nonce is wrong")
+	ErrRes      = errors.New("error result in node")
+	ErrNonce    = errors.New("nonce is wrong")
+	ErrMethod   = errors.New("no such method")
+	ErrClock    = errors.New("clock can not be advanced")
+	ErrBlock    = errors.New("no such block")
+	ErrReceipt  = errors.New("no such receipt")
+	ErrGas      = errors.New("gas limit is too low")
+	ErrFee      = errors.New("fee of gas limit is not affordable")
+	ErrGasPrice = errors.New("gas price is lower than node's")
+	ErrMessage  = errors.New("params of message is malformed")
+	ErrNonceGap = errors.New("nonce is too far ahead")
+	ErrReplace  = errors.New("replacement has lower gas price")
+	ErrChainID  = errors.New("chain id is not same as genesis")
+	ErrGenesis  = errors.New("genesis is not made by spec")
+	ErrSpec     = errors.New("genesis spec is wrong")
+	ErrPersist  = errors.New("state is not written, it is recovered from journal on restart")
+
+	ErrNonceFuture = errors.New("call of this method is not queued, nonce should be next")
+	ErrMpoolFull   = errors.New("mpool is full of calls with no lower gas price")
+	ErrNoRoleMgr   = errors.New("role mgr is not created")
+	ErrPanic       = errors.New("call panics, it is reverted")
 )
 
 type ChainAPI interface {
 	GetNonce(caller, addr utils.Address) uint64
+	ChainID(caller utils.Address) uint64
+	GetGasPrice(caller utils.Address) *big.Int
+	AdvanceTime(uid uint64, sig []byte, caller utils.Address, d uint64) (uint64, error)
+
+	ChainHead(caller utils.Address) (*types.Block, error)
+	GetBlockByHeight(caller utils.Address, height uint64) (*types.Block, error)
+	GetBlockByHash(caller utils.Address, h utils.Hash) (*types.Block, error)
+	GetReceipt(caller utils.Address, tx utils.Hash) (*types.Receipt, error)
+	GetEvents(caller utils.Address, filter *types.EventFilter) ([]*types.Event, error)
+	SubscribeHeads(ctx context.Context, caller utils.Address) (<-chan *types.Block, error)
+	SubscribeEvents(ctx context.Context, caller utils.Address, filter *types.EventFilter) (<-chan *types.Event, error)
+
+	PushMessage(sm *message.SignedMessage) (utils.Hash, error)
+	MpoolPending(caller, addr utils.Address) ([]*types.PendingCall, error)
 
-	CreateErcToken(uid uint64, sig []byte, caller utils.Address) (utils.Address, error)
+	CreateErcToken(uid uint64, sig []byte, caller utils.Address) (*types.CreateResult, error)
 	TotalSupply(tAddr, caller utils.Address) *big.Int
 	BalanceOf(tAddr, caller, tokenOwner utils.Address) *big.Int
 	Allowance(tAddr, caller, tokenOwner, spender utils.Address) *big.Int
-	Approve(uid uint64, sig []byte, tAddr, caller, spender utils.Address, value *big.Int) error
-	Transfer(uid uint64, sig []byte, tAddr, caller, to utils.Address, value *big.Int) error
-	TransferFrom(uid uint64, sig []byte, tAddr, caller, from, to utils.Address, value *big.Int) error
-	MintToken(uid uint64, sig []byte, tAddr, caller, target utils.Address, mintedAmount *big.Int) error
-	Burn(uid uint64, sig []byte, tAddr, caller utils.Address, burnAmount *big.Int) error
-	AirDrop(uid uint64, sig []byte, tAddr, caller utils.Address, addrs []utils.Address, money *big.Int) error
+	Approve(uid uint64, sig []byte, tAddr, caller, spender utils.Address, value *big.Int) (utils.Hash, error)
+	Transfer(uid uint64, sig []byte, tAddr, caller, to utils.Address, value *big.Int) (utils.Hash, error)
+	TransferFrom(uid uint64, sig []byte, tAddr, caller, from, to utils.Address, value *big.Int) (utils.Hash, error)
+	MintToken(uid uint64, sig []byte, tAddr, caller, target utils.Address, mintedAmount *big.Int) (utils.Hash, error)
+	Burn(uid uint64, sig []byte, tAddr, caller utils.Address, burnAmount *big.Int) (utils.Hash, error)
+	AirDrop(uid uint64, sig []byte, tAddr, caller utils.Address, addrs []utils.Address, money *big.Int) (utils.Hash, error)
 
-	CreateRoleMgr(uid uint64, sig []byte, caller, founder, token utils.Address) (utils.Address, error)
-	Register(uid uint64, sig []byte, caller, addr utils.Address, sign []byte) error
-	RegisterToken(uid uint64, sig []byte, caller, taddr utils.Address) error
-	RegisterKeeper(uid uint64, sig []byte, caller utils.Address, index uint64, blsKey, signature []byte) error
-	RegisterProvider(uid uint64, sig []byte, caller utils.Address, index uint64, signature []byte) error
-	RegisterUser(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, blsKey []byte) error
-	Pledge(uid uint64, sig []byte, caller utils.Address, index uint64, money *big.Int) error
-	Withdraw(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, money *big.Int) error
-	CreateGroup(uid uint64, sig []byte, caller utils.Address, level uint16) error
-	AddKeeperToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, asign []byte) error
-	AddProviderToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64) error
-	Recharge(uid uint64, sig []byte, caller utils.Address, user uint64, tokenIndex uint32, money *big.Int) error
-	ProWithdraw(uid uint64, sig []byte, caller utils.Address, proIndex uint64, tokenIndex uint32, pay, lost *big.Int, ksigns [][]byte) error
-	WithdrawFromFs(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, amount *big.Int) error
-	AddOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error
-	SubOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error
+	CreateRoleMgr(uid uint64, sig []byte, caller, founder, token utils.Address) (*types.CreateResult, error)
+	Register(uid uint64, sig []byte, caller, addr utils.Address, sign []byte) (utils.Hash, error)
+	RegisterToken(uid uint64, sig []byte, caller, taddr utils.Address) (utils.Hash, error)
+	RegisterKeeper(uid uint64, sig []byte, caller utils.Address, index uint64, blsKey, signature []byte) (utils.Hash, error)
+	RegisterProvider(uid uint64, sig []byte, caller utils.Address, index uint64, signature []byte) (utils.Hash, error)
+	RegisterUser(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, blsKey []byte) (utils.Hash, error)
+	Pledge(uid uint64, sig []byte, caller utils.Address, index uint64, money *big.Int) (utils.Hash, error)
+	Withdraw(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, money *big.Int) (utils.Hash, error)
+	CreateGroup(uid uint64, sig []byte, caller utils.Address, level uint16) (utils.Hash, error)
+	Propose(uid uint64, sig []byte, caller utils.Address, op uint8, paras *contract.ProposalParas) (*types.ProposeResult, error)
+	ApproveProposal(uid uint64, sig []byte, caller utils.Address, id uint64) (utils.Hash, error)
+	ExecuteProposal(uid uint64, sig []byte, caller utils.Address, id uint64) (utils.Hash, error)
+	TransferOwnership(uid uint64, sig []byte, caller, caddr, newOwner utils.Address) (utils.Hash, error)
+	AcceptOwnership(uid uint64, sig []byte, caller, caddr utils.Address) (utils.Hash, error)
+	ChangeAddress(uid uint64, sig []byte, caller utils.Address, index uint64, newAddr utils.Address, oldSig, newSig []byte) (utils.Hash, error)
+	ConfirmAddress(uid uint64, sig []byte, caller utils.Address, index uint64) (utils.Hash, error)
+	CancelAddress(uid uint64, sig []byte, caller utils.Address, index uint64) (utils.Hash, error)
+	AddKeeperToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, asigns [][]byte) (utils.Hash, error)
+	AddProviderToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64) (utils.Hash, error)
+	SetReady(uid uint64, sig []byte, caller utils.Address, gIndex uint64, ksigns [][]byte) (utils.Hash, error)
+	Recharge(uid uint64, sig []byte, caller utils.Address, user uint64, tokenIndex uint32, money *big.Int) (utils.Hash, error)
+	ProWithdraw(uid uint64, sig []byte, caller utils.Address, proIndex uint64, tokenIndex uint32, pay, lost *big.Int, ksigns [][]byte) (utils.Hash, error)
+	WithdrawFromFs(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, amount *big.Int) (utils.Hash, error)
+	AddOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) (utils.Hash, error)
+	SubOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) (utils.Hash, error)
+	AddRepair(uid uint64, sig []byte, caller utils.Address, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) (utils.Hash, error)
+	SubRepair(uid uint64, sig []byte, caller utils.Address, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) (utils.Hash, error)
 
 	GetIndex(caller, addr utils.Address) (uint64, error)
 	GetAddr(caller utils.Address, index uint64) (utils.Address, error)
@@ -63,4 +110,8 @@ type ChainAPI interface {
 	GetAllAddrs(caller utils.Address) []utils.Address
 	GetAllGroups(caller utils.Address) []*contract.GroupInfo
 	GetFoundation(caller utils.Address) utils.Address
+	GetAdmins(caller utils.Address) (*contract.AdminInfo, error)
+	GetProposal(caller utils.Address, id uint64) (*contract.Proposal, error)
+	GetOwnerInfo(caller, caddr utils.Address) (*contract.OwnerInfo, error)
+	GetAddrChange(caller utils.Address, index uint64) (*contract.AddrChange, error)
 }
@@ -1,12 +1,12 @@
 package node
 
 import (
-	"encoding/binary"
 	"math/big"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/types"
 	"github.com/memoio/go-settlement/utils"
-	"github.com/minio/blake2b-simd"
 )
 
 func (n *Node) getTokenMgr(addr utils.Address) (contract.ErcToken, error) {
@@ -17,196 +17,173 @@ func (n *Node) getTokenMgr(addr utils.Address) (contract.ErcToken, error) {
 	return nil, ErrRes
 }
 
-func (n *Node) CreateErcToken(uid uint64, sig []byte, caller utils.Address) (utils.Address, error) {
-	n.Lock()
-	defer n.Unlock()
-
-	n.count++
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return utils.NilAddress, ErrNonce
+func (n *Node) CreateErcToken(uid uint64, sig []byte, caller utils.Address) (*types.CreateResult, error) {
+	ret, tx, err := n.submit("CreateErcToken", uid, sig, caller, []interface{}{})
+	if tx == utils.NilHash {
+		return nil, err
 	}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return utils.NilAddress, ErrRes
-	}
+	return &types.CreateResult{
+		Addr:   utils.BytesToAddress(ret),
+		TxHash: tx,
+	}, err
+}
 
-	et := contract.NewErcToken(caller)
+func (n *Node) execCreateErcToken(c *Call) ([]byte, error) {
+	et := contract.NewErcToken(n.state, c.Caller)
 
 	n.ercMap[et.GetContractAddress()] = et
 
-	log.Info("create erctoken for: ", caller.String())
+	log.Info("create erctoken for: ", c.Caller.String())
 
-	return et.GetContractAddress(), nil
+	local := et.GetContractAddress()
+	return local[:], nil
 }
 
 // 处理， caller is from msg.Sender in real contract
-func (n *Node) Approve(uid uint64, sig []byte, tAddr, caller, spender utils.Address, value *big.Int) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) Approve(uid uint64, sig []byte, tAddr, caller, spender utils.Address, value *big.Int) (utils.Hash, error) {
+	_, tx, err := n.submit("Approve", uid, sig, caller, &approveParams{
+		TAddr:   tAddr,
+		Spender: spender,
+		Value:   value,
+	})
+	return tx, err
+}
 
-	n.count++
-	er, err := n.getTokenMgr(tAddr)
+func (n *Node) execApprove(c *Call) ([]byte, error) {
+	p := new(approveParams)
+	err := cbor.Unmarshal(c.Params, p)
 	if err != nil {
-		return err
-	}
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
+		return nil, err
 	}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+	er, err := n.getTokenMgr(p.TAddr)
+	if err != nil {
+		return nil, err
 	}
 
-	er.Approve(caller, spender, value)
-	return nil
+	er.Approve(c.Caller, p.Spender, p.Value)
+	return nil, nil
 }
 
-func (n *Node) Transfer(uid uint64, sig []byte, tAddr, caller, to utils.Address, value *big.Int) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) Transfer(uid uint64, sig []byte, tAddr, caller, to utils.Address, value *big.Int) (utils.Hash, error) {
+	_, tx, err := n.submit("Transfer", uid, sig, caller, &transferParams{
+		TAddr: tAddr,
+		To:    to,
+		Value: value,
+	})
+	return tx, err
+}
 
-	n.count++
-	er, err := n.getTokenMgr(tAddr)
+func (n *Node) execTransfer(c *Call) ([]byte, error) {
+	p := new(transferParams)
+	err := cbor.Unmarshal(c.Params, p)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
-
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+	er, err := n.getTokenMgr(p.TAddr)
+	if err != nil {
+		return nil, err
 	}
 
-	return er.Transfer(caller, to, value)
+	return nil, er.Transfer(c.Caller, p.To, p.Value)
 }
 
-func (n *Node) TransferFrom(uid uint64, sig []byte, tAddr, caller, from, to utils.Address, value *big.Int) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) TransferFrom(uid uint64, sig []byte, tAddr, caller, from, to utils.Address, value *big.Int) (utils.Hash, error) {
+	_, tx, err := n.submit("TransferFrom", uid, sig, caller, &transferFromParams{
+		TAddr: tAddr,
+		From:  from,
+		To:    to,
+		Value: value,
+	})
+	return tx, err
+}
 
-	n.count++
-	er, err := n.getTokenMgr(tAddr)
+func (n *Node) execTransferFrom(c *Call) ([]byte, error) {
+	p := new(transferFromParams)
+	err := cbor.Unmarshal(c.Params, p)
 	if err != nil {
-		return err
-	}
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
+		return nil, err
 	}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+	er, err := n.getTokenMgr(p.TAddr)
+	if err != nil {
+		return nil, err
 	}
 
-	return er.TransferFrom(caller, from, to, value)
+	return nil, er.TransferFrom(c.Caller, p.From, p.To, p.Value)
 }
 
-func (n *Node) MintToken(uid uint64, sig []byte, tAddr, caller, target utils.Address, mintedAmount *big.Int) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) MintToken(uid uint64, sig []byte, tAddr, caller, target utils.Address, mintedAmount *big.Int) (utils.Hash, error) {
+	_, tx, err := n.submit("MintToken", uid, sig, caller, &mintTokenParams{
+		TAddr:        tAddr,
+		Target:       target,
+		MintedAmount: mintedAmount,
+	})
+	return tx, err
+}
 
-	n.count++
-	er, err := n.getTokenMgr(tAddr)
+func (n *Node) execMintToken(c *Call) ([]byte, error) {
+	p := new(mintTokenParams)
+	err := cbor.Unmarshal(c.Params, p)
 	if err != nil {
-		return err
-	}
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
+		return nil, err
 	}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+	er, err := n.getTokenMgr(p.TAddr)
+	if err != nil {
+		return nil, err
 	}
 
-	return er.MintToken(caller, target, mintedAmount)
+	return nil, er.MintToken(c.Caller, p.Target, p.MintedAmount)
 }
 
-func (n *Node) Burn(uid uint64, sig []byte, tAddr, caller utils.Address, burnAmount *big.Int) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) Burn(uid uint64, sig []byte, tAddr, caller utils.Address, burnAmount *big.Int) (utils.Hash, error) {
+	_, tx, err := n.submit("Burn", uid, sig, caller, &burnParams{
+		TAddr:      tAddr,
+		BurnAmount: burnAmount,
+	})
+	return tx, err
+}
 
-	n.count++
-	er, err := n.getTokenMgr(tAddr)
+func (n *Node) execBurn(c *Call) ([]byte, error) {
+	p := new(burnParams)
+	err := cbor.Unmarshal(c.Params, p)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
-
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+	er, err := n.getTokenMgr(p.TAddr)
+	if err != nil {
+		return nil, err
 	}
 
-	return er.Burn(caller, burnAmount)
+	return nil, er.Burn(c.Caller, p.BurnAmount)
 }
 
-func (n *Node) AirDrop(uid uint64, sig []byte, tAddr, caller utils.Address, addrs []utils.Address, money *big.Int) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) AirDrop(uid uint64, sig []byte, tAddr, caller utils.Address, addrs []utils.Address, money *big.Int) (utils.Hash, error) {
+	_, tx, err := n.submit("AirDrop", uid, sig, caller, &airDropParams{
+		TAddr: tAddr,
+		Addrs: addrs,
+		Money: money,
+	})
+	return tx, err
+}
 
-	n.count++
-	er, err := n.getTokenMgr(tAddr)
+func (n *Node) execAirDrop(c *Call) ([]byte, error) {
+	p := new(airDropParams)
+	err := cbor.Unmarshal(c.Params, p)
 	if err != nil {
-		return err
-	}
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
+		return nil, err
 	}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+	er, err := n.getTokenMgr(p.TAddr)
+	if err != nil {
+		return nil, err
 	}
 
-	return er.AirDrop(caller, addrs, money)
+	return nil, er.AirDrop(c.Caller, p.Addrs, p.Money)
 }
 
 func (n *Node) TotalSupply(tAddr, caller utils.Address) *big.Int {
@@ -0,0 +1,68 @@
+package node
+
+import (
+	"encoding/binary"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+var eventPrefix = []byte("event/") // height, tx index, event index -> event
+
+func eventHeightPrefix(height uint64) []byte {
+	key := make([]byte, len(eventPrefix)+8)
+	copy(key, eventPrefix)
+	binary.BigEndian.PutUint64(key[len(eventPrefix):], height)
+	return key
+}
+
+func eventKey(height uint64, tx, index uint32) []byte {
+	key := make([]byte, len(eventPrefix)+16)
+	copy(key, eventHeightPrefix(height))
+	binary.BigEndian.PutUint32(key[len(eventPrefix)+8:], tx)
+	binary.BigEndian.PutUint32(key[len(eventPrefix)+12:], index)
+	return key
+}
+
+// GetEvents returns events selected by filter in order; nil filter
+// selects all
+func (n *Node) GetEvents(caller utils.Address, filter *types.EventFilter) ([]*types.Event, error) {
+	if filter == nil {
+		filter = new(types.EventFilter)
+	}
+
+	n.RLock()
+	defer n.RUnlock()
+
+	latest := n.head.Height
+	if n.pending != nil {
+		latest = n.pending.Height
+	}
+
+	to := filter.ToHeight
+	if to == 0 || to > latest {
+		to = latest
+	}
+
+	res := make([]*types.Event, 0)
+	for h := filter.FromHeight; h <= to; h++ {
+		err := n.ds.Iter(eventHeightPrefix(h), func(key, value []byte) error {
+			ev := new(types.Event)
+			err := cbor.Unmarshal(value, ev)
+			if err != nil {
+				return err
+			}
+
+			if filter.Match(ev) {
+				res = append(res, ev)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,137 @@
+package node
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+)
+
+func TestEvents(t *testing.T) {
+	end := uint64(1600041600)
+	n, err := NewNode(store.NewMemStore(), contract.NewMockClock(end-100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	gindex := testCreateGroup(t, n, admin)
+	for i := 0; i < 7; i++ {
+		testAddKeeper(t, n, admin, gindex)
+	}
+	kIndex := testAddKeeper(t, n, admin, gindex)
+	pIndex := testAddProvider(t, n, admin, gindex)
+	uIndex := testCreateUser(t, n, admin, gindex)
+
+	testAdvanceTime(t, n, admin, 10)
+	head, _ := n.ChainHead(admin)
+
+	testAddOrder(t, n, admin, kIndex, uIndex, pIndex, end-200, end, 300, 0)
+
+	evs, err := n.GetEvents(admin, &types.EventFilter{
+		Contract: taddr,
+		Types:    []string{types.EventTransfer},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) == 0 {
+		t.Fatal("no transfer event")
+	}
+	for _, ev := range evs {
+		if ev.Contract != taddr || ev.Type != types.EventTransfer {
+			t.Fatal("filter by contract and type is wrong")
+		}
+		tev, err := ev.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := tev.(*types.TransferEvent); !ok {
+			t.Fatal("decode transfer event fails")
+		}
+	}
+
+	// nil filter selects all
+	all, err := n.GetEvents(admin, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) <= len(evs) {
+		t.Fatal("nil filter should get all events: ", len(all))
+	}
+
+	// user is registered, then as user
+	evs, err = n.GetEvents(admin, &types.EventFilter{
+		Types:   []string{types.EventRegistered},
+		Indexes: []uint64{uIndex},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 2 {
+		t.Fatal("registered events of user are wrong: ", len(evs))
+	}
+	rev, err := evs[1].Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev.(*types.RegisteredEvent).RoleType != contract.RoleUser {
+		t.Fatal("registered event is wrong")
+	}
+
+	// only order is in the last block
+	evs, err = n.GetEvents(admin, &types.EventFilter{
+		FromHeight: head.Height + 1,
+		Indexes:    []uint64{pIndex},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 1 || evs[0].Type != types.EventOrderAdded {
+		t.Fatal("events by height are wrong: ", len(evs))
+	}
+	oev, err := evs[0].Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := oev.(*types.OrderEvent)
+	if order.User != uIndex || order.Keeper != kIndex || order.End != end {
+		t.Fatal("order event is wrong")
+	}
+
+	r, err := n.GetReceipt(admin, evs[0].TxHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Events) != 1 || r.Events[0].Type != types.EventOrderAdded {
+		t.Fatal("receipt events are wrong")
+	}
+
+	evs, err = n.GetEvents(admin, &types.EventFilter{ToHeight: head.Height, Types: []string{types.EventOrderAdded}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evs) != 0 {
+		t.Fatal("filter by height is wrong")
+	}
+
+	// failed call emits nothing
+	uid := n.GetNonce(admin, admin)
+	tx, err := n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, founder, new(big.Int).Lsh(big.NewInt(1), 200)), taddr, admin, founder, new(big.Int).Lsh(big.NewInt(1), 200))
+	if err == nil {
+		t.Fatal("transfer more than balance should fail")
+	}
+	r, err = n.GetReceipt(admin, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Events) != 0 {
+		t.Fatal("failed call should not have events")
+	}
+}
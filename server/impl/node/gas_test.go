@@ -0,0 +1,165 @@
+package node
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestGas(t *testing.T) {
+	n, err := NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	user := testNewKey(t)
+	to := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+	foundation := n.GetFoundation(admin)
+
+	// free call still reports gas
+	uid := n.GetNonce(admin, admin)
+	tx, err := n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, user, big.NewInt(1e12)), taddr, admin, user, big.NewInt(1e12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := n.GetReceipt(admin, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.GasUsed <= contract.GasCall {
+		t.Fatal("gas used is wrong: ", r.GasUsed)
+	}
+
+	n.SetGasPrice(big.NewInt(2))
+
+	ubal := n.BalanceOf(taddr, user, user)
+	fbal := n.BalanceOf(taddr, user, foundation)
+
+	uid = n.GetNonce(user, user)
+	tx, err = n.Transfer(uid, sign(t, user, "Transfer", uid, taddr, to, big.NewInt(10)), taddr, user, to, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = n.GetReceipt(user, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fee := new(big.Int).SetUint64(r.GasUsed * 2)
+	ubal.Sub(ubal, fee)
+	ubal.Sub(ubal, big.NewInt(10))
+	fbal.Add(fbal, fee)
+	if n.BalanceOf(taddr, user, user).Cmp(ubal) != 0 || n.BalanceOf(taddr, user, foundation).Cmp(fbal) != 0 {
+		t.Fatal("fee is not paid to foundation")
+	}
+
+	// fee is a Fee event, not a Transfer
+	transfers := 0
+	for _, ev := range r.Events {
+		switch ev.Type {
+		case types.EventTransfer:
+			transfers++
+		case types.EventFee:
+			fe, err := ev.Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := fe.(*types.FeeEvent)
+			if f.Payer != user || f.Foundation != foundation || f.Value.Cmp(fee) != 0 {
+				t.Fatal("fee event is wrong: ", f)
+			}
+		}
+	}
+	if transfers != 1 || len(r.Events) != 2 {
+		t.Fatal("events of paid transfer are wrong: ", len(r.Events), transfers)
+	}
+
+	// limit below intrinsic gas is rejected and keeps nonce
+	uid = n.GetNonce(user, user)
+	_, _, err = n.submitGas("Transfer", uid, sign(t, user, "Transfer", uid, taddr, to, big.NewInt(1)), user, &transferParams{TAddr: taddr, To: to, Value: big.NewInt(1)}, contract.GasCall, big.NewInt(2))
+	if err != ErrGas {
+		t.Fatal("low gas limit should be rejected: ", err)
+	}
+	if n.GetNonce(user, user) != uid {
+		t.Fatal("rejected call should not use nonce")
+	}
+
+	// out of gas reverts call, but whole limit is paid
+	ubal = n.BalanceOf(taddr, user, user)
+	limit := contract.GasCall + 1000
+	_, tx, err = n.submitGas("Transfer", uid, sign(t, user, "Transfer", uid, taddr, to, big.NewInt(1)), user, &transferParams{TAddr: taddr, To: to, Value: big.NewInt(1)}, limit, big.NewInt(2))
+	if err != contract.ErrOutOfGas {
+		t.Fatal("call should be out of gas: ", err)
+	}
+	r, err = n.GetReceipt(user, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ErrCode != types.CodeOutOfGas || r.GasUsed != limit {
+		t.Fatal("receipt of out of gas is wrong: ", r)
+	}
+	ubal.Sub(ubal, new(big.Int).SetUint64(limit*2))
+	if n.BalanceOf(taddr, user, user).Cmp(ubal) != 0 {
+		t.Fatal("out of gas should pay whole limit")
+	}
+
+	// fee is paid in fee token registered in roleMgr
+	n.SetGasPrice(new(big.Int))
+	t2 := testErc(t, n, founder)
+	uid = n.GetNonce(admin, admin)
+	_, err = n.RegisterToken(uid, sign(t, admin, "RegisterToken", uid, t2), admin, t2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid = n.GetNonce(founder, founder)
+	_, err = n.Transfer(uid, sign(t, founder, "Transfer", uid, t2, user, big.NewInt(1e12)), t2, founder, user, big.NewInt(1e12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.SetFeeToken(t2)
+	n.SetGasPrice(big.NewInt(2))
+
+	ubal = n.BalanceOf(taddr, user, user)
+	u2bal := n.BalanceOf(t2, user, user)
+	f2bal := n.BalanceOf(t2, user, foundation)
+	uid = n.GetNonce(user, user)
+	tx, err = n.Transfer(uid, sign(t, user, "Transfer", uid, taddr, to, big.NewInt(10)), taddr, user, to, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = n.GetReceipt(user, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee = new(big.Int).SetUint64(r.GasUsed * 2)
+	ubal.Sub(ubal, big.NewInt(10))
+	u2bal.Sub(u2bal, fee)
+	f2bal.Add(f2bal, fee)
+	if n.BalanceOf(taddr, user, user).Cmp(ubal) != 0 || n.BalanceOf(t2, user, user).Cmp(u2bal) != 0 || n.BalanceOf(t2, user, foundation).Cmp(f2bal) != 0 {
+		t.Fatal("fee is not paid in fee token")
+	}
+
+	// token not in roleMgr pays no fee
+	n.SetFeeToken(testErc(t, n, user))
+	uid = n.GetNonce(user, user)
+	_, err = n.Transfer(uid, sign(t, user, "Transfer", uid, taddr, to, big.NewInt(10)), taddr, user, to, big.NewInt(10))
+	if err != ErrFee {
+		t.Fatal("fee token not in roleMgr should be rejected: ", err)
+	}
+	n.SetFeeToken(utils.NilAddress)
+
+	// caller without fee is rejected
+	uid = n.GetNonce(to, to)
+	_, err = n.Transfer(uid, sign(t, to, "Transfer", uid, taddr, user, big.NewInt(1)), taddr, to, user, big.NewInt(1))
+	if err != ErrFee {
+		t.Fatal("caller without fee should be rejected: ", err)
+	}
+}
@@ -0,0 +1,380 @@
+package node
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+	"golang.org/x/xerrors"
+)
+
+var genesisSpecKey = []byte("genesis/spec")
+
+// InitGenesis stores genesis of chainID in ds if there is none;
+// a stored genesis of other chain ID is an error
+func InitGenesis(ds store.KVStore, chainID uint64) (*types.Block, error) {
+	g, err := LoadGenesis(ds)
+	if err == nil {
+		if g.ChainID != chainID {
+			return nil, ErrChainID
+		}
+		return g, nil
+	}
+	if err != ErrBlock {
+		return nil, err
+	}
+
+	g = types.Genesis(chainID)
+	bt := ds.NewBatch()
+	err = putGenesis(bt, g)
+	if err != nil {
+		return nil, err
+	}
+	err = bt.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// LoadGenesis returns genesis in ds, ErrBlock if there is none
+func LoadGenesis(ds store.KVStore) (*types.Block, error) {
+	g, err := loadBlock(ds, 0)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, ErrBlock
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
+func putGenesis(bt store.Batch, g *types.Block) error {
+	val, err := cbor.Marshal(g)
+	if err != nil {
+		return err
+	}
+
+	height := make([]byte, 8)
+	bt.Put(blockKey(0), val)
+	bt.Put(blockHashKey(g.Hash()), height)
+	return nil
+}
+
+// GenesisSpec is initial state of a chain; every node applying it
+// gets the same genesis, whose Parent is root of the initial state
+type GenesisSpec struct {
+	ChainID uint64 // 0 is default chain id
+	Time    uint64 // contract time of genesis
+	Tokens  []*GenesisToken
+	RoleMgr *GenesisRoleMgr
+}
+
+// GenesisToken is created by Admin, which is distinct for each token,
+// then Admin pays Holders
+type GenesisToken struct {
+	Admin   utils.Address
+	Supply  *big.Int // nil keeps supply of new token
+	Holders []*GenesisBalance
+}
+
+type GenesisBalance struct {
+	Addr  utils.Address
+	Value *big.Int
+}
+
+// GenesisRoleMgr takes first token as primary, others are registered in order
+type GenesisRoleMgr struct {
+	Admin          utils.Address
+	Foundation     utils.Address
+	KeeperPledge   *big.Int             // nil is KeeperDeposit Token
+	ProviderPledge *big.Int             // nil is ProviderDeposit Token
+	Mint           []*contract.MintInfo // nil keeps default table
+	Groups         []uint16             // level of each group
+	Accounts       []*GenesisAccount    // registered in order
+}
+
+// GenesisAccount pledges from its primary token, so it should be a holder;
+// keepers of a group come before its users
+type GenesisAccount struct {
+	Addr   utils.Address
+	Role   string        // "", "keeper", "provider" or "user"
+	Pledge *big.Int      // nil is pledge of role
+	GIndex uint64        // group of keeper, provider and user
+	BlsKey hexutil.Bytes // pub key with its proof, of keeper and user
+}
+
+// ApplyGenesis stores genesis made by spec in ds if there is none;
+// a stored genesis made otherwise is an error
+func ApplyGenesis(ds store.KVStore, spec *GenesisSpec) (*types.Block, error) {
+	g, s, ns, err := makeGenesis(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	og, err := LoadGenesis(ds)
+	if err == nil {
+		if og.Hash() != g.Hash() {
+			return nil, ErrGenesis
+		}
+		return og, nil
+	}
+	if err != ErrBlock {
+		return nil, err
+	}
+
+	bt := ds.NewBatch()
+	err = putGenesis(bt, g)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Save(bt)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := cbor.Marshal(ns)
+	if err != nil {
+		return nil, err
+	}
+	bt.Put(nodeKey, val)
+
+	val, err = cbor.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	bt.Put(genesisSpecKey, val)
+
+	err = bt.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("apply genesis %s of chain %d, state root %s", g.Hash(), g.ChainID, g.Parent)
+
+	return g, nil
+}
+
+// LoadGenesisSpec returns spec applied to ds, store.ErrNotFound if genesis
+// is not made by a spec
+func LoadGenesisSpec(ds store.KVStore) (*GenesisSpec, error) {
+	val, err := ds.Get(genesisSpecKey)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := new(GenesisSpec)
+	err = cbor.Unmarshal(val, spec)
+	if err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// makeGenesis applies spec on an empty state at spec time
+func makeGenesis(spec *GenesisSpec) (*types.Block, *contract.State, *nodeState, error) {
+	chainID := spec.ChainID
+	if chainID == 0 {
+		chainID = utils.DefaultChainID
+	}
+
+	s := contract.NewState(contract.NewMockClock(spec.Time))
+	s.SetChainID(chainID)
+
+	ns := &nodeState{
+		Tokens: make([]utils.Address, 0, len(spec.Tokens)),
+		Nonce:  make(map[utils.Address]uint64),
+	}
+
+	for i, gt := range spec.Tokens {
+		taddr, err := applyToken(s, gt)
+		if err != nil {
+			return nil, nil, nil, xerrors.Errorf("genesis token %d: %w", i, err)
+		}
+		ns.Tokens = append(ns.Tokens, taddr)
+	}
+
+	if spec.RoleMgr != nil {
+		if len(ns.Tokens) == 0 {
+			return nil, nil, nil, xerrors.Errorf("genesis roleMgr has no token: %w", ErrSpec)
+		}
+
+		raddr, err := applyRoleMgr(s, spec.RoleMgr, ns.Tokens)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		ns.RoleMgr = raddr
+	}
+
+	// events of genesis are not kept
+	s.TakeEvents()
+
+	root, err := s.Root()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	g := types.Genesis(chainID)
+	g.Time = spec.Time
+	g.Parent = root
+
+	return g, s, ns, nil
+}
+
+func applyToken(s *contract.State, gt *GenesisToken) (utils.Address, error) {
+	if gt == nil {
+		return utils.NilAddress, ErrSpec
+	}
+
+	taddr := utils.GetContractAddress(gt.Admin, []byte("ErcToken"))
+	_, err := s.GetErcToken(taddr)
+	if err == nil {
+		return utils.NilAddress, xerrors.Errorf("admin %s has a token: %w", gt.Admin, ErrSpec)
+	}
+
+	et := contract.NewErcToken(s, gt.Admin)
+	if gt.Supply != nil {
+		supply := et.TotalSupply(gt.Admin)
+		switch gt.Supply.Cmp(supply) {
+		case 1:
+			err = et.MintToken(gt.Admin, gt.Admin, new(big.Int).Sub(gt.Supply, supply))
+		case -1:
+			err = et.Burn(gt.Admin, new(big.Int).Sub(supply, gt.Supply))
+		}
+		if err != nil {
+			return utils.NilAddress, err
+		}
+	}
+
+	for _, gb := range gt.Holders {
+		if gb == nil || gb.Value == nil {
+			return utils.NilAddress, ErrSpec
+		}
+
+		err = et.Transfer(gt.Admin, gb.Addr, gb.Value)
+		if err != nil {
+			return utils.NilAddress, xerrors.Errorf("pay holder %s: %w", gb.Addr, err)
+		}
+	}
+
+	return taddr, nil
+}
+
+func applyRoleMgr(s *contract.State, gr *GenesisRoleMgr, tokens []utils.Address) (utils.Address, error) {
+	kPledge := gr.KeeperPledge
+	if kPledge == nil {
+		kPledge = new(big.Int).Mul(new(big.Int).SetUint64(contract.KeeperDeposit), new(big.Int).SetUint64(contract.Token))
+	}
+	pPledge := gr.ProviderPledge
+	if pPledge == nil {
+		pPledge = new(big.Int).Mul(new(big.Int).SetUint64(contract.ProviderDeposit), new(big.Int).SetUint64(contract.Token))
+	}
+
+	rm := contract.NewRoleMgr(s, gr.Admin, gr.Foundation, tokens[0], new(big.Int).Set(kPledge), new(big.Int).Set(pPledge))
+
+	for _, taddr := range tokens[1:] {
+		err := rm.RegisterToken(gr.Admin, taddr)
+		if err != nil {
+			return utils.NilAddress, xerrors.Errorf("genesis register token %s: %w", taddr, err)
+		}
+	}
+
+	if gr.Mint != nil {
+		err := rm.SetMintInfo(gr.Admin, gr.Mint)
+		if err != nil {
+			return utils.NilAddress, xerrors.Errorf("genesis mint: %w", err)
+		}
+	}
+
+	for i, level := range gr.Groups {
+		err := rm.CreateGroup(gr.Admin, level)
+		if err != nil {
+			return utils.NilAddress, xerrors.Errorf("genesis group %d: %w", i, err)
+		}
+	}
+
+	for i, ga := range gr.Accounts {
+		if ga == nil {
+			return utils.NilAddress, xerrors.Errorf("genesis account %d: %w", i, ErrSpec)
+		}
+
+		err := applyAccount(s, rm, gr.Admin, tokens[0], ga)
+		if err != nil {
+			return utils.NilAddress, xerrors.Errorf("genesis account %d: %w", i, err)
+		}
+	}
+
+	return rm.GetContractAddress(), nil
+}
+
+// applyAccount registers ga, pledges and gets its role; foundation is
+// registered already
+func applyAccount(s *contract.State, rm contract.RoleMgr, admin, primary utils.Address, ga *GenesisAccount) error {
+	addr := ga.Addr
+	_, err := rm.GetIndex(addr, addr)
+	if err != nil {
+		err = rm.Register(addr, addr, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	index, err := rm.GetIndex(addr, addr)
+	if err != nil {
+		return err
+	}
+
+	money := ga.Pledge
+	kPledge, pPledge := rm.GetPledge(addr)
+	switch ga.Role {
+	case "keeper":
+		if money == nil {
+			money = kPledge
+		}
+	case "provider":
+		if money == nil {
+			money = pPledge
+		}
+	case "", "user":
+	default:
+		return xerrors.Errorf("role %q: %w", ga.Role, ErrSpec)
+	}
+
+	if money != nil && money.Sign() > 0 {
+		et, err := s.GetErcToken(primary)
+		if err != nil {
+			return err
+		}
+		et.Approve(addr, rm.GetPledgeAddress(addr), money)
+
+		err = rm.Pledge(addr, index, new(big.Int).Set(money))
+		if err != nil {
+			return err
+		}
+	}
+
+	switch ga.Role {
+	case "keeper":
+		err = rm.RegisterKeeper(addr, index, ga.BlsKey, nil)
+		if err != nil {
+			return err
+		}
+		return rm.AddKeeperToGroup(admin, index, ga.GIndex, nil)
+	case "provider":
+		err = rm.RegisterProvider(addr, index, nil)
+		if err != nil {
+			return err
+		}
+		return rm.AddProviderToGroup(addr, index, ga.GIndex)
+	case "user":
+		return rm.RegisterUser(addr, index, ga.GIndex, ga.BlsKey)
+	}
+
+	return nil
+}
@@ -0,0 +1,276 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestGenesis(t *testing.T) {
+	ds := store.NewMemStore()
+	g, err := InitGenesis(ds, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = InitGenesis(ds, 8)
+	if err != ErrChainID {
+		t.Fatal("genesis of other chain id should be rejected")
+	}
+
+	n, err := NewNode(ds, contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	if n.ChainID(admin) != 7 {
+		t.Fatal("chain id is not from genesis: ", n.ChainID(admin))
+	}
+
+	gb, err := n.GetBlockByHash(admin, g.Hash())
+	if err != nil || gb.ChainID != 7 {
+		t.Fatal("genesis is not found: ", err)
+	}
+
+	// sig of default chain does not work on chain 7
+	uid := n.GetNonce(admin, admin)
+	_, err = n.CreateErcToken(uid, sign(t, admin, "CreateErcToken", uid), admin)
+	if err != ErrRes {
+		t.Fatal("sig of other chain id should be rejected")
+	}
+
+	key := testKey(t, admin)
+	sig, err := utils.SignCall(key.SecretKey, "CreateErcToken", 7, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = n.CreateErcToken(uid, sig, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := n.ChainHead(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Height != 0 || b.Hash() != g.Hash() {
+		t.Fatal("head is not genesis")
+	}
+}
+
+func TestRegisterSign(t *testing.T) {
+	n := testNewNode(t)
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	raddr := testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	// admin relays register of uAddr
+	uAddr := testNewKey(t)
+	uid := n.GetNonce(admin, admin)
+	_, err := n.Register(uid, sign(t, admin, "Register", uid, uAddr, []byte(nil)), admin, uAddr, nil)
+	if err != contract.ErrSign {
+		t.Fatal("register of other address needs its sign: ", err)
+	}
+
+	h, err := contract.RegisterDigest(utils.DefaultChainID+1, raddr, uAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usig, err := utils.Sign(testKey(t, uAddr).SecretKey, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid = n.GetNonce(admin, admin)
+	_, err = n.Register(uid, sign(t, admin, "Register", uid, uAddr, usig), admin, uAddr, usig)
+	if err != contract.ErrSign {
+		t.Fatal("sign of other chain id should be rejected: ", err)
+	}
+
+	h, err = contract.RegisterDigest(utils.DefaultChainID, raddr, uAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usig, err = utils.Sign(testKey(t, uAddr).SecretKey, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid = n.GetNonce(admin, admin)
+	_, err = n.Register(uid, sign(t, admin, "Register", uid, uAddr, usig), admin, uAddr, usig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = n.GetIndex(admin, uAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenesisSpec(t *testing.T) {
+	tAdmin := testNewKey(t)
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	keeper := testNewKey(t)
+	pro := testNewKey(t)
+	user := testNewKey(t)
+
+	kPledge := big.NewInt(1000)
+	spec := &GenesisSpec{
+		ChainID: 9,
+		Time:    1600000000,
+		Tokens: []*GenesisToken{
+			{
+				Admin:  tAdmin,
+				Supply: big.NewInt(1e9),
+				Holders: []*GenesisBalance{
+					{Addr: keeper, Value: big.NewInt(5000)},
+					{Addr: pro, Value: big.NewInt(5000)},
+				},
+			},
+			{
+				Admin: admin,
+			},
+		},
+		RoleMgr: &GenesisRoleMgr{
+			Admin:          admin,
+			Foundation:     founder,
+			KeeperPledge:   kPledge,
+			ProviderPledge: big.NewInt(100),
+			Mint: []*contract.MintInfo{
+				{Ratio: 100, Size: 1, Duration: 1},
+			},
+			Groups: []uint16{1},
+			Accounts: []*GenesisAccount{
+				{Addr: keeper, Role: "keeper"},
+				{Addr: pro, Role: "provider", Pledge: big.NewInt(3000)},
+				{Addr: user, Role: "user"},
+			},
+		},
+	}
+
+	// spec is read from json
+	buf, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js := new(GenesisSpec)
+	err = json.Unmarshal(buf, js)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds := store.NewMemStore()
+	g, err := ApplyGenesis(ds, js)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g2, err := ApplyGenesis(store.NewMemStore(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Hash() != g2.Hash() || g.Parent == utils.NilHash {
+		t.Fatal("genesis of same spec is not same")
+	}
+
+	_, err = ApplyGenesis(ds, spec)
+	if err != nil {
+		t.Fatal("same spec should be accepted again: ", err)
+	}
+
+	_, err = InitGenesis(ds, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec.Tokens[0].Supply = big.NewInt(2e9)
+	_, err = ApplyGenesis(ds, spec)
+	if err != ErrGenesis {
+		t.Fatal("other spec should be rejected: ", err)
+	}
+
+	ls, err := LoadGenesisSpec(ds)
+	if err != nil || ls.Tokens[0].Supply.Cmp(big.NewInt(1e9)) != 0 {
+		t.Fatal("spec is not stored: ", err)
+	}
+
+	n, err := NewNode(ds, contract.NewMockClock(1600000100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.ChainID(admin) != 9 {
+		t.Fatal("chain id is not from spec")
+	}
+
+	ts := n.GetAllTokens(admin)
+	if len(ts) != 2 {
+		t.Fatal("tokens are not registered: ", ts)
+	}
+	if n.TotalSupply(ts[0], admin).Cmp(big.NewInt(1e9)) != 0 {
+		t.Fatal("supply is wrong: ", n.TotalSupply(ts[0], admin))
+	}
+	if n.BalanceOf(ts[0], admin, pro).Cmp(big.NewInt(2000)) != 0 {
+		t.Fatal("balance is wrong: ", n.BalanceOf(ts[0], admin, pro))
+	}
+
+	for addr, role := range map[utils.Address]uint8{
+		keeper: contract.RoleKeeper,
+		pro:    contract.RoleProvider,
+		user:   contract.RoleUser,
+	} {
+		index, err := n.GetIndex(admin, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bi, err := n.GetInfo(admin, index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bi.RoleType != role {
+			t.Fatal("role is wrong: ", bi.RoleType, role)
+		}
+	}
+
+	kindex, _ := n.GetIndex(admin, keeper)
+	bal, err := n.GetBalance(admin, kindex)
+	if err != nil || bal[0].Cmp(kPledge) != 0 {
+		t.Fatal("pledge of keeper is wrong: ", bal, err)
+	}
+
+	gi, err := n.GetGroupInfo(admin, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gi.IsActive || len(gi.Keepers) != 1 || len(gi.Providers) != 1 {
+		t.Fatal("group is wrong: ", gi)
+	}
+
+	// node goes on from genesis
+	uid := n.GetNonce(admin, admin)
+	sig, err := utils.SignCall(testKey(t, admin).SecretKey, "CreateGroup", 9, uid, uint16(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = n.CreateGroup(uid, sig, admin, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wrong spec makes no genesis
+	_, err = ApplyGenesis(store.NewMemStore(), &GenesisSpec{
+		RoleMgr: &GenesisRoleMgr{Admin: admin},
+	})
+	if !errors.Is(err, ErrSpec) {
+		t.Fatal("roleMgr without token should fail: ", err)
+	}
+}
@@ -0,0 +1,132 @@
+package node
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/utils"
+)
+
+var (
+	journalPrefix = []byte("journal/")
+	journalHead   = []byte("node/journal") // seq of last journaled call
+)
+
+var errStop = errors.New("stop iteration")
+
+func journalKey(seq uint64) []byte {
+	key := make([]byte, len(journalPrefix)+8)
+	copy(key, journalPrefix)
+	binary.BigEndian.PutUint64(key[len(journalPrefix):], seq)
+	return key
+}
+
+func loadJournalHead(ds store.KVStore) (uint64, error) {
+	val, err := ds.Get(journalHead)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	if len(val) != 8 {
+		return 0, ErrRes
+	}
+
+	return binary.BigEndian.Uint64(val), nil
+}
+
+// appendJournal writes c as the next journal entry; called with lock held
+func (n *Node) appendJournal(c *Call) error {
+	val, err := cbor.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	seq := n.journal + 1
+
+	head := make([]byte, 8)
+	binary.BigEndian.PutUint64(head, seq)
+
+	b := n.ds.NewBatch()
+	b.Put(journalKey(seq), val)
+	b.Put(journalHead, head)
+	err = b.Commit()
+	if err != nil {
+		return err
+	}
+
+	n.journal = seq
+	return nil
+}
+
+// IterJournal calls fn on each journaled call of ds in order
+func IterJournal(ds store.KVStore, fn func(seq uint64, c *Call) error) error {
+	return ds.Iter(journalPrefix, func(key, value []byte) error {
+		if len(key) != len(journalPrefix)+8 {
+			return ErrRes
+		}
+		seq := binary.BigEndian.Uint64(key[len(journalPrefix):])
+
+		c := new(Call)
+		err := cbor.Unmarshal(value, c)
+		if err != nil {
+			return err
+		}
+
+		return fn(seq, c)
+	})
+}
+
+// Replay rebuilds state by executing calls journaled in src in order,
+// up to seq to (0 means all); fn is called with result of each call.
+// n should be a fresh node.
+func (n *Node) Replay(src store.KVStore, to uint64, fn func(seq uint64, c *Call, err error)) error {
+	n.Lock()
+	defer n.Unlock()
+
+	err := IterJournal(src, func(seq uint64, c *Call) error {
+		if to > 0 && seq > to {
+			return errStop
+		}
+
+		if !utils.Verify(c.Caller, c.digest(n.chainID), c.Sig) {
+			log.Errorf("journal %d has wrong sig", seq)
+			return ErrRes
+		}
+
+		n.count++
+		if n.nonceMap[c.Caller] <= c.Uid {
+			n.nonceMap[c.Caller] = c.Uid + 1
+		}
+
+		err := n.appendJournal(c)
+		if err != nil {
+			return err
+		}
+
+		// rebuild same blocks
+		if n.pending != nil && (n.pending.Height != c.Height || n.pending.Time != c.Time) {
+			n.sealBlock()
+		}
+		b := n.openBlock(c.Time)
+
+		_, used, cerr := n.apply(c)
+		if fn != nil {
+			fn(seq, c, cerr)
+		}
+
+		n.applied = n.journal
+		n.addTx(b, c, used, cerr)
+
+		return nil
+	})
+	if err != nil && err != errStop {
+		return err
+	}
+
+	return n.persist()
+}
@@ -0,0 +1,198 @@
+package node
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func stateOf(t *testing.T, ds store.KVStore) map[string][]byte {
+	res := make(map[string][]byte)
+	err := ds.Iter([]byte("contract/"), func(key, value []byte) error {
+		res[string(key)] = append([]byte(nil), value...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestReplay(t *testing.T) {
+	ds := store.NewMemStore()
+	n, err := NewNode(ds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	gindex := testCreateGroup(t, n, admin)
+	for i := 0; i < 7; i++ {
+		testAddKeeper(t, n, admin, gindex)
+	}
+	kIndex := testAddKeeper(t, n, admin, gindex)
+	pIndex := testAddProvider(t, n, admin, gindex)
+	uIndex := testCreateUser(t, n, admin, gindex)
+
+	nt := uint64(time.Now().Unix())
+	end := (nt/86400 + 1) * 86400
+	kAddr, err := n.GetAddr(admin, kIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usign, psign, ksigns := testOrderSigns(t, n, admin, utils.DomainAddOrder, uIndex, pIndex, nt-100, end, 300, 0, big.NewInt(600))
+	uid := n.GetNonce(admin, kAddr)
+	_, err = n.AddOrder(uid, sign(t, kAddr, "AddOrder", uid, uIndex, pIndex, nt-100, end, 300, 0, 0, big.NewInt(600), usign, psign, ksigns), kAddr, uIndex, pIndex, nt-100, end, 300, 0, 0, big.NewInt(600), usign, psign, ksigns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// failed call is journaled as well
+	uid = n.GetNonce(admin, admin)
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, founder, new(big.Int).Lsh(big.NewInt(1), 200)), taddr, admin, founder, new(big.Int).Lsh(big.NewInt(1), 200))
+	if err == nil {
+		t.Fatal("transfer more than balance should fail")
+	}
+
+	before := stateOf(t, ds)
+	bal := n.BalanceOf(taddr, admin, admin)
+
+	rds := store.NewMemStore()
+	rn, err := NewNode(rds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cnt := 0
+	failed := 0
+	err = rn.Replay(ds, 0, func(seq uint64, c *Call, err error) {
+		cnt++
+		if err != nil {
+			failed++
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uint64(cnt) != n.journal || failed != 1 {
+		t.Fatal("replay count is wrong: ", cnt, failed, n.journal)
+	}
+
+	after := stateOf(t, rds)
+	if len(before) != len(after) {
+		t.Fatal("contract number is wrong: ", len(before), len(after))
+	}
+	for key, val := range before {
+		if !bytes.Equal(val, after[key]) {
+			t.Fatal("replayed state is different")
+		}
+	}
+
+	if rn.BalanceOf(taddr, admin, admin).Cmp(bal) != 0 {
+		t.Fatal("replayed balance is wrong")
+	}
+
+	if rn.GetNonce(admin, admin) != n.GetNonce(admin, admin) {
+		t.Fatal("replayed nonce is wrong")
+	}
+
+	n.sealBlock()
+	rn.sealBlock()
+	if n.head.Hash() != rn.head.Hash() {
+		t.Fatal("replayed blocks are different")
+	}
+
+	// partial replay stops at seq
+	pn, err := NewNode(store.NewMemStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnt = 0
+	err = pn.Replay(ds, 3, func(seq uint64, c *Call, err error) {
+		cnt++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != 3 {
+		t.Fatal("partial replay count is wrong: ", cnt)
+	}
+}
+
+func TestNoRoleMgr(t *testing.T) {
+	ds := store.NewMemStore()
+	n, err := NewNode(ds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	taddr := testErc(t, n, admin)
+
+	// rejected before it is journaled
+	uid := n.GetNonce(admin, admin)
+	journal := n.journal
+	_, err = n.Register(uid, sign(t, admin, "Register", uid, admin, nil), admin, admin, nil)
+	if err != ErrNoRoleMgr {
+		t.Fatal("register without role mgr should fail: ", err)
+	}
+	if n.GetNonce(admin, admin) != uid || n.journal != journal {
+		t.Fatal("rejected call should not be journaled")
+	}
+
+	// such call in old journal fails, and does not stop replay
+	pb, err := utils.CanonicalMarshal(&registerParams{Addr: admin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Call{
+		Method: "Register",
+		Uid:    uid,
+		Sig:    sign(t, admin, "Register", uid, admin, nil),
+		Caller: admin,
+		Params: pb,
+	}
+	n.Lock()
+	_, tx, err := n.execCall(c)
+	n.Unlock()
+	if err != ErrPanic {
+		t.Fatal("call on nil role mgr should be caught: ", err)
+	}
+	r, err := n.GetReceipt(admin, tx)
+	if err != nil || r.ErrCode != types.CodePanic {
+		t.Fatal("receipt of panic is wrong: ", r, err)
+	}
+
+	uid = n.GetNonce(admin, admin)
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, admin, big.NewInt(1)), taddr, admin, admin, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rn, err := NewNode(store.NewMemStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := 0
+	err = rn.Replay(ds, 0, func(seq uint64, c *Call, err error) {
+		if err != nil {
+			failed++
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 1 || rn.GetNonce(admin, admin) != n.GetNonce(admin, admin) {
+		t.Fatal("replay is wrong: ", failed)
+	}
+}
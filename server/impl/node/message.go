@@ -0,0 +1,485 @@
+package node
+
+import (
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// msgMethod turns a message into params of a node method
+type msgMethod struct {
+	name   string
+	decode func(n *Node, m *message.Message) (interface{}, error)
+}
+
+// msgMethods maps Message.Method to node methods
+var msgMethods = map[uint32]msgMethod{
+	message.MethodTransfer:           {"Transfer", decodeTransfer},
+	message.MethodApprove:            {"Approve", decodeApprove},
+	message.MethodRegister:           {"Register", decodeRegister},
+	message.MethodRegisterToken:      {"RegisterToken", decodeRegisterToken},
+	message.MethodRegisterKeeper:     {"RegisterKeeper", decodeRegisterKeeper},
+	message.MethodRegisterProvider:   {"RegisterProvider", decodeRegisterProvider},
+	message.MethodRegisterUser:       {"RegisterUser", decodeRegisterUser},
+	message.MethodCreateGroup:        {"CreateGroup", decodeCreateGroup},
+	message.MethodAddKeeperToGroup:   {"AddKeeperToGroup", decodeAddKeeperToGroup},
+	message.MethodAddProviderToGroup: {"AddProviderToGroup", decodeAddProviderToGroup},
+	message.MethodPledge:             {"Pledge", decodePledge},
+	message.MethodWithdraw:           {"Withdraw", decodeWithdraw},
+	message.MethodRecharge:           {"Recharge", decodeRecharge},
+	message.MethodWithdrawFromFs:     {"WithdrawFromFs", decodeWithdrawFromFs},
+	message.MethodProWithdraw:        {"ProWithdraw", decodeProWithdraw},
+	message.MethodAddOrder:           {"AddOrder", decodeOrder},
+	message.MethodSubOrder:           {"SubOrder", decodeOrder},
+	message.MethodTransferFrom:       {"TransferFrom", decodeTransferFrom},
+	message.MethodMintToken:          {"MintToken", decodeMintToken},
+	message.MethodBurn:               {"Burn", decodeBurn},
+	message.MethodAirDrop:            {"AirDrop", decodeAirDrop},
+}
+
+// PushMessage executes a message signed by role From; it is checked and
+// journaled as other calls, and nonce of From is Message.Nonce
+func (n *Node) PushMessage(sm *message.SignedMessage) (utils.Hash, error) {
+	mm, ok := msgMethods[sm.Method]
+	if !ok {
+		return utils.NilHash, ErrMethod
+	}
+
+	gasPrice := sm.GasPrice
+	if gasPrice == nil {
+		gasPrice = new(big.Int)
+	}
+	if gasPrice.Cmp(n.GetGasPrice(utils.NilAddress)) < 0 {
+		return utils.NilHash, ErrGasPrice
+	}
+
+	gasLimit := sm.GasLimit
+	if gasLimit == 0 {
+		gasLimit = contract.DefaultGasLimit
+	}
+
+	mb, err := sm.Message.Serialize()
+	if err != nil {
+		return utils.NilHash, err
+	}
+
+	// roles are resolved now, so the call does not change on replay
+	n.RLock()
+	caller, params, err := n.decodeMessage(mm, &sm.Message)
+	n.RUnlock()
+	if err != nil {
+		return utils.NilHash, err
+	}
+
+	pb, err := utils.CanonicalMarshal(params)
+	if err != nil {
+		return utils.NilHash, err
+	}
+
+	_, tx, err := n.submitCall(&Call{
+		Method:   mm.name,
+		Uid:      sm.Nonce,
+		Sig:      sm.Signature,
+		Caller:   caller,
+		Params:   pb,
+		GasLimit: gasLimit,
+		GasPrice: gasPrice,
+		Msg:      mb,
+	})
+	return tx, err
+}
+
+// decodeMessage returns address of From and params of m; called with lock held
+func (n *Node) decodeMessage(mm msgMethod, m *message.Message) (utils.Address, interface{}, error) {
+	caller, err := n.roleAddr(m.From)
+	if err != nil {
+		return utils.NilAddress, nil, err
+	}
+
+	params, err := mm.decode(n, m)
+	if err != nil {
+		return utils.NilAddress, nil, err
+	}
+
+	return caller, params, nil
+}
+
+func (n *Node) roleAddr(index uint64) (utils.Address, error) {
+	if n.rm == nil {
+		return utils.NilAddress, ErrRes
+	}
+
+	_, addr, err := n.rm.GetInfo(n.rm.GetContractAddress(), index)
+	return addr, err
+}
+
+func decodeParas(m *message.Message, v interface{}) error {
+	if len(m.Params) == 0 {
+		return nil
+	}
+
+	err := cbor.Unmarshal(m.Params, v)
+	if err != nil {
+		return ErrMessage
+	}
+	return nil
+}
+
+func amountOf(v *big.Int) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v
+}
+
+// firstAuth returns first signature in auth, or nil
+func firstAuth(auth [][]byte) []byte {
+	if len(auth) == 0 {
+		return nil
+	}
+	return auth[0]
+}
+
+// tokenTo resolves token and receiver of Value; receiver is address in
+// Extra, such as a contract, or role To
+func (n *Node) tokenTo(m *message.Message) (utils.Address, utils.Address, error) {
+	p := new(message.ParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return utils.NilAddress, utils.NilAddress, err
+	}
+
+	taddr, err := n.rm.GetTokenAddress(n.rm.GetContractAddress(), p.TokenIndex)
+	if err != nil {
+		return utils.NilAddress, utils.NilAddress, err
+	}
+
+	switch len(p.Extra) {
+	case 0:
+		to, err := n.roleAddr(m.To)
+		if err != nil {
+			return utils.NilAddress, utils.NilAddress, err
+		}
+		return taddr, to, nil
+	case utils.AddressLength:
+		return taddr, utils.BytesToAddress(p.Extra), nil
+	default:
+		return utils.NilAddress, utils.NilAddress, ErrMessage
+	}
+}
+
+func decodeTransfer(n *Node, m *message.Message) (interface{}, error) {
+	taddr, to, err := n.tokenTo(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transferParams{
+		TAddr: taddr,
+		To:    to,
+		Value: amountOf(m.Value),
+	}, nil
+}
+
+func decodeTransferFrom(n *Node, m *message.Message) (interface{}, error) {
+	taddr, to, err := n.tokenTo(m)
+	if err != nil {
+		return nil, err
+	}
+
+	p := new(message.ParasCommon)
+	err = decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := n.roleAddr(p.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	return &transferFromParams{
+		TAddr: taddr,
+		From:  from,
+		To:    to,
+		Value: amountOf(m.Value),
+	}, nil
+}
+
+func decodeMintToken(n *Node, m *message.Message) (interface{}, error) {
+	taddr, to, err := n.tokenTo(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mintTokenParams{
+		TAddr:        taddr,
+		Target:       to,
+		MintedAmount: amountOf(m.Value),
+	}, nil
+}
+
+func decodeBurn(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	taddr, err := n.rm.GetTokenAddress(n.rm.GetContractAddress(), p.TokenIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	return &burnParams{
+		TAddr:      taddr,
+		BurnAmount: amountOf(m.Value),
+	}, nil
+}
+
+// decodeAirDrop reads addresses packed in Extra
+func decodeAirDrop(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.Extra) == 0 || len(p.Extra)%utils.AddressLength != 0 {
+		return nil, ErrMessage
+	}
+
+	taddr, err := n.rm.GetTokenAddress(n.rm.GetContractAddress(), p.TokenIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]utils.Address, 0, len(p.Extra)/utils.AddressLength)
+	for i := 0; i < len(p.Extra); i += utils.AddressLength {
+		addrs = append(addrs, utils.BytesToAddress(p.Extra[i:i+utils.AddressLength]))
+	}
+
+	return &airDropParams{
+		TAddr: taddr,
+		Addrs: addrs,
+		Money: amountOf(m.Value),
+	}, nil
+}
+
+func decodeApprove(n *Node, m *message.Message) (interface{}, error) {
+	taddr, to, err := n.tokenTo(m)
+	if err != nil {
+		return nil, err
+	}
+
+	return &approveParams{
+		TAddr:   taddr,
+		Spender: to,
+		Value:   amountOf(m.Value),
+	}, nil
+}
+
+func decodeRegister(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasBase)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registerParams{
+		Addr: p.Addr,
+		Sign: p.Sig,
+	}, nil
+}
+
+func decodeRegisterToken(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasBase)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registerTokenParams{
+		TAddr: p.Addr,
+	}, nil
+}
+
+func decodeRegisterKeeper(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.SignedParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registerKeeperParams{
+		Index:     p.Index,
+		BlsKey:    p.Extra,
+		Signature: firstAuth(p.Auth),
+	}, nil
+}
+
+func decodeRegisterProvider(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.SignedParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registerProviderParams{
+		Index:     p.Index,
+		Signature: firstAuth(p.Auth),
+	}, nil
+}
+
+func decodeRegisterUser(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registerUserParams{
+		Index:  p.Index,
+		GIndex: p.GIndex,
+		BlsKey: p.Extra,
+	}, nil
+}
+
+func decodeCreateGroup(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Index > 0xffff {
+		return nil, ErrMessage
+	}
+
+	return &createGroupParams{
+		Level: uint16(p.Index),
+	}, nil
+}
+
+func decodeAddKeeperToGroup(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.SignedParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &addKeeperToGroupParams{
+		Index:  p.Index,
+		GIndex: p.GIndex,
+		Asigns: p.Auth,
+	}, nil
+}
+
+func decodeAddProviderToGroup(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &addProviderToGroupParams{
+		Index:  p.Index,
+		GIndex: p.GIndex,
+	}, nil
+}
+
+func decodePledge(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pledgeParams{
+		Index: p.Index,
+		Money: amountOf(p.Amount),
+	}, nil
+}
+
+func decodeWithdraw(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &withdrawParams{
+		Index:      p.Index,
+		TokenIndex: p.TokenIndex,
+		Money:      amountOf(p.Amount),
+	}, nil
+}
+
+func decodeRecharge(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rechargeParams{
+		User:       p.Index,
+		TokenIndex: p.TokenIndex,
+		Money:      amountOf(p.Amount),
+	}, nil
+}
+
+func decodeWithdrawFromFs(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.ParasCommon)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &withdrawFromFsParams{
+		Index:      p.Index,
+		TokenIndex: p.TokenIndex,
+		Amount:     amountOf(p.Amount),
+	}, nil
+}
+
+func decodeProWithdraw(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.SignedParasProWithdraw)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proWithdrawParams{
+		ProIndex:   p.Index,
+		TokenIndex: p.TokenIndex,
+		Pay:        amountOf(p.Amount),
+		Lost:       amountOf(p.Lost),
+		Ksigns:     p.Auth,
+	}, nil
+}
+
+func decodeOrder(n *Node, m *message.Message) (interface{}, error) {
+	p := new(message.SignedParasOrder)
+	err := decodeParas(m, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &orderParams{
+		User:       p.User,
+		ProIndex:   p.Provider,
+		Start:      p.Start,
+		End:        p.End,
+		Size:       p.Size,
+		Nonce:      p.Nonce,
+		TokenIndex: p.TokenIndex,
+		Sprice:     amountOf(p.Price),
+		Usign:      p.Usign,
+		Psign:      p.PSign,
+		Ksigns:     p.Auth,
+	}, nil
+}
@@ -0,0 +1,277 @@
+package node
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func signMessage(t *testing.T, addr utils.Address, m *message.Message) *message.SignedMessage {
+	h, err := m.Digest(utils.DefaultChainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &message.SignedMessage{
+		Message:   *m,
+		Signature: signMsg(t, addr, h[:]),
+	}
+}
+
+func testParas(t *testing.T, v interface{}) []byte {
+	buf, err := cbor.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestPushMessage(t *testing.T) {
+	ds := store.NewMemStore()
+	n, err := NewNode(ds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	uAddr := testNewKey(t)
+	uid := n.GetNonce(admin, admin)
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, uAddr, big.NewInt(1000)), taddr, admin, uAddr, big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid = n.GetNonce(uAddr, uAddr)
+	_, err = n.Register(uid, sign(t, uAddr, "Register", uid, uAddr, nil), uAddr, uAddr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uIndex, err := n.GetIndex(uAddr, uAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// value to role To
+	m := &message.Message{
+		From:   uIndex,
+		To:     0,
+		Nonce:  n.GetNonce(uAddr, uAddr),
+		Value:  big.NewInt(100),
+		Method: message.MethodTransfer,
+	}
+	_, err = n.PushMessage(signMessage(t, uAddr, m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.BalanceOf(taddr, uAddr, uAddr).Cmp(big.NewInt(900)) != 0 {
+		t.Fatal("transfer by message fail")
+	}
+
+	// approve pledge pool, then pledge
+	plAddr := n.GetPledgeAddress(uAddr)
+	m = &message.Message{
+		From:   uIndex,
+		Nonce:  n.GetNonce(uAddr, uAddr),
+		Value:  big.NewInt(500),
+		Method: message.MethodApprove,
+		Params: testParas(t, &message.ParasCommon{Extra: plAddr[:]}),
+	}
+	_, err = n.PushMessage(signMessage(t, uAddr, m))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m = &message.Message{
+		From:   uIndex,
+		Nonce:  n.GetNonce(uAddr, uAddr),
+		Method: message.MethodPledge,
+		Params: testParas(t, &message.ParasCommon{Index: uIndex, Amount: big.NewInt(500)}),
+	}
+	tx, err := n.PushMessage(signMessage(t, uAddr, m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := n.GetReceipt(uAddr, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Err != "" || r.GasUsed == 0 {
+		t.Fatal("receipt of pledge is wrong: ", r)
+	}
+
+	bal, err := n.GetBalance(uAddr, uIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bal[0].Cmp(big.NewInt(500)) != 0 {
+		t.Fatal("pledge by message fail: ", bal[0])
+	}
+
+	// signed by other key
+	nonce := n.GetNonce(uAddr, uAddr)
+	m = &message.Message{
+		From:   uIndex,
+		Nonce:  nonce,
+		Value:  big.NewInt(1),
+		Method: message.MethodTransfer,
+	}
+	_, err = n.PushMessage(signMessage(t, admin, m))
+	if err != ErrRes {
+		t.Fatal("message with wrong sig should be rejected: ", err)
+	}
+
+	m.Method = 0
+	_, err = n.PushMessage(signMessage(t, uAddr, m))
+	if err != ErrMethod {
+		t.Fatal("message with unknown method should be rejected: ", err)
+	}
+	if n.GetNonce(uAddr, uAddr) != nonce {
+		t.Fatal("rejected message should not use nonce")
+	}
+
+	// messages are replayed with their sig
+	n2, err := NewNode(store.NewMemStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n2.Replay(ds, 0, func(seq uint64, c *Call, err error) {
+		if err != nil {
+			t.Fatal("replay fail: ", seq, c.Method, err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bal, err = n2.GetBalance(uAddr, uIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bal[0].Cmp(big.NewInt(500)) != 0 {
+		t.Fatal("replayed pledge is wrong: ", bal[0])
+	}
+}
+
+func TestPushMessageErc(t *testing.T) {
+	ds := store.NewMemStore()
+	n, err := NewNode(ds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	register := func(addr utils.Address) uint64 {
+		uid := n.GetNonce(addr, addr)
+		_, err := n.Register(uid, sign(t, addr, "Register", uid, addr, nil), addr, addr, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index, err := n.GetIndex(addr, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return index
+	}
+	aIndex := register(admin)
+	uAddr := testNewKey(t)
+	uIndex := register(uAddr)
+
+	push := func(addr utils.Address, m *message.Message) {
+		m.Nonce = n.GetNonce(addr, addr)
+		_, err := n.PushMessage(signMessage(t, addr, m))
+		if err != nil {
+			t.Fatal(m.Method, err)
+		}
+	}
+
+	// token admin mints to role To
+	push(admin, &message.Message{
+		From:   aIndex,
+		To:     uIndex,
+		Value:  big.NewInt(100),
+		Method: message.MethodMintToken,
+	})
+	if n.BalanceOf(taddr, uAddr, uAddr).Cmp(big.NewInt(100)) != 0 {
+		t.Fatal("mint by message fail")
+	}
+
+	// user approves admin, which moves it to founder
+	push(uAddr, &message.Message{
+		From:   uIndex,
+		Value:  big.NewInt(30),
+		Method: message.MethodApprove,
+		Params: testParas(t, &message.ParasCommon{Extra: admin[:]}),
+	})
+	push(admin, &message.Message{
+		From:   aIndex,
+		Value:  big.NewInt(30),
+		Method: message.MethodTransferFrom,
+		Params: testParas(t, &message.ParasCommon{Index: uIndex, Extra: founder[:]}),
+	})
+	if n.BalanceOf(taddr, uAddr, uAddr).Cmp(big.NewInt(70)) != 0 || n.BalanceOf(taddr, founder, founder).Cmp(big.NewInt(30)) != 0 {
+		t.Fatal("transfer from by message fail")
+	}
+
+	supply := n.TotalSupply(taddr, admin)
+	push(admin, &message.Message{
+		From:   aIndex,
+		Value:  big.NewInt(20),
+		Method: message.MethodBurn,
+	})
+	if n.TotalSupply(taddr, admin).Cmp(supply.Sub(supply, big.NewInt(20))) != 0 {
+		t.Fatal("burn by message fail")
+	}
+
+	other := testNewKey(t)
+	push(admin, &message.Message{
+		From:   aIndex,
+		Value:  big.NewInt(5),
+		Method: message.MethodAirDrop,
+		Params: testParas(t, &message.ParasCommon{Extra: append(append([]byte(nil), founder[:]...), other[:]...)}),
+	})
+	if n.BalanceOf(taddr, founder, founder).Cmp(big.NewInt(35)) != 0 || n.BalanceOf(taddr, other, other).Cmp(big.NewInt(5)) != 0 {
+		t.Fatal("air drop by message fail")
+	}
+
+	// addresses of air drop are whole
+	m := &message.Message{
+		From:   aIndex,
+		Nonce:  n.GetNonce(admin, admin),
+		Value:  big.NewInt(5),
+		Method: message.MethodAirDrop,
+		Params: testParas(t, &message.ParasCommon{Extra: founder[1:]}),
+	}
+	_, err = n.PushMessage(signMessage(t, admin, m))
+	if err != ErrMessage {
+		t.Fatal("air drop of short address should be rejected: ", err)
+	}
+
+	// replay gets the same balances
+	n2, err := NewNode(store.NewMemStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n2.Replay(ds, 0, func(seq uint64, c *Call, err error) {
+		if err != nil {
+			t.Fatal("replay fail: ", seq, c.Method, err)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range []utils.Address{admin, uAddr, founder, other} {
+		if n2.BalanceOf(taddr, addr, addr).Cmp(n.BalanceOf(taddr, addr, addr)) != 0 {
+			t.Fatal("replayed balance is wrong: ", addr)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package node
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// MaxNonceGap is how far ahead of its sender's nonce a call is kept
+const MaxNonceGap uint64 = 64
+
+// MaxMpoolSize is how many calls of all senders are kept
+const MaxMpoolSize = 4096
+
+// mpool keeps calls of future nonces, until gaps before them are filled;
+// it is guarded by node lock and is not persisted
+type mpool struct {
+	calls map[utils.Address]map[uint64]*Call
+	size  int // number of calls
+	max   int
+}
+
+func newMpool() *mpool {
+	return &mpool{
+		calls: make(map[utils.Address]map[uint64]*Call),
+		max:   MaxMpoolSize,
+	}
+}
+
+// add keeps c, or replaces call of same nonce unless its gas price is lower;
+// when mpool is full, call of lowest gas price is evicted for c
+func (mp *mpool) add(c *Call) error {
+	old, ok := mp.calls[c.Caller][c.Uid]
+	if ok {
+		if old.GasPrice != nil && old.GasPrice.Cmp(priceOf(c)) > 0 {
+			return ErrReplace
+		}
+		mp.calls[c.Caller][c.Uid] = c
+		return nil
+	}
+
+	if mp.size >= mp.max {
+		low := mp.lowest()
+		if low == nil || priceOf(low).Cmp(priceOf(c)) >= 0 {
+			return ErrMpoolFull
+		}
+		log.Warnf("evict pending call %s of %s", low.Hash(), low.Caller)
+		mp.take(low.Caller, low.Uid)
+	}
+
+	cs, ok := mp.calls[c.Caller]
+	if !ok {
+		cs = make(map[uint64]*Call)
+		mp.calls[c.Caller] = cs
+	}
+	cs[c.Uid] = c
+	mp.size++
+
+	return nil
+}
+
+// lowest returns call of lowest gas price, the last nonce of its sender
+// if prices are same
+func (mp *mpool) lowest() *Call {
+	var low *Call
+	for _, cs := range mp.calls {
+		for _, c := range cs {
+			if low == nil {
+				low = c
+				continue
+			}
+			cmp := priceOf(c).Cmp(priceOf(low))
+			if cmp < 0 || (cmp == 0 && c.Caller == low.Caller && c.Uid > low.Uid) {
+				low = c
+			}
+		}
+	}
+	return low
+}
+
+// take removes and returns call of addr at nonce
+func (mp *mpool) take(addr utils.Address, nonce uint64) (*Call, bool) {
+	cs, ok := mp.calls[addr]
+	if !ok {
+		return nil, false
+	}
+
+	c, ok := cs[nonce]
+	if !ok {
+		return nil, false
+	}
+
+	delete(cs, nonce)
+	mp.size--
+	if len(cs) == 0 {
+		delete(mp.calls, addr)
+	}
+
+	return c, true
+}
+
+// drop removes all calls of addr, returns how many are removed
+func (mp *mpool) drop(addr utils.Address) int {
+	cnt := len(mp.calls[addr])
+	mp.size -= cnt
+	delete(mp.calls, addr)
+	return cnt
+}
+
+// pending returns calls of addr in nonce order, or of all senders if addr is nil
+func (mp *mpool) pending(addr utils.Address) []*Call {
+	res := make([]*Call, 0)
+	for caller, cs := range mp.calls {
+		if addr != utils.NilAddress && caller != addr {
+			continue
+		}
+		for _, c := range cs {
+			res = append(res, c)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Caller != res[j].Caller {
+			return res[i].Caller.String() < res[j].Caller.String()
+		}
+		return res[i].Uid < res[j].Uid
+	})
+
+	return res
+}
+
+func priceOf(c *Call) *big.Int {
+	if c.GasPrice == nil {
+		return new(big.Int)
+	}
+	return c.GasPrice
+}
+
+// execPending runs queued calls of addr whose nonce is reached;
+// a call which can not pay is dropped with all later calls of addr,
+// which could not run before its nonce is sent again. called with lock held
+func (n *Node) execPending(addr utils.Address) {
+	for {
+		c, ok := n.mpool.take(addr, n.nonceMap[addr])
+		if !ok {
+			return
+		}
+
+		err := n.canRun(c)
+		if err != nil {
+			cnt := n.mpool.drop(addr)
+			log.Warnf("drop pending call %s of %s and %d after it: %s", c.Hash(), addr, cnt, err)
+			return
+		}
+
+		_, tx, _ := n.execCall(c)
+		if tx == utils.NilHash {
+			return
+		}
+	}
+}
+
+// MpoolPending returns calls waiting for earlier nonces of addr;
+// calls of all senders if addr is nil address
+func (n *Node) MpoolPending(caller, addr utils.Address) ([]*types.PendingCall, error) {
+	n.RLock()
+	defer n.RUnlock()
+
+	cs := n.mpool.pending(addr)
+	res := make([]*types.PendingCall, len(cs))
+	for i, c := range cs {
+		res[i] = &types.PendingCall{
+			TxHash:   c.Hash(),
+			Caller:   c.Caller,
+			Nonce:    c.Uid,
+			Method:   c.Method,
+			GasLimit: c.GasLimit,
+			GasPrice: c.GasPrice,
+		}
+	}
+
+	return res, nil
+}
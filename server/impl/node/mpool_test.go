@@ -0,0 +1,217 @@
+package node
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestMpool(t *testing.T) {
+	n, err := NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	to := testNewKey(t)
+	taddr := testErc(t, n, admin)
+
+	// future nonces wait for the gap
+	uid := n.GetNonce(admin, admin)
+	tx2, err := n.Transfer(uid+2, sign(t, admin, "Transfer", uid+2, taddr, to, big.NewInt(2)), taddr, admin, to, big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = n.Transfer(uid+1, sign(t, admin, "Transfer", uid+1, taddr, to, big.NewInt(5)), taddr, admin, to, big.NewInt(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// replace pending nonce
+	tx1, err := n.Transfer(uid+1, sign(t, admin, "Transfer", uid+1, taddr, to, big.NewInt(7)), taddr, admin, to, big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.GetNonce(admin, admin) != uid {
+		t.Fatal("pending calls should not use nonce")
+	}
+	_, err = n.GetReceipt(admin, tx2)
+	if err != ErrReceipt {
+		t.Fatal("pending call should have no receipt")
+	}
+
+	ps, err := n.MpoolPending(admin, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 2 || ps[0].Nonce != uid+1 || ps[0].TxHash != tx1 || ps[1].Nonce != uid+2 {
+		t.Fatal("pending calls are wrong: ", ps)
+	}
+
+	_, err = n.Transfer(uid+MaxNonceGap, sign(t, admin, "Transfer", uid+MaxNonceGap, taddr, to, big.NewInt(1)), taddr, admin, to, big.NewInt(1))
+	if err != ErrNonceGap {
+		t.Fatal("call far ahead should be rejected: ", err)
+	}
+
+	// call which returns a value is not queued
+	_, err = n.CreateErcToken(uid+3, sign(t, admin, "CreateErcToken", uid+3), admin)
+	if err != ErrNonceFuture {
+		t.Fatal("create of future nonce should be rejected: ", err)
+	}
+
+	// fill the gap, queued calls run in nonce order
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, to, big.NewInt(1)), taddr, admin, to, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.GetNonce(admin, admin) != uid+3 {
+		t.Fatal("queued calls should be executed")
+	}
+	if n.BalanceOf(taddr, admin, to).Cmp(big.NewInt(10)) != 0 {
+		t.Fatal("balance after queued calls is wrong: ", n.BalanceOf(taddr, admin, to))
+	}
+
+	r, err := n.GetReceipt(admin, tx2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Err != "" {
+		t.Fatal("queued call fail: ", r.Err)
+	}
+
+	ps, err = n.MpoolPending(admin, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 0 {
+		t.Fatal("mpool should be empty")
+	}
+}
+
+func TestMpoolFull(t *testing.T) {
+	n, err := NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.mpool.max = 2
+
+	admin := testNewKey(t)
+	other := testNewKey(t)
+	to := testNewKey(t)
+	taddr := testErc(t, n, admin)
+
+	queue := func(addr utils.Address, uid uint64, price int64) (utils.Hash, error) {
+		_, tx, err := n.submitGas("Transfer", uid, sign(t, addr, "Transfer", uid, taddr, to, big.NewInt(1)), addr, &transferParams{TAddr: taddr, To: to, Value: big.NewInt(1)}, contract.DefaultGasLimit, big.NewInt(price))
+		return tx, err
+	}
+
+	uid := n.GetNonce(admin, admin)
+	tx1, err := queue(admin, uid+1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = queue(admin, uid+2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = queue(other, 1, 1)
+	if err != ErrMpoolFull {
+		t.Fatal("full mpool should reject call of same price: ", err)
+	}
+
+	// last nonce of lowest price is evicted
+	tx3, err := queue(other, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := n.MpoolPending(admin, utils.NilAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 2 {
+		t.Fatal("mpool size is wrong: ", len(ps))
+	}
+	for _, p := range ps {
+		if p.TxHash != tx1 && p.TxHash != tx3 {
+			t.Fatal("wrong call is evicted: ", p.Caller, p.Nonce)
+		}
+	}
+
+	// replacement does not grow mpool
+	_, err = queue(admin, uid+1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.mpool.size != 2 {
+		t.Fatal("replacement changes size: ", n.mpool.size)
+	}
+}
+
+func TestMpoolDrop(t *testing.T) {
+	n, err := NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	user := testNewKey(t)
+	to := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	limit := new(big.Int).SetUint64(contract.DefaultGasLimit)
+	uid := n.GetNonce(admin, admin)
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, user, new(big.Int).Mul(limit, big.NewInt(3))), taddr, admin, user, new(big.Int).Mul(limit, big.NewInt(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.SetGasPrice(big.NewInt(1))
+
+	transfer := func(uid uint64, value *big.Int) error {
+		_, err := n.Transfer(uid, sign(t, user, "Transfer", uid, taddr, to, value), taddr, user, to, value)
+		return err
+	}
+
+	uid = n.GetNonce(user, user)
+	for i := uint64(1); i <= 2; i++ {
+		err = transfer(uid+i, big.NewInt(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// left balance can not pay uid+1, uid+2 is dropped with it
+	err = transfer(uid, new(big.Int).Mul(limit, big.NewInt(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps, err := n.MpoolPending(user, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 0 || n.mpool.size != 0 {
+		t.Fatal("calls after unpaid call should be dropped: ", len(ps))
+	}
+	if n.GetNonce(user, user) != uid+1 {
+		t.Fatal("nonce is wrong: ", n.GetNonce(user, user))
+	}
+
+	// sender is not stuck once it can pay
+	uid = n.GetNonce(admin, admin)
+	_, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, user, limit), taddr, admin, user, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = transfer(n.GetNonce(user, user), big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
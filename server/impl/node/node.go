@@ -1,427 +1,577 @@
 package node
 
 import (
-	"encoding/binary"
 	"math/big"
 	"sync"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
 	"github.com/memoio/go-settlement/utils"
-	"github.com/minio/blake2b-simd"
 )
 
 type Node struct {
 	sync.RWMutex
+	ds       store.KVStore
+	state    *contract.State
+	clock    contract.Clock
 	count    uint64
+	journal  uint64 // seq of last journaled call
+	applied  uint64 // seq of last call in persisted state
+	head     *types.Block
+	pending  *types.Block // open block taking calls
+	subs     *subHub
+	mpool    *mpool
+	chainID  uint64 // in digest of each call
 	rm       contract.RoleMgr
 	ercMap   map[utils.Address]contract.ErcToken
 	nonceMap map[utils.Address]uint64
+	gasPrice *big.Int // fee per gas of calls, zero is free
+	kDeposit *big.Int // pledge of keeper in RoleMgr created by node
+	pDeposit *big.Int // pledge of provider in RoleMgr created by node
 }
 
-func NewNode() *Node {
+// NewNode creates a node on ds, state in ds is loaded;
+// clk is the real clock if nil
+func NewNode(ds store.KVStore, clk contract.Clock) (*Node, error) {
+	if clk == nil {
+		clk = contract.NewRealClock()
+	}
+
+	g, err := LoadGenesis(ds)
+	if err == ErrBlock {
+		g, err = InitGenesis(ds, utils.DefaultChainID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	n := &Node{
+		ds:       ds,
+		state:    contract.NewState(clk),
+		clock:    clk,
+		head:     g,
+		subs:     newSubHub(),
+		mpool:    newMpool(),
+		chainID:  g.ChainID,
 		count:    0,
 		ercMap:   make(map[utils.Address]contract.ErcToken),
 		nonceMap: make(map[utils.Address]uint64),
+		gasPrice: new(big.Int),
+		kDeposit: new(big.Int).Mul(new(big.Int).SetUint64(contract.KeeperDeposit), new(big.Int).SetUint64(contract.Token)),
+		pDeposit: new(big.Int).Mul(new(big.Int).SetUint64(contract.ProviderDeposit), new(big.Int).SetUint64(contract.Token)),
 	}
+	n.state.SetChainID(g.ChainID)
 
-	// todo: persist and load
+	err = n.load()
+	if err != nil {
+		return nil, err
+	}
 
-	return n
+	return n, nil
 }
 
-func (n *Node) getAndIncNonce(addr utils.Address) uint64 {
-	non, ok := n.nonceMap[addr]
-	if ok {
-		n.nonceMap[addr] = non + 1
-		return non
-	}
+// SetDeposit sets pledges of keeper and provider in RoleMgr created later;
+// replay must use the same values
+func (n *Node) SetDeposit(kDeposit, pDeposit *big.Int) {
+	n.Lock()
+	defer n.Unlock()
 
-	n.nonceMap[addr] = 1
-	return 0
+	n.kDeposit = new(big.Int).Set(kDeposit)
+	n.pDeposit = new(big.Int).Set(pDeposit)
 }
 
 func (n *Node) GetNonce(caller, addr utils.Address) uint64 {
-	non, ok := n.nonceMap[addr]
-	if ok {
-		return non
-	} else {
-		return 0
-	}
+	n.RLock()
+	defer n.RUnlock()
+
+	return n.nonceMap[addr]
+}
+
+func (n *Node) ChainID(caller utils.Address) uint64 {
+	return n.chainID
 }
 
-func (n *Node) CreateRoleMgr(uid uint64, sig []byte, caller, founder, token utils.Address) (utils.Address, error) {
+// SetFeeToken sets token of gas fee, registered in roleMgr; nil is its
+// primary token. replay must use the same value
+func (n *Node) SetFeeToken(tAddr utils.Address) {
 	n.Lock()
 	defer n.Unlock()
+	n.state.SetFeeToken(tAddr)
+}
 
-	n.count++
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return utils.NilAddress, ErrNonce
-	}
+// SetGasPrice sets fee per gas of later calls, paid in fee token of roleMgr
+func (n *Node) SetGasPrice(price *big.Int) {
+	n.Lock()
+	defer n.Unlock()
+	n.gasPrice = new(big.Int).Set(price)
+}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
+func (n *Node) GetGasPrice(caller utils.Address) *big.Int {
+	n.RLock()
+	defer n.RUnlock()
+	return new(big.Int).Set(n.gasPrice)
+}
 
-	ok := utils.Verify(caller, msg[:], sig)
+// AdvanceTime moves the mock clock of a dev node forward by d seconds;
+// caller is an admin of RoleMgr, and the call is journaled as others
+func (n *Node) AdvanceTime(uid uint64, sig []byte, caller utils.Address, d uint64) (uint64, error) {
+	// clock is not changed after node is made
+	mc, ok := n.clock.(*contract.MockClock)
 	if !ok {
-		return utils.NilAddress, ErrRes
+		return 0, ErrClock
 	}
 
-	kposit := new(big.Int).Mul(new(big.Int).SetUint64(contract.KeeperDeposit), new(big.Int).SetUint64(contract.Token))
-	pposit := new(big.Int).Mul(new(big.Int).SetUint64(contract.ProviderDeposit), new(big.Int).SetUint64(contract.Token))
-
-	rm := contract.NewRoleMgr(caller, founder, token, kposit, pposit)
+	_, _, err := n.submit("AdvanceTime", uid, sig, caller, &advanceTimeParams{
+		D: d,
+	})
+	if err != nil {
+		return 0, err
+	}
 
-	n.rm = rm
+	n.Lock()
+	defer n.Unlock()
 
-	log.Info("create roleMgr for: ", caller.String())
+	// calls after this see the new time in next block
+	n.sealBlock()
+	err = n.persist()
+	if err != nil {
+		return 0, err
+	}
 
-	return rm.GetContractAddress(), nil
+	nt := mc.Advance(d)
+	log.Infof("%s advances time by %d to %d", caller, d, nt)
+	return nt, nil
 }
 
-// 注册地址，获取序号
-func (n *Node) Register(uid uint64, sig []byte, caller, addr utils.Address, sign []byte) error {
-	n.Lock()
-	defer n.Unlock()
-
-	n.count++
+func (n *Node) execAdvanceTime(c *Call) ([]byte, error) {
+	p := new(advanceTimeParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
+	if n.rm == nil {
+		return nil, contract.ErrPermission
 	}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
+	for _, a := range n.rm.GetAdmins(c.Caller).Admins {
+		if a == c.Caller {
+			return nil, nil
+		}
+	}
+	return nil, contract.ErrPermission
+}
 
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) CreateRoleMgr(uid uint64, sig []byte, caller, founder, token utils.Address) (*types.CreateResult, error) {
+	ret, tx, err := n.submit("CreateRoleMgr", uid, sig, caller, &createRoleMgrParams{
+		Founder: founder,
+		Token:   token,
+	})
+	if tx == utils.NilHash {
+		return nil, err
 	}
 
-	return n.rm.Register(caller, addr, sign)
+	return &types.CreateResult{
+		Addr:   utils.BytesToAddress(ret),
+		TxHash: tx,
+	}, err
 }
 
-// by admin, 注册erc20代币地址
-func (n *Node) RegisterToken(uid uint64, sig []byte, caller, taddr utils.Address) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) execCreateRoleMgr(c *Call) ([]byte, error) {
+	p := new(createRoleMgrParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
 
-	n.count++
+	rm := contract.NewRoleMgr(n.state, c.Caller, p.Founder, p.Token, new(big.Int).Set(n.kDeposit), new(big.Int).Set(n.pDeposit))
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
+	n.rm = rm
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
+	log.Info("create roleMgr for: ", c.Caller.String())
 
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
-	}
+	local := rm.GetContractAddress()
+	return local[:], nil
+}
 
-	return n.rm.RegisterToken(caller, taddr)
+// 注册地址，获取序号
+func (n *Node) Register(uid uint64, sig []byte, caller, addr utils.Address, sign []byte) (utils.Hash, error) {
+	_, tx, err := n.submit("Register", uid, sig, caller, &registerParams{
+		Addr: addr,
+		Sign: sign,
+	})
+	return tx, err
 }
 
-// 注册成为keeper角色
-func (n *Node) RegisterKeeper(uid uint64, sig []byte, caller utils.Address, index uint64, blsKey, signature []byte) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) execRegister(c *Call) ([]byte, error) {
+	p := new(registerParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
 
-	n.count++
+	return nil, n.rm.Register(c.Caller, p.Addr, p.Sign)
+}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
+// by admin, 注册erc20代币地址
+func (n *Node) RegisterToken(uid uint64, sig []byte, caller, taddr utils.Address) (utils.Hash, error) {
+	_, tx, err := n.submit("RegisterToken", uid, sig, caller, &registerTokenParams{
+		TAddr: taddr,
+	})
+	return tx, err
+}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) execRegisterToken(c *Call) ([]byte, error) {
+	p := new(registerTokenParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	return n.rm.RegisterKeeper(caller, index, blsKey, signature)
+	return nil, n.rm.RegisterToken(c.Caller, p.TAddr)
 }
 
-// 注册成为prvider角色
-func (n *Node) RegisterProvider(uid uint64, sig []byte, caller utils.Address, index uint64, signature []byte) error {
-	n.Lock()
-	defer n.Unlock()
+// 注册成为keeper角色
+func (n *Node) RegisterKeeper(uid uint64, sig []byte, caller utils.Address, index uint64, blsKey, signature []byte) (utils.Hash, error) {
+	_, tx, err := n.submit("RegisterKeeper", uid, sig, caller, &registerKeeperParams{
+		Index:     index,
+		BlsKey:    blsKey,
+		Signature: signature,
+	})
+	return tx, err
+}
+
+func (n *Node) execRegisterKeeper(c *Call) ([]byte, error) {
+	p := new(registerKeeperParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
 
-	n.count++
+	return nil, n.rm.RegisterKeeper(c.Caller, p.Index, p.BlsKey, p.Signature)
+}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
+// 注册成为prvider角色
+func (n *Node) RegisterProvider(uid uint64, sig []byte, caller utils.Address, index uint64, signature []byte) (utils.Hash, error) {
+	_, tx, err := n.submit("RegisterProvider", uid, sig, caller, &registerProviderParams{
+		Index:     index,
+		Signature: signature,
+	})
+	return tx, err
+}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) execRegisterProvider(c *Call) ([]byte, error) {
+	p := new(registerProviderParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	return n.rm.RegisterProvider(caller, index, signature)
+	return nil, n.rm.RegisterProvider(c.Caller, p.Index, p.Signature)
 }
 
 // 注册成为user角色，从fs contract调用
-func (n *Node) RegisterUser(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, blsKey []byte) error {
-	n.Lock()
-	defer n.Unlock()
-
-	n.count++
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
-
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) RegisterUser(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, blsKey []byte) (utils.Hash, error) {
+	_, tx, err := n.submit("RegisterUser", uid, sig, caller, &registerUserParams{
+		Index:  index,
+		GIndex: gIndex,
+		BlsKey: blsKey,
+	})
+	return tx, err
+}
+
+func (n *Node) execRegisterUser(c *Call) ([]byte, error) {
+	p := new(registerUserParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	return n.rm.RegisterUser(caller, index, gIndex, blsKey)
+	return nil, n.rm.RegisterUser(c.Caller, p.Index, p.GIndex, p.BlsKey)
 }
 
 // 质押,
-func (n *Node) Pledge(uid uint64, sig []byte, caller utils.Address, index uint64, money *big.Int) error {
-	n.Lock()
-	defer n.Unlock()
-
-	n.count++
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
+func (n *Node) Pledge(uid uint64, sig []byte, caller utils.Address, index uint64, money *big.Int) (utils.Hash, error) {
+	_, tx, err := n.submit("Pledge", uid, sig, caller, &pledgeParams{
+		Index: index,
+		Money: money,
+	})
+	return tx, err
+}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) execPledge(c *Call) ([]byte, error) {
+	p := new(pledgeParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	return n.rm.Pledge(caller, index, money)
+	return nil, n.rm.Pledge(c.Caller, p.Index, p.Money)
 }
 
 // 取回token对应的代币, money zero means all
-func (n *Node) Withdraw(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, money *big.Int) error {
-	n.Lock()
-	defer n.Unlock()
-
-	n.count++
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
-
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) Withdraw(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, money *big.Int) (utils.Hash, error) {
+	_, tx, err := n.submit("Withdraw", uid, sig, caller, &withdrawParams{
+		Index:      index,
+		TokenIndex: tokenIndex,
+		Money:      money,
+	})
+	return tx, err
+}
+
+func (n *Node) execWithdraw(c *Call) ([]byte, error) {
+	p := new(withdrawParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	return n.rm.Withdraw(caller, index, tokenIndex, money)
+	return nil, n.rm.Withdraw(c.Caller, p.Index, p.TokenIndex, p.Money)
 }
 
 // 创建组，by admin
-func (n *Node) CreateGroup(uid uint64, sig []byte, caller utils.Address, level uint16) error {
-	n.Lock()
-	defer n.Unlock()
-
-	n.count++
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
+func (n *Node) CreateGroup(uid uint64, sig []byte, caller utils.Address, level uint16) (utils.Hash, error) {
+	_, tx, err := n.submit("CreateGroup", uid, sig, caller, &createGroupParams{
+		Level: level,
+	})
+	return tx, err
+}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) execCreateGroup(c *Call) ([]byte, error) {
+	p := new(createGroupParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	return n.rm.CreateGroup(caller, level)
+	return nil, n.rm.CreateGroup(c.Caller, p.Level)
 }
 
 // 向组中添加keeper，by keeper and admin
-func (n *Node) AddKeeperToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, asign []byte) error {
-	n.Lock()
-	defer n.Unlock()
-
-	n.count++
-
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
-
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) AddKeeperToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64, asigns [][]byte) (utils.Hash, error) {
+	_, tx, err := n.submit("AddKeeperToGroup", uid, sig, caller, &addKeeperToGroupParams{
+		Index:  index,
+		GIndex: gIndex,
+		Asigns: asigns,
+	})
+	return tx, err
+}
+
+func (n *Node) execAddKeeperToGroup(c *Call) ([]byte, error) {
+	p := new(addKeeperToGroupParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	return n.rm.AddKeeperToGroup(caller, index, gIndex, asign)
+	return nil, n.rm.AddKeeperToGroup(c.Caller, p.Index, p.GIndex, p.Asigns)
 }
 
 // 向组中添加provider
-func (n *Node) AddProviderToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64) error {
-	n.Lock()
-	defer n.Unlock()
-
-	n.count++
+func (n *Node) AddProviderToGroup(uid uint64, sig []byte, caller utils.Address, index, gIndex uint64) (utils.Hash, error) {
+	_, tx, err := n.submit("AddProviderToGroup", uid, sig, caller, &addProviderToGroupParams{
+		Index:  index,
+		GIndex: gIndex,
+	})
+	return tx, err
+}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
+func (n *Node) execAddProviderToGroup(c *Call) ([]byte, error) {
+	p := new(addProviderToGroupParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
-	}
+	return nil, n.rm.AddProviderToGroup(c.Caller, p.Index, p.GIndex)
+}
 
-	return n.rm.AddProviderToGroup(caller, index, gIndex)
+// SetReady marks group formed, by keepers of it; ksigns is one sig of each
+// keeper in order, or one aggregated BLS sig by contract.KeeperAggSign
+func (n *Node) SetReady(uid uint64, sig []byte, caller utils.Address, gIndex uint64, ksigns [][]byte) (utils.Hash, error) {
+	_, tx, err := n.submit("SetReady", uid, sig, caller, &setReadyParams{
+		GIndex: gIndex,
+		Ksigns: ksigns,
+	})
+	return tx, err
 }
 
-func (n *Node) Recharge(uid uint64, sig []byte, caller utils.Address, user uint64, tokenIndex uint32, money *big.Int) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) execSetReady(c *Call) ([]byte, error) {
+	p := new(setReadyParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
 
-	n.count++
+	return nil, n.rm.SetReady(c.Caller, p.GIndex, p.Ksigns)
+}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
+func (n *Node) Recharge(uid uint64, sig []byte, caller utils.Address, user uint64, tokenIndex uint32, money *big.Int) (utils.Hash, error) {
+	_, tx, err := n.submit("Recharge", uid, sig, caller, &rechargeParams{
+		User:       user,
+		TokenIndex: tokenIndex,
+		Money:      money,
+	})
+	return tx, err
+}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) execRecharge(c *Call) ([]byte, error) {
+	p := new(rechargeParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	return n.rm.Recharge(caller, user, tokenIndex, money)
+	return nil, n.rm.Recharge(c.Caller, p.User, p.TokenIndex, p.Money)
 }
 
-func (n *Node) ProWithdraw(uid uint64, sig []byte, caller utils.Address, proIndex uint64, tokenIndex uint32, pay, lost *big.Int, ksigns [][]byte) error {
-	n.Lock()
-	defer n.Unlock()
-
-	n.count++
+func (n *Node) ProWithdraw(uid uint64, sig []byte, caller utils.Address, proIndex uint64, tokenIndex uint32, pay, lost *big.Int, ksigns [][]byte) (utils.Hash, error) {
+	_, tx, err := n.submit("ProWithdraw", uid, sig, caller, &proWithdrawParams{
+		ProIndex:   proIndex,
+		TokenIndex: tokenIndex,
+		Pay:        pay,
+		Lost:       lost,
+		Ksigns:     ksigns,
+	})
+	return tx, err
+}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
+func (n *Node) execProWithdraw(c *Call) ([]byte, error) {
+	p := new(proWithdrawParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
-	}
+	return nil, n.rm.ProWithdraw(c.Caller, p.ProIndex, p.TokenIndex, p.Pay, p.Lost, p.Ksigns)
+}
 
-	return n.rm.ProWithdraw(caller, proIndex, tokenIndex, pay, lost, ksigns)
+func (n *Node) WithdrawFromFs(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, amount *big.Int) (utils.Hash, error) {
+	_, tx, err := n.submit("WithdrawFromFs", uid, sig, caller, &withdrawFromFsParams{
+		Index:      index,
+		TokenIndex: tokenIndex,
+		Amount:     amount,
+	})
+	return tx, err
 }
 
-func (n *Node) WithdrawFromFs(uid uint64, sig []byte, caller utils.Address, index uint64, tokenIndex uint32, amount *big.Int) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) execWithdrawFromFs(c *Call) ([]byte, error) {
+	p := new(withdrawFromFsParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
 
-	n.count++
+	return nil, n.rm.WithdrawFromFs(c.Caller, p.Index, p.TokenIndex, p.Amount)
+}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
+func (n *Node) AddOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) (utils.Hash, error) {
+	_, tx, err := n.submit("AddOrder", uid, sig, caller, &orderParams{
+		User:       user,
+		ProIndex:   proIndex,
+		Start:      start,
+		End:        end,
+		Size:       size,
+		Nonce:      nonce,
+		TokenIndex: tokenIndex,
+		Sprice:     sprice,
+		Usign:      usign,
+		Psign:      psign,
+		Ksigns:     ksigns,
+	})
+	return tx, err
+}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) execAddOrder(c *Call) ([]byte, error) {
+	p := new(orderParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	return n.rm.WithdrawFromFs(caller, index, tokenIndex, amount)
+	return nil, n.rm.AddOrder(c.Caller, p.User, p.ProIndex, p.Start, p.End, p.Size, p.Nonce, p.TokenIndex, p.Sprice, p.Usign, p.Psign, p.Ksigns)
 }
 
-func (n *Node) AddOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error {
-	n.Lock()
-	defer n.Unlock()
-
-	n.count++
+func (n *Node) SubOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) (utils.Hash, error) {
+	_, tx, err := n.submit("SubOrder", uid, sig, caller, &orderParams{
+		User:       user,
+		ProIndex:   proIndex,
+		Start:      start,
+		End:        end,
+		Size:       size,
+		Nonce:      nonce,
+		TokenIndex: tokenIndex,
+		Sprice:     sprice,
+		Usign:      usign,
+		Psign:      psign,
+		Ksigns:     ksigns,
+	})
+	return tx, err
+}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
+func (n *Node) execSubOrder(c *Call) ([]byte, error) {
+	p := new(orderParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
-	}
+	return nil, n.rm.SubOrder(c.Caller, p.User, p.ProIndex, p.Start, p.End, p.Size, p.Nonce, p.TokenIndex, p.Sprice, p.Usign, p.Psign, p.Ksigns)
+}
 
-	return n.rm.AddOrder(caller, user, proIndex, start, end, size, nonce, tokenIndex, sprice, usign, psign, ksigns)
+// AddRepair moves an order from proIndex to newPro, signed by newPro and keepers
+func (n *Node) AddRepair(uid uint64, sig []byte, caller utils.Address, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) (utils.Hash, error) {
+	_, tx, err := n.submit("AddRepair", uid, sig, caller, &repairParams{
+		ProIndex:   proIndex,
+		NewPro:     newPro,
+		Start:      start,
+		End:        end,
+		Size:       size,
+		Nonce:      nonce,
+		TokenIndex: tokenIndex,
+		Sprice:     sprice,
+		Psign:      psign,
+		Ksigns:     ksigns,
+	})
+	return tx, err
 }
 
-func (n *Node) SubOrder(uid uint64, sig []byte, caller utils.Address, user, proIndex, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, usign, psign []byte, ksigns [][]byte) error {
-	n.Lock()
-	defer n.Unlock()
+func (n *Node) execAddRepair(c *Call) ([]byte, error) {
+	p := new(repairParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
 
-	n.count++
+	return nil, n.rm.AddRepair(c.Caller, p.ProIndex, p.NewPro, p.Start, p.End, p.Size, p.Nonce, p.TokenIndex, p.Sprice, p.Psign, p.Ksigns)
+}
 
-	cn := n.getAndIncNonce(caller)
-	if cn != uid {
-		return ErrNonce
-	}
+func (n *Node) SubRepair(uid uint64, sig []byte, caller utils.Address, proIndex, newPro, start, end, size, nonce uint64, tokenIndex uint32, sprice *big.Int, psign []byte, ksigns [][]byte) (utils.Hash, error) {
+	_, tx, err := n.submit("SubRepair", uid, sig, caller, &repairParams{
+		ProIndex:   proIndex,
+		NewPro:     newPro,
+		Start:      start,
+		End:        end,
+		Size:       size,
+		Nonce:      nonce,
+		TokenIndex: tokenIndex,
+		Sprice:     sprice,
+		Psign:      psign,
+		Ksigns:     ksigns,
+	})
+	return tx, err
+}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	ok := utils.Verify(caller, msg[:], sig)
-	if !ok {
-		return ErrRes
+func (n *Node) execSubRepair(c *Call) ([]byte, error) {
+	p := new(repairParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
 	}
 
-	return n.rm.SubOrder(caller, user, proIndex, start, end, size, nonce, tokenIndex, sprice, usign, psign, ksigns)
+	return nil, n.rm.SubRepair(c.Caller, p.ProIndex, p.NewPro, p.Start, p.End, p.Size, p.Nonce, p.TokenIndex, p.Sprice, p.Psign, p.Ksigns)
 }
 
 func (n *Node) GetIndex(caller, addr utils.Address) (uint64, error) {
@@ -474,7 +624,7 @@ func (n *Node) GetBalance(caller utils.Address, index uint64) ([]*big.Int, error
 
 	paddr := n.rm.GetPledgeAddress(caller)
 
-	pp, err := contract.GetPledgePool(paddr)
+	pp, err := n.state.GetPledgePool(paddr)
 	if err != nil {
 		return nil, err
 	}
@@ -496,7 +646,7 @@ func (n *Node) GetSettleInfo(caller utils.Address, index uint64, tIndex uint32)
 		return nil, err
 	}
 
-	fm, err := contract.GetFsMgr(gi.FsAddr)
+	fm, err := n.state.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -523,7 +673,7 @@ func (n *Node) GetBalanceInFs(caller utils.Address, index uint64, tIndex uint32)
 		return nil, err
 	}
 
-	fm, err := contract.GetFsMgr(gi.FsAddr)
+	fm, err := n.state.GetFsMgr(gi.FsAddr)
 	if err != nil {
 		return nil, err
 	}
@@ -567,7 +717,7 @@ func (n *Node) GetPledgeBalance(caller utils.Address) []*big.Int {
 
 	paddr := n.rm.GetPledgeAddress(caller)
 
-	pp, err := contract.GetPledgePool(paddr)
+	pp, err := n.state.GetPledgePool(paddr)
 	if err != nil {
 		return nil
 	}
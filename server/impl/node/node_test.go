@@ -1,29 +1,53 @@
 package node
 
 import (
-	"crypto/rand"
-	"encoding/binary"
+	"io/ioutil"
 	"math/big"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/memoio/go-settlement/keystore"
 	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/message"
+	"github.com/memoio/go-settlement/server/store"
 	"github.com/memoio/go-settlement/utils"
-	"github.com/minio/blake2b-simd"
 )
 
-var addrMap = make(map[utils.Address]*utils.Key)
+const testPassword = "test"
 
-func sign(t *testing.T, addr utils.Address, uid uint64) []byte {
-	key, ok := addrMap[addr]
-	if !ok {
-		t.Fatal("no secretkey")
+// testKS keeps keys of tests
+var testKS *keystore.KeyStore
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		panic(err)
+	}
+
+	testKS, err = keystore.NewKeyStore(dir, keystore.LightScryptN)
+	if err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// testKey decrypts key of addr in testKS
+func testKey(t *testing.T, addr utils.Address) *utils.Key {
+	key, err := testKS.Get(addr, testPassword)
+	if err != nil {
+		t.Fatal("no secretkey: ", err)
 	}
+	return key
+}
 
-	buf := make([]byte, 8)
-	binary.LittleEndian.PutUint64(buf, uid)
-	msg := blake2b.Sum256(buf)
-	sig, err := utils.Sign(key.SecretKey, msg[:])
+// sign signs call of method at uid; params are args of node method after caller
+func sign(t *testing.T, addr utils.Address, method string, uid uint64, params ...interface{}) []byte {
+	key := testKey(t, addr)
+	sig, err := utils.SignCall(key.SecretKey, method, utils.DefaultChainID, uid, params...)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -31,11 +55,19 @@ func sign(t *testing.T, addr utils.Address, uid uint64) []byte {
 	return sig
 }
 
-func signMsg(t *testing.T, addr utils.Address, msg []byte) []byte {
-	key, ok := addrMap[addr]
-	if !ok {
-		t.Fatal("no secretkey")
+// testAdvanceTime advances clock of n by admin of RoleMgr
+func testAdvanceTime(t *testing.T, n *Node, admin utils.Address, d uint64) uint64 {
+	uid := n.GetNonce(admin, admin)
+	nt, err := n.AdvanceTime(uid, sign(t, admin, "AdvanceTime", uid, d), admin, d)
+	if err != nil {
+		t.Fatal(err)
 	}
+
+	return nt
+}
+
+func signMsg(t *testing.T, addr utils.Address, msg []byte) []byte {
+	key := testKey(t, addr)
 	sig, err := utils.Sign(key.SecretKey, msg)
 	if err != nil {
 		t.Fatal(err)
@@ -44,45 +76,99 @@ func signMsg(t *testing.T, addr utils.Address, msg []byte) []byte {
 	return sig
 }
 
+// testKeeperSigns signs h by all keepers in group of role index
+func testKeeperSigns(t *testing.T, n *Node, admin utils.Address, index uint64, h utils.Hash) [][]byte {
+	bi, err := n.GetInfo(admin, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gi, err := n.GetGroupInfo(admin, bi.GIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ksigns := make([][]byte, len(gi.Keepers))
+	for i, kindex := range gi.Keepers {
+		kAddr, err := n.GetAddr(admin, kindex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ksigns[i] = signMsg(t, kAddr, h[:])
+	}
+
+	return ksigns
+}
+
+// testOrderSigns returns usign, psign and ksigns of an order
+func testOrderSigns(t *testing.T, n *Node, admin utils.Address, tag string, userIndex, proIndex, start, end, size, nonce uint64, sprice *big.Int) ([]byte, []byte, [][]byte) {
+	h, err := contract.OrderDigest(tag, n.ChainID(admin), n.rm.GetContractAddress(), &message.ParasOrder{
+		User:     userIndex,
+		Provider: proIndex,
+		Start:    start,
+		End:      end,
+		Size:     size,
+		Nonce:    nonce,
+		Price:    sprice,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uAddr, err := n.GetAddr(admin, userIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pAddr, err := n.GetAddr(admin, proIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signMsg(t, uAddr, h[:]), signMsg(t, pAddr, h[:]), testKeeperSigns(t, n, admin, proIndex, h)
+}
+
 func testNewKey(t *testing.T) utils.Address {
-	adminkey, err := utils.GenerateKey(rand.Reader)
+	addr, err := testKS.NewKey(testPassword)
 	if err != nil {
 		t.Fatal(err)
 	}
-	adminAddr := utils.ToAddress(adminkey.PubKey)
-	addrMap[adminAddr] = adminkey
-	return adminAddr
+	return addr
 }
 
 func testNewNode(t *testing.T) *Node {
-	return NewNode()
+	n, err := NewNode(store.NewMemStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
 }
 
 func testErc(t *testing.T, n *Node, admin utils.Address) utils.Address {
 	uid := n.GetNonce(admin, admin)
-	sig := sign(t, admin, uid)
+	sig := sign(t, admin, "CreateErcToken", uid)
 
-	taddr, err := n.CreateErcToken(uid, sig, admin)
+	res, err := n.CreateErcToken(uid, sig, admin)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	return taddr
+	return res.Addr
 }
 
 func testCreateRoleMgr(t *testing.T, n *Node, admin, taddr, founder utils.Address) utils.Address {
 	uid := n.GetNonce(admin, admin)
-	sig := sign(t, admin, uid)
+	sig := sign(t, admin, "CreateRoleMgr", uid, founder, taddr)
 
-	raddr, err := n.CreateRoleMgr(uid, sig, admin, founder, taddr)
+	res, err := n.CreateRoleMgr(uid, sig, admin, founder, taddr)
 	if err != nil {
 		t.Fatal(err)
 	}
+	raddr := res.Addr
 
 	uid = n.GetNonce(admin, admin)
-	sig = sign(t, admin, uid)
+	sig = sign(t, admin, "Transfer", uid, taddr, raddr, big.NewInt(1000000000000000))
 
-	err = n.Transfer(uid, sig, taddr, admin, raddr, big.NewInt(1000000000000000))
+	_, err = n.Transfer(uid, sig, taddr, admin, raddr, big.NewInt(1000000000000000))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -98,25 +184,25 @@ func testPledge(t *testing.T, n *Node, admin utils.Address, amount *big.Int) uin
 	}
 
 	uid := n.GetNonce(admin, admin)
-	sig := sign(t, admin, uid)
+	sig := sign(t, admin, "Transfer", uid, ts[0], uAddr, amount)
 
-	err := n.Transfer(uid, sig, ts[0], admin, uAddr, amount)
+	_, err := n.Transfer(uid, sig, ts[0], admin, uAddr, amount)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	uid = n.GetNonce(admin, uAddr)
-	sig = sign(t, uAddr, uid)
+	sig = sign(t, uAddr, "Register", uid, uAddr, nil)
 
-	err = n.Register(uid, sig, uAddr, uAddr, nil)
+	_, err = n.Register(uid, sig, uAddr, uAddr, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	plAddr := n.GetPledgeAddress(uAddr)
 	uid = n.GetNonce(admin, uAddr)
-	sig = sign(t, uAddr, uid)
-	err = n.Approve(uid, sig, ts[0], uAddr, plAddr, amount)
+	sig = sign(t, uAddr, "Approve", uid, ts[0], plAddr, amount)
+	_, err = n.Approve(uid, sig, ts[0], uAddr, plAddr, amount)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -126,8 +212,8 @@ func testPledge(t *testing.T, n *Node, admin utils.Address, amount *big.Int) uin
 		t.Fatal(err)
 	}
 	uid = n.GetNonce(admin, uAddr)
-	sig = sign(t, uAddr, uid)
-	err = n.Pledge(uid, sig, uAddr, uindex, amount)
+	sig = sign(t, uAddr, "Pledge", uid, uindex, amount)
+	_, err = n.Pledge(uid, sig, uAddr, uindex, amount)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -158,9 +244,9 @@ func testWithdrawPledge(t *testing.T, n *Node, admin utils.Address, index uint64
 	if send {
 		plAddr := n.GetPledgeAddress(uAddr)
 		uid := n.GetNonce(admin, admin)
-		sig := sign(t, admin, uid)
 		val := new(big.Int).Mul(new(big.Int).SetUint64(1), new(big.Int).SetUint64(contract.Token))
-		err := n.Transfer(uid, sig, ts[tIndex], admin, plAddr, val)
+		sig := sign(t, admin, "Transfer", uid, ts[tIndex], plAddr, val)
+		_, err := n.Transfer(uid, sig, ts[tIndex], admin, plAddr, val)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -175,8 +261,8 @@ func testWithdrawPledge(t *testing.T, n *Node, admin utils.Address, index uint64
 	bres := n.GetPledgeBalance(uAddr)
 
 	uid := n.GetNonce(admin, uAddr)
-	sig := sign(t, uAddr, uid)
-	err = n.Withdraw(uid, sig, uAddr, index, tIndex, amount)
+	sig := sign(t, uAddr, "Withdraw", uid, index, tIndex, amount)
+	_, err = n.Withdraw(uid, sig, uAddr, index, tIndex, amount)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -222,9 +308,9 @@ func testCreateKeeper(t *testing.T, n *Node, admin utils.Address) uint64 {
 	}
 
 	uid := n.GetNonce(admin, uAddr)
-	sig := sign(t, uAddr, uid)
+	sig := sign(t, uAddr, "RegisterKeeper", uid, index, nil, nil)
 
-	err = n.RegisterKeeper(uid, sig, uAddr, index, nil, nil)
+	_, err = n.RegisterKeeper(uid, sig, uAddr, index, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -251,9 +337,9 @@ func testCreateProvider(t *testing.T, n *Node, admin utils.Address) uint64 {
 	}
 
 	uid := n.GetNonce(admin, uAddr)
-	sig := sign(t, uAddr, uid)
+	sig := sign(t, uAddr, "RegisterProvider", uid, index, nil)
 
-	err = n.RegisterProvider(uid, sig, uAddr, index, nil)
+	_, err = n.RegisterProvider(uid, sig, uAddr, index, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -269,8 +355,8 @@ func testCreateGroup(t *testing.T, n *Node, admin utils.Address) uint64 {
 	gs := n.GetAllGroups(admin)
 
 	uid := n.GetNonce(admin, admin)
-	sig := sign(t, admin, uid)
-	err := n.CreateGroup(uid, sig, admin, 7)
+	sig := sign(t, admin, "CreateGroup", uid, 7)
+	_, err := n.CreateGroup(uid, sig, admin, 7)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -293,8 +379,8 @@ func testAddKeeper(t *testing.T, n *Node, admin utils.Address, gIndex uint64) ui
 	kindex := testCreateKeeper(t, n, admin)
 
 	uid := n.GetNonce(admin, admin)
-	sig := sign(t, admin, uid)
-	err = n.AddKeeperToGroup(uid, sig, admin, kindex, gIndex, nil)
+	sig := sign(t, admin, "AddKeeperToGroup", uid, kindex, gIndex, nil)
+	_, err = n.AddKeeperToGroup(uid, sig, admin, kindex, gIndex, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -327,9 +413,9 @@ func testAddProvider(t *testing.T, n *Node, admin utils.Address, gIndex uint64)
 	}
 
 	uid := n.GetNonce(admin, pAddr)
-	sig := sign(t, pAddr, uid)
+	sig := sign(t, pAddr, "AddProviderToGroup", uid, pindex, gIndex)
 
-	err = n.AddProviderToGroup(uid, sig, pAddr, pindex, gIndex)
+	_, err = n.AddProviderToGroup(uid, sig, pAddr, pindex, gIndex)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -355,8 +441,8 @@ func testCreateUser(t *testing.T, n *Node, admin utils.Address, gIndex uint64) u
 	}
 
 	uid := n.GetNonce(admin, uAddr)
-	sig := sign(t, uAddr, uid)
-	err = n.RegisterUser(uid, sig, uAddr, uindex, gIndex, nil)
+	sig := sign(t, uAddr, "RegisterUser", uid, uindex, gIndex, nil)
+	_, err = n.RegisterUser(uid, sig, uAddr, uindex, gIndex, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -375,8 +461,8 @@ func testCreateUser(t *testing.T, n *Node, admin utils.Address, gIndex uint64) u
 	amount := big.NewInt(4000000000000)
 
 	uid = n.GetNonce(admin, admin)
-	sig = sign(t, admin, uid)
-	err = n.Transfer(uid, sig, ts[0], admin, uAddr, amount)
+	sig = sign(t, admin, "Transfer", uid, ts[0], uAddr, amount)
+	_, err = n.Transfer(uid, sig, ts[0], admin, uAddr, amount)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -387,12 +473,12 @@ func testCreateUser(t *testing.T, n *Node, admin utils.Address, gIndex uint64) u
 	}
 
 	uid = n.GetNonce(admin, uAddr)
-	sig = sign(t, uAddr, uid)
+	sig = sign(t, uAddr, "Approve", uid, ts[0], gi.FsAddr, amount)
 	n.Approve(uid, sig, ts[0], uAddr, gi.FsAddr, amount)
 
 	uid = n.GetNonce(admin, uAddr)
-	sig = sign(t, uAddr, uid)
-	err = n.Recharge(uid, sig, uAddr, uindex, 0, amount)
+	sig = sign(t, uAddr, "Recharge", uid, uindex, 0, amount)
+	_, err = n.Recharge(uid, sig, uAddr, uindex, 0, amount)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -423,10 +509,12 @@ func testAddOrder(t *testing.T, n *Node, admin utils.Address, kIndex, userIndex,
 	t.Log(kIndex, "before:", bk[0], bk[1])
 	t.Log(proIndex, "before:", bp[0], bp[1])
 
+	usign, psign, ksigns := testOrderSigns(t, n, admin, utils.DomainAddOrder, userIndex, proIndex, start, end, size, nonce, big.NewInt(600000))
+
 	uid := n.GetNonce(admin, kAddr)
-	sig := sign(t, kAddr, uid)
+	sig := sign(t, kAddr, "AddOrder", uid, userIndex, proIndex, start, end, size, nonce, 0, big.NewInt(600000), usign, psign, ksigns)
 
-	err = n.AddOrder(uid, sig, kAddr, userIndex, proIndex, start, end, size, nonce, 0, big.NewInt(600000), nil, nil, nil)
+	_, err = n.AddOrder(uid, sig, kAddr, userIndex, proIndex, start, end, size, nonce, 0, big.NewInt(600000), usign, psign, ksigns)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -465,10 +553,12 @@ func testSubOrder(t *testing.T, n *Node, admin utils.Address, kIndex, userIndex,
 	t.Log(kIndex, "before:", bk[0], bk[1])
 	t.Log(proIndex, "before:", bp[0], bp[1])
 
+	usign, psign, ksigns := testOrderSigns(t, n, admin, utils.DomainSubOrder, userIndex, proIndex, start, end, size, nonce, big.NewInt(600000))
+
 	uid := n.GetNonce(admin, kAddr)
-	sig := sign(t, kAddr, uid)
+	sig := sign(t, kAddr, "SubOrder", uid, userIndex, proIndex, start, end, size, nonce, 0, big.NewInt(600000), usign, psign, ksigns)
 
-	err = n.SubOrder(uid, sig, kAddr, userIndex, proIndex, start, end, size, nonce, 0, big.NewInt(600000), nil, nil, nil)
+	_, err = n.SubOrder(uid, sig, kAddr, userIndex, proIndex, start, end, size, nonce, 0, big.NewInt(600000), usign, psign, ksigns)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -499,9 +589,19 @@ func testProWithdraw(t *testing.T, n *Node, admin utils.Address, proIndex uint64
 	se, _ := n.GetSettleInfo(pAddr, proIndex, 0)
 	paid := new(big.Int).Set(se.HasPaid)
 
+	h, err := contract.ProWithdrawDigest(n.ChainID(admin), n.rm.GetContractAddress(), &message.ParasProWithdraw{
+		Index:  proIndex,
+		Amount: amount,
+		Lost:   lost,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ksigns := testKeeperSigns(t, n, admin, proIndex, h)
+
 	uid := n.GetNonce(admin, pAddr)
-	sig := sign(t, pAddr, uid)
-	err = n.ProWithdraw(uid, sig, pAddr, proIndex, 0, amount, lost, nil)
+	sig := sign(t, pAddr, "ProWithdraw", uid, proIndex, 0, amount, lost, ksigns)
+	_, err = n.ProWithdraw(uid, sig, pAddr, proIndex, 0, amount, lost, ksigns)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -532,9 +632,9 @@ func testFsWithdraw(t *testing.T, n *Node, admin utils.Address, index uint64, am
 	t.Log(index, "before:", bp[0], bp[1])
 
 	uid := n.GetNonce(admin, pAddr)
-	sig := sign(t, pAddr, uid)
+	sig := sign(t, pAddr, "WithdrawFromFs", uid, index, 0, amount)
 
-	err = n.WithdrawFromFs(uid, sig, pAddr, index, 0, amount)
+	_, err = n.WithdrawFromFs(uid, sig, pAddr, index, 0, amount)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -610,3 +710,94 @@ func TestNode(t *testing.T) {
 
 	t.Fatal("end")
 }
+
+func TestIsolation(t *testing.T) {
+	n1, err := NewNode(store.NewMemStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n2, err := NewNode(store.NewMemStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	taddr := testErc(t, n1, admin)
+
+	if n1.BalanceOf(taddr, admin, admin).Sign() == 0 {
+		t.Fatal("token is not created")
+	}
+
+	if n2.BalanceOf(taddr, admin, admin).Sign() != 0 {
+		t.Fatal("token leaks into another node")
+	}
+
+	_, err = n2.state.GetErcToken(taddr)
+	if err == nil {
+		t.Fatal("contract leaks into another node")
+	}
+}
+
+func TestAdvanceTime(t *testing.T) {
+	n := testNewNode(t)
+	admin := testNewKey(t)
+	uid := n.GetNonce(admin, admin)
+	_, err := n.AdvanceTime(uid, sign(t, admin, "AdvanceTime", uid, 10), admin, 10)
+	if err != ErrClock {
+		t.Fatal("real clock should not be advanced")
+	}
+	if n.GetNonce(admin, admin) != uid {
+		t.Fatal("nonce is used by refused advance")
+	}
+
+	// order end is aligned to day
+	end := uint64(1600041600)
+	n, err = NewNode(store.NewMemStore(), contract.NewMockClock(end-100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	gindex := testCreateGroup(t, n, admin)
+	for i := 0; i < 7; i++ {
+		testAddKeeper(t, n, admin, gindex)
+	}
+	kIndex := testAddKeeper(t, n, admin, gindex)
+	pIndex := testAddProvider(t, n, admin, gindex)
+	uIndex := testCreateUser(t, n, admin, gindex)
+
+	testAddOrder(t, n, admin, kIndex, uIndex, pIndex, end-200, end, 300, 0)
+
+	kAddr, err := n.GetAddr(admin, kIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid = n.GetNonce(admin, kAddr)
+	_, err = n.SubOrder(uid, sign(t, kAddr, "SubOrder", uid, uIndex, pIndex, end-200, end, 300, 0, 0, big.NewInt(600000), nil, nil, nil), kAddr, uIndex, pIndex, end-200, end, 300, 0, 0, big.NewInt(600000), nil, nil, nil)
+	if err == nil {
+		t.Fatal("sub order before it expires should fail")
+	}
+
+	// only admin of RoleMgr advances time
+	uid = n.GetNonce(kAddr, kAddr)
+	_, err = n.AdvanceTime(uid, sign(t, kAddr, "AdvanceTime", uid, 100), kAddr, 100)
+	if err != contract.ErrPermission {
+		t.Fatal("advance by non admin should fail: ", err)
+	}
+
+	uid = n.GetNonce(admin, admin) + 1
+	_, err = n.AdvanceTime(uid, sign(t, admin, "AdvanceTime", uid, 100), admin, 100)
+	if err != ErrNonceFuture {
+		t.Fatal("advance of future nonce should fail: ", err)
+	}
+
+	ntime := testAdvanceTime(t, n, admin, 100)
+	if ntime != end {
+		t.Fatal("time is not advanced")
+	}
+
+	testSubOrder(t, n, admin, kIndex, uIndex, pIndex, end-200, end, 300, 0)
+}
@@ -0,0 +1,67 @@
+package node
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// TransferOwnership names newOwner as pending owner of contract caddr,
+// it takes over after AcceptOwnership; nil newOwner cancels
+func (n *Node) TransferOwnership(uid uint64, sig []byte, caller, caddr, newOwner utils.Address) (utils.Hash, error) {
+	_, tx, err := n.submit("TransferOwnership", uid, sig, caller, &transferOwnershipParams{
+		Contract: caddr,
+		NewOwner: newOwner,
+	})
+	return tx, err
+}
+
+func (n *Node) execTransferOwnership(c *Call) ([]byte, error) {
+	p := new(transferOwnershipParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := n.state.GetOwnable(p.Contract)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, o.TransferOwnership(c.Caller, p.NewOwner)
+}
+
+// AcceptOwnership is called by pending owner of contract caddr
+func (n *Node) AcceptOwnership(uid uint64, sig []byte, caller, caddr utils.Address) (utils.Hash, error) {
+	_, tx, err := n.submit("AcceptOwnership", uid, sig, caller, &acceptOwnershipParams{
+		Contract: caddr,
+	})
+	return tx, err
+}
+
+func (n *Node) execAcceptOwnership(c *Call) ([]byte, error) {
+	p := new(acceptOwnershipParams)
+	err := cbor.Unmarshal(c.Params, p)
+	if err != nil {
+		return nil, err
+	}
+
+	o, err := n.state.GetOwnable(p.Contract)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, o.AcceptOwnership(c.Caller)
+}
+
+func (n *Node) GetOwnerInfo(caller, caddr utils.Address) (*contract.OwnerInfo, error) {
+	n.RLock()
+	defer n.RUnlock()
+
+	o, err := n.state.GetOwnable(caddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.GetOwnerInfo(caller), nil
+}
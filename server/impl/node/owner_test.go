@@ -0,0 +1,170 @@
+package node
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func testTransferOwnership(t *testing.T, n *Node, owner, caddr, newOwner utils.Address) error {
+	uid := n.GetNonce(owner, owner)
+	_, err := n.TransferOwnership(uid, sign(t, owner, "TransferOwnership", uid, caddr, newOwner), owner, caddr, newOwner)
+	return err
+}
+
+func testAcceptOwnership(t *testing.T, n *Node, caller, caddr utils.Address) error {
+	uid := n.GetNonce(caller, caller)
+	_, err := n.AcceptOwnership(uid, sign(t, caller, "AcceptOwnership", uid, caddr), caller, caddr)
+	return err
+}
+
+func TestOwnership(t *testing.T) {
+	n := testNewNode(t)
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	raddr := testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	newAdmin := testNewKey(t)
+	other := testNewKey(t)
+
+	// erc token
+	err := testTransferOwnership(t, n, other, taddr, other)
+	if err != contract.ErrPermission {
+		t.Fatal("transfer by non owner should fail: ", err)
+	}
+
+	err = testTransferOwnership(t, n, admin, taddr, newAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oi, err := n.GetOwnerInfo(admin, taddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oi.Owner != admin || oi.Pending != newAdmin {
+		t.Fatal("owner info is wrong: ", oi)
+	}
+
+	err = testAcceptOwnership(t, n, other, taddr)
+	if err != contract.ErrPermission {
+		t.Fatal("accept by non pending owner should fail: ", err)
+	}
+
+	err = testAcceptOwnership(t, n, newAdmin, taddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uid := n.GetNonce(admin, admin)
+	_, err = n.MintToken(uid, sign(t, admin, "MintToken", uid, taddr, admin, big.NewInt(1)), taddr, admin, admin, big.NewInt(1))
+	if err != contract.ErrPermission {
+		t.Fatal("old admin should not mint: ", err)
+	}
+
+	uid = n.GetNonce(newAdmin, newAdmin)
+	_, err = n.MintToken(uid, sign(t, newAdmin, "MintToken", uid, taddr, newAdmin, big.NewInt(1)), taddr, newAdmin, newAdmin, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oi, err = n.GetOwnerInfo(admin, taddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oi.Owner != newAdmin || oi.Pending != utils.NilAddress {
+		t.Fatal("owner info is wrong after accept: ", oi)
+	}
+
+	// role mgr, with cancel
+	err = testTransferOwnership(t, n, admin, raddr, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testTransferOwnership(t, n, admin, raddr, utils.NilAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testAcceptOwnership(t, n, other, raddr)
+	if err != contract.ErrPermission {
+		t.Fatal("canceled transfer should not be accepted: ", err)
+	}
+
+	err = testTransferOwnership(t, n, admin, raddr, newAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testAcceptOwnership(t, n, newAdmin, raddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ai, err := n.GetAdmins(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ai.Admins) != 1 || ai.Admins[0] != newAdmin {
+		t.Fatal("admin set is not rotated: ", ai)
+	}
+
+	uid = n.GetNonce(admin, admin)
+	_, err = n.CreateGroup(uid, sign(t, admin, "CreateGroup", uid, 7), admin, 7)
+	if err != contract.ErrPermission {
+		t.Fatal("old admin should not create group: ", err)
+	}
+	testCreateGroup(t, n, newAdmin)
+
+	// pledge pool is owned by role mgr contract, which can not sign
+	paddr := n.GetPledgeAddress(admin)
+	err = testTransferOwnership(t, n, newAdmin, paddr, newAdmin)
+	if err != contract.ErrEmpty {
+		t.Fatal("owner of pledge pool should not change: ", err)
+	}
+	_, err = n.GetOwnerInfo(admin, paddr)
+	if err != contract.ErrEmpty {
+		t.Fatal("pledge pool has no owner info: ", err)
+	}
+
+	// with more admins, transfer goes by proposal
+	id := testPropose(t, n, newAdmin, contract.OpAddAdmin, &contract.ProposalParas{Addr: admin})
+	err = testExecute(t, n, newAdmin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id = testPropose(t, n, newAdmin, contract.OpSetThreshold, &contract.ProposalParas{Threshold: 2})
+	err = testExecute(t, n, newAdmin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = testTransferOwnership(t, n, newAdmin, raddr, other)
+	if err != contract.ErrPermission {
+		t.Fatal("direct transfer should fail with threshold 2: ", err)
+	}
+
+	id = testPropose(t, n, newAdmin, contract.OpTransferOwnership, &contract.ProposalParas{Addr: other})
+	uid = n.GetNonce(admin, admin)
+	_, err = n.ApproveProposal(uid, sign(t, admin, "ApproveProposal", uid, id), admin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testExecute(t, n, newAdmin, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = testAcceptOwnership(t, n, other, raddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ai, err = n.GetAdmins(admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ai.Admins) != 2 || ai.Admins[0] != other || ai.Admins[1] != admin {
+		t.Fatal("admin set is wrong after proposal: ", ai)
+	}
+}
@@ -0,0 +1,219 @@
+package node
+
+import (
+	"math/big"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// params of each method, encoded as cbor array in Call.Params
+
+type advanceTimeParams struct {
+	_ struct{} `cbor:",toarray"`
+	D uint64
+}
+
+type approveParams struct {
+	_       struct{} `cbor:",toarray"`
+	TAddr   utils.Address
+	Spender utils.Address
+	Value   *big.Int
+}
+
+type transferParams struct {
+	_     struct{} `cbor:",toarray"`
+	TAddr utils.Address
+	To    utils.Address
+	Value *big.Int
+}
+
+type transferFromParams struct {
+	_     struct{} `cbor:",toarray"`
+	TAddr utils.Address
+	From  utils.Address
+	To    utils.Address
+	Value *big.Int
+}
+
+type mintTokenParams struct {
+	_            struct{} `cbor:",toarray"`
+	TAddr        utils.Address
+	Target       utils.Address
+	MintedAmount *big.Int
+}
+
+type burnParams struct {
+	_          struct{} `cbor:",toarray"`
+	TAddr      utils.Address
+	BurnAmount *big.Int
+}
+
+type airDropParams struct {
+	_     struct{} `cbor:",toarray"`
+	TAddr utils.Address
+	Addrs []utils.Address
+	Money *big.Int
+}
+
+type createRoleMgrParams struct {
+	_       struct{} `cbor:",toarray"`
+	Founder utils.Address
+	Token   utils.Address
+}
+
+type registerParams struct {
+	_    struct{} `cbor:",toarray"`
+	Addr utils.Address
+	Sign []byte
+}
+
+type registerTokenParams struct {
+	_     struct{} `cbor:",toarray"`
+	TAddr utils.Address
+}
+
+type registerKeeperParams struct {
+	_         struct{} `cbor:",toarray"`
+	Index     uint64
+	BlsKey    []byte
+	Signature []byte
+}
+
+type registerProviderParams struct {
+	_         struct{} `cbor:",toarray"`
+	Index     uint64
+	Signature []byte
+}
+
+type registerUserParams struct {
+	_      struct{} `cbor:",toarray"`
+	Index  uint64
+	GIndex uint64
+	BlsKey []byte
+}
+
+type pledgeParams struct {
+	_     struct{} `cbor:",toarray"`
+	Index uint64
+	Money *big.Int
+}
+
+type withdrawParams struct {
+	_          struct{} `cbor:",toarray"`
+	Index      uint64
+	TokenIndex uint32
+	Money      *big.Int
+}
+
+type createGroupParams struct {
+	_     struct{} `cbor:",toarray"`
+	Level uint16
+}
+
+type proposeParams struct {
+	_     struct{} `cbor:",toarray"`
+	Op    uint8
+	Paras contract.ProposalParas
+}
+
+type proposalParams struct {
+	_  struct{} `cbor:",toarray"`
+	ID uint64
+}
+
+type addKeeperToGroupParams struct {
+	_      struct{} `cbor:",toarray"`
+	Index  uint64
+	GIndex uint64
+	Asigns [][]byte
+}
+
+type addProviderToGroupParams struct {
+	_      struct{} `cbor:",toarray"`
+	Index  uint64
+	GIndex uint64
+}
+
+type rechargeParams struct {
+	_          struct{} `cbor:",toarray"`
+	User       uint64
+	TokenIndex uint32
+	Money      *big.Int
+}
+
+type proWithdrawParams struct {
+	_          struct{} `cbor:",toarray"`
+	ProIndex   uint64
+	TokenIndex uint32
+	Pay        *big.Int
+	Lost       *big.Int
+	Ksigns     [][]byte
+}
+
+type withdrawFromFsParams struct {
+	_          struct{} `cbor:",toarray"`
+	Index      uint64
+	TokenIndex uint32
+	Amount     *big.Int
+}
+
+type orderParams struct {
+	_          struct{} `cbor:",toarray"`
+	User       uint64
+	ProIndex   uint64
+	Start      uint64
+	End        uint64
+	Size       uint64
+	Nonce      uint64
+	TokenIndex uint32
+	Sprice     *big.Int
+	Usign      []byte
+	Psign      []byte
+	Ksigns     [][]byte
+}
+
+type setReadyParams struct {
+	_      struct{} `cbor:",toarray"`
+	GIndex uint64
+	Ksigns [][]byte
+}
+
+// repairParams moves data of ProIndex to NewPro
+type repairParams struct {
+	_          struct{} `cbor:",toarray"`
+	ProIndex   uint64
+	NewPro     uint64
+	Start      uint64
+	End        uint64
+	Size       uint64
+	Nonce      uint64
+	TokenIndex uint32
+	Sprice     *big.Int
+	Psign      []byte
+	Ksigns     [][]byte
+}
+
+type transferOwnershipParams struct {
+	_        struct{} `cbor:",toarray"`
+	Contract utils.Address
+	NewOwner utils.Address
+}
+
+type acceptOwnershipParams struct {
+	_        struct{} `cbor:",toarray"`
+	Contract utils.Address
+}
+
+type changeAddressParams struct {
+	_       struct{} `cbor:",toarray"`
+	Index   uint64
+	NewAddr utils.Address
+	OldSig  []byte
+	NewSig  []byte
+}
+
+type addrIndexParams struct {
+	_     struct{} `cbor:",toarray"`
+	Index uint64
+}
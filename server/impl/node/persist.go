@@ -0,0 +1,133 @@
+package node
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+var nodeKey = []byte("node/meta")
+
+// nodeState is what node keeps besides contracts
+type nodeState struct {
+	Count   uint64
+	Applied uint64 // journal seq included in this state
+	RoleMgr utils.Address
+	Tokens  []utils.Address
+	Nonce   map[utils.Address]uint64
+	Head    uint64
+	Pending *types.Block
+}
+
+// load restores contracts and node state from ds; empty ds is a fresh node
+func (n *Node) load() error {
+	jh, err := loadJournalHead(n.ds)
+	if err != nil {
+		return err
+	}
+	n.journal = jh
+
+	val, err := n.ds.Get(nodeKey)
+	if err != nil {
+		if err == store.ErrNotFound {
+			if n.journal > 0 {
+				log.Warnf("no state, but journal is at %d; run replay to recover", n.journal)
+			}
+			return nil
+		}
+		return err
+	}
+
+	ns := new(nodeState)
+	err = cbor.Unmarshal(val, ns)
+	if err != nil {
+		return err
+	}
+
+	err = n.state.Load(n.ds)
+	if err != nil {
+		return err
+	}
+
+	for _, taddr := range ns.Tokens {
+		et, err := n.state.GetErcToken(taddr)
+		if err != nil {
+			return err
+		}
+		n.ercMap[taddr] = et
+	}
+
+	if ns.RoleMgr != utils.NilAddress {
+		rm, err := n.state.GetRoleMgr(ns.RoleMgr)
+		if err != nil {
+			return err
+		}
+		n.rm = rm
+	}
+
+	if ns.Nonce != nil {
+		n.nonceMap = ns.Nonce
+	}
+	n.count = ns.Count
+	n.applied = ns.Applied
+
+	n.head, err = loadBlock(n.ds, ns.Head)
+	if err != nil {
+		return err
+	}
+	n.pending = ns.Pending
+
+	if n.applied < n.journal {
+		log.Warnf("state is at journal %d, but journal is at %d; run replay to recover", n.applied, n.journal)
+	}
+
+	log.Infof("load %d tokens, roleMgr %s, %d calls, head %d", len(ns.Tokens), ns.RoleMgr, ns.Count, ns.Head)
+
+	return nil
+}
+
+// persist writes changed contracts and node state to ds in one batch;
+// contracts stay changed if it fails, so next persist writes them.
+// called with lock held
+func (n *Node) persist() error {
+	ns := &nodeState{
+		Count:   n.count,
+		Applied: n.applied,
+		Tokens:  make([]utils.Address, 0, len(n.ercMap)),
+		Nonce:   n.nonceMap,
+		Head:    n.head.Height,
+		Pending: n.pending,
+	}
+
+	if n.rm != nil {
+		ns.RoleMgr = n.rm.GetContractAddress()
+	}
+
+	for taddr := range n.ercMap {
+		ns.Tokens = append(ns.Tokens, taddr)
+	}
+
+	b := n.ds.NewBatch()
+	err := n.state.SaveDirty(b)
+	if err != nil {
+		log.Error("persist contracts fail: ", err)
+		return ErrPersist
+	}
+
+	val, err := cbor.Marshal(ns)
+	if err != nil {
+		log.Error("persist node fail: ", err)
+		return ErrPersist
+	}
+	b.Put(nodeKey, val)
+
+	err = b.Commit()
+	if err != nil {
+		log.Error("persist fail: ", err)
+		return ErrPersist
+	}
+
+	n.state.Clean()
+	return nil
+}
@@ -0,0 +1,223 @@
+package node
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func encodeContracts(t *testing.T, n *Node) map[string][]byte {
+	ds := store.NewMemStore()
+	b := ds.NewBatch()
+	err := n.state.Save(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := make(map[string][]byte)
+	err = ds.Iter(nil, func(key, value []byte) error {
+		res[string(key)] = value
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestPersist(t *testing.T) {
+	ds := store.NewMemStore()
+	n, err := NewNode(ds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	gindex := testCreateGroup(t, n, admin)
+	for i := 0; i < 7; i++ {
+		testAddKeeper(t, n, admin, gindex)
+	}
+	testAddProvider(t, n, admin, gindex)
+	uIndex := testCreateUser(t, n, admin, gindex)
+
+	before := encodeContracts(t, n)
+	bal := n.BalanceOf(taddr, admin, admin)
+	fsBal, err := n.GetBalanceInFs(admin, uIndex, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := n.GetNonce(admin, admin)
+
+	// restart on same datastore
+	nn, err := NewNode(ds, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after := encodeContracts(t, nn)
+	if len(before) != len(after) {
+		t.Fatal("contract number changes: ", len(before), len(after))
+	}
+
+	for key, val := range before {
+		if !bytes.Equal(val, after[key]) {
+			t.Fatal("contract state changes after reload: ", utils.BytesToAddress([]byte(key)))
+		}
+	}
+
+	if nn.GetNonce(admin, admin) != nonce {
+		t.Fatal("nonce is not restored")
+	}
+
+	if nn.BalanceOf(taddr, admin, admin).Cmp(bal) != 0 {
+		t.Fatal("balance is not restored")
+	}
+
+	nfsBal, err := nn.GetBalanceInFs(admin, uIndex, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nfsBal[0].Cmp(fsBal[0]) != 0 {
+		t.Fatal("fs balance is not restored")
+	}
+
+	// reloaded node keeps working
+	uid := nn.GetNonce(admin, admin)
+	sig := sign(t, admin, "Transfer", uid, taddr, founder, big.NewInt(10))
+	_, err = nn.Transfer(uid, sig, taddr, admin, founder, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if nn.BalanceOf(taddr, founder, founder).Cmp(big.NewInt(10)) != 0 {
+		t.Fatal("transfer after reload fails")
+	}
+
+	val, err := ds.Get(nodeKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := new(nodeState)
+	err = cbor.Unmarshal(val, ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns.Nonce[admin] != uid+1 {
+		t.Fatal("nonce is not persisted")
+	}
+}
+
+// recordStore keeps contract keys written by each persist, and fails
+// persist of node state if fail is set
+type recordStore struct {
+	store.KVStore
+	fail    bool
+	written []string
+}
+
+type recordBatch struct {
+	store.Batch
+	rs   *recordStore
+	keys []string
+}
+
+func (rs *recordStore) NewBatch() store.Batch {
+	return &recordBatch{Batch: rs.KVStore.NewBatch(), rs: rs}
+}
+
+func (b *recordBatch) Put(key, value []byte) {
+	b.keys = append(b.keys, string(key))
+	b.Batch.Put(key, value)
+}
+
+func (b *recordBatch) Commit() error {
+	isState := false
+	for _, key := range b.keys {
+		if key == string(nodeKey) {
+			isState = true
+		}
+	}
+	if !isState {
+		return b.Batch.Commit()
+	}
+
+	if b.rs.fail {
+		return errors.New("disk is full")
+	}
+
+	b.rs.written = b.rs.written[:0]
+	for _, key := range b.keys {
+		if strings.HasPrefix(key, "contract/") {
+			b.rs.written = append(b.rs.written, key)
+		}
+	}
+	return b.Batch.Commit()
+}
+
+func TestPersistChanged(t *testing.T) {
+	rs := &recordStore{KVStore: store.NewMemStore()}
+	n, err := NewNode(rs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+	testCreateGroup(t, n, admin)
+
+	// transfer only changes the token
+	uid := n.GetNonce(admin, admin)
+	sig := sign(t, admin, "Transfer", uid, taddr, founder, big.NewInt(10))
+	_, err = n.Transfer(uid, sig, taddr, admin, founder, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.written) != 1 || rs.written[0] != string(contractKey(taddr)) {
+		t.Fatal("persist should write the token only: ", len(rs.written))
+	}
+
+	// failed write is reported, and written by next call
+	rs.fail = true
+	uid = n.GetNonce(admin, admin)
+	sig = sign(t, admin, "Transfer", uid, taddr, founder, big.NewInt(10))
+	tx, err := n.Transfer(uid, sig, taddr, admin, founder, big.NewInt(10))
+	if err != ErrPersist || tx == utils.NilHash {
+		t.Fatal("failed persist should be returned with tx: ", err)
+	}
+
+	rs.fail = false
+	uid = n.GetNonce(admin, admin)
+	sig = sign(t, admin, "Transfer", uid, taddr, admin, big.NewInt(0))
+	_, err = n.Transfer(uid, sig, taddr, admin, admin, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nn, err := NewNode(rs.KVStore, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nn.BalanceOf(taddr, founder, founder).Cmp(big.NewInt(20)) != 0 {
+		t.Fatal("balance of failed persist is not written later")
+	}
+}
+
+func contractKey(addr utils.Address) []byte {
+	return append([]byte("contract/"), addr[:]...)
+}
@@ -0,0 +1,143 @@
+package node
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+var receiptPrefix = []byte("receipt/") // tx hash -> receipt
+
+var errCodes = map[error]uint32{
+	contract.ErrRes:              types.CodeRes,
+	contract.ErrInput:            types.CodeInput,
+	contract.ErrEmpty:            types.CodeEmpty,
+	contract.ErrExist:            types.CodeExist,
+	contract.ErrValue:            types.CodeValue,
+	contract.ErrNoSuchAddr:       types.CodeNoSuchAddr,
+	contract.ErrMisType:          types.CodeMisType,
+	contract.ErrRoleType:         types.CodeRoleType,
+	contract.ErrBalanceNotEnough: types.CodeBalanceNotEnough,
+	contract.ErrPermission:       types.CodePermission,
+	contract.ErrNonce:            types.CodeNonce,
+	contract.ErrOutOfGas:         types.CodeOutOfGas,
+	contract.ErrSign:             types.CodeSign,
+	contract.ErrQuorum:           types.CodeQuorum,
+	ErrRes:                       types.CodeRes,
+	ErrNonce:                     types.CodeNonce,
+	ErrMethod:                    types.CodeMethod,
+	ErrClock:                     types.CodeClock,
+	ErrBlock:                     types.CodeBlock,
+	ErrReceipt:                   types.CodeReceipt,
+	ErrGas:                       types.CodeGas,
+	ErrFee:                       types.CodeFee,
+	ErrGasPrice:                  types.CodeGasPrice,
+	ErrMessage:                   types.CodeMessage,
+	ErrNonceGap:                  types.CodeNonceGap,
+	ErrReplace:                   types.CodeReplace,
+	ErrChainID:                   types.CodeChainID,
+	ErrGenesis:                   types.CodeGenesis,
+	ErrSpec:                      types.CodeSpec,
+	ErrPersist:                   types.CodePersist,
+	ErrNonceFuture:               types.CodeNonceFuture,
+	ErrMpoolFull:                 types.CodeMpoolFull,
+	ErrNoRoleMgr:                 types.CodeNoRoleMgr,
+	ErrPanic:                     types.CodePanic,
+}
+
+func errCode(err error) uint32 {
+	if err == nil {
+		return types.CodeOK
+	}
+
+	code, ok := errCodes[err]
+	if ok {
+		return code
+	}
+
+	return types.CodeUnknown
+}
+
+func receiptKey(tx utils.Hash) []byte {
+	return append(append([]byte(nil), receiptPrefix...), tx[:]...)
+}
+
+// addTx puts executed call c into block b and keeps its receipt;
+// called with lock held
+func (n *Node) addTx(b *types.Block, c *Call, gasUsed uint64, err error) utils.Hash {
+	tx := c.Hash()
+
+	r := &types.Receipt{
+		TxHash:  tx,
+		Height:  b.Height,
+		Index:   uint32(len(b.Txs)),
+		Status:  types.ReceiptSuccess,
+		ErrCode: errCode(err),
+		GasUsed: gasUsed,
+	}
+	if err != nil {
+		r.Status = types.ReceiptFailed
+		r.Err = err.Error()
+	}
+
+	// events of failed call are reverted, only its fee is left
+	r.Events = n.state.TakeEvents()
+
+	b.Txs = append(b.Txs, tx)
+
+	bt := n.ds.NewBatch()
+	for i, ev := range r.Events {
+		ev.Height = b.Height
+		ev.TxHash = tx
+
+		val, merr := cbor.Marshal(ev)
+		if merr != nil {
+			log.Error("marshal event fail: ", merr)
+			return tx
+		}
+		bt.Put(eventKey(b.Height, r.Index, uint32(i)), val)
+	}
+
+	val, merr := cbor.Marshal(r)
+	if merr != nil {
+		log.Error("marshal receipt fail: ", merr)
+		return tx
+	}
+	bt.Put(receiptKey(tx), val)
+
+	merr = bt.Commit()
+	if merr != nil {
+		log.Error("store receipt fail: ", merr)
+		return tx
+	}
+
+	for _, ev := range r.Events {
+		n.subs.pubEvent(ev)
+	}
+
+	return tx
+}
+
+// GetReceipt returns receipt of tx once it is executed; its block may still be open
+func (n *Node) GetReceipt(caller utils.Address, tx utils.Hash) (*types.Receipt, error) {
+	n.RLock()
+	defer n.RUnlock()
+
+	val, err := n.ds.Get(receiptKey(tx))
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, ErrReceipt
+		}
+		return nil, err
+	}
+
+	r := new(types.Receipt)
+	err = cbor.Unmarshal(val, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
@@ -0,0 +1,179 @@
+package node
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+func TestReceipt(t *testing.T) {
+	n, err := NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+
+	uid := n.GetNonce(admin, admin)
+	tx, err := n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, founder, big.NewInt(10)), taddr, admin, founder, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := n.GetReceipt(admin, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TxHash != tx || r.Status != types.ReceiptSuccess || r.ErrCode != types.CodeOK || r.Height != 1 || r.Index != 1 {
+		t.Fatal("receipt of transfer is wrong: ", r)
+	}
+
+	// failed call gets a receipt and uses the nonce
+	uid = n.GetNonce(admin, admin)
+	tx, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, founder, new(big.Int).Lsh(big.NewInt(1), 200)), taddr, admin, founder, new(big.Int).Lsh(big.NewInt(1), 200))
+	if err == nil {
+		t.Fatal("transfer more than balance should fail")
+	}
+	if tx == utils.NilHash {
+		t.Fatal("failed call should have tx hash")
+	}
+
+	r, err = n.GetReceipt(admin, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != types.ReceiptFailed || r.ErrCode == types.CodeOK || r.Err == "" {
+		t.Fatal("receipt of failed transfer is wrong: ", r)
+	}
+	if n.GetNonce(admin, admin) != uid+1 {
+		t.Fatal("executed call should use nonce")
+	}
+
+	// call which is not accepted keeps the nonce
+	uid = n.GetNonce(admin, admin)
+	tx, err = n.Transfer(uid-1, sign(t, admin, "Transfer", uid-1, taddr, founder, big.NewInt(10)), taddr, admin, founder, big.NewInt(10))
+	if err != ErrNonce || tx != utils.NilHash {
+		t.Fatal("call with wrong nonce should be rejected")
+	}
+
+	tx, err = n.Transfer(uid, sign(t, founder, "Transfer", uid, taddr, founder, big.NewInt(10)), taddr, admin, founder, big.NewInt(10))
+	if err != ErrRes || tx != utils.NilHash {
+		t.Fatal("call with wrong sig should be rejected")
+	}
+
+	// sig does not cover other params
+	tx, err = n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, founder, big.NewInt(10)), taddr, admin, admin, big.NewInt(11))
+	if err != ErrRes || tx != utils.NilHash {
+		t.Fatal("sig with other params should be rejected")
+	}
+
+	if n.GetNonce(admin, admin) != uid {
+		t.Fatal("rejected call burns nonce")
+	}
+
+	_, err = n.GetReceipt(admin, utils.HashOf([]byte("none")))
+	if err != ErrReceipt {
+		t.Fatal("receipt should not exist")
+	}
+}
+
+func TestErrCodes(t *testing.T) {
+	errs := map[string]error{
+		"contract.ErrRes":              contract.ErrRes,
+		"contract.ErrInput":            contract.ErrInput,
+		"contract.ErrEmpty":            contract.ErrEmpty,
+		"contract.ErrExist":            contract.ErrExist,
+		"contract.ErrValue":            contract.ErrValue,
+		"contract.ErrNoSuchAddr":       contract.ErrNoSuchAddr,
+		"contract.ErrMisType":          contract.ErrMisType,
+		"contract.ErrRoleType":         contract.ErrRoleType,
+		"contract.ErrBalanceNotEnough": contract.ErrBalanceNotEnough,
+		"contract.ErrPermission":       contract.ErrPermission,
+		"contract.ErrNonce":            contract.ErrNonce,
+		"contract.ErrOutOfGas":         contract.ErrOutOfGas,
+		"contract.ErrSign":             contract.ErrSign,
+		"contract.ErrQuorum":           contract.ErrQuorum,
+		"node.ErrRes":                  ErrRes,
+		"node.ErrNonce":                ErrNonce,
+		"node.ErrMethod":               ErrMethod,
+		"node.ErrClock":                ErrClock,
+		"node.ErrBlock":                ErrBlock,
+		"node.ErrReceipt":              ErrReceipt,
+		"node.ErrGas":                  ErrGas,
+		"node.ErrFee":                  ErrFee,
+		"node.ErrGasPrice":             ErrGasPrice,
+		"node.ErrMessage":              ErrMessage,
+		"node.ErrNonceGap":             ErrNonceGap,
+		"node.ErrReplace":              ErrReplace,
+		"node.ErrChainID":              ErrChainID,
+		"node.ErrGenesis":              ErrGenesis,
+		"node.ErrSpec":                 ErrSpec,
+		"node.ErrPersist":              ErrPersist,
+		"node.ErrNonceFuture":          ErrNonceFuture,
+		"node.ErrMpoolFull":            ErrMpoolFull,
+		"node.ErrNoRoleMgr":            ErrNoRoleMgr,
+		"node.ErrPanic":                ErrPanic,
+	}
+
+	for name, err := range errs {
+		code := errCode(err)
+		if code == types.CodeOK || code == types.CodeUnknown {
+			t.Error(name, " has no code")
+		}
+	}
+
+	// every exported error of both packages is in the table
+	for pkg, dir := range map[string]string{"contract": "../../contract", "node": "."} {
+		for _, name := range exportedErrs(t, dir) {
+			_, ok := errs[pkg+"."+name]
+			if !ok {
+				t.Error(pkg+"."+name, " is not in table")
+			}
+		}
+	}
+}
+
+// exportedErrs returns names of exported package vars named Err* in dir
+func exportedErrs(t *testing.T, dir string) []string {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0)
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.Name, "_test") {
+			continue
+		}
+		for fname, f := range pkg.Files {
+			if strings.HasSuffix(fname, "_test.go") {
+				continue
+			}
+			for _, d := range f.Decls {
+				gd, ok := d.(*ast.GenDecl)
+				if !ok || gd.Tok != token.VAR {
+					continue
+				}
+				for _, spec := range gd.Specs {
+					for _, id := range spec.(*ast.ValueSpec).Names {
+						if id.IsExported() && strings.HasPrefix(id.Name, "Err") {
+							names = append(names, id.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
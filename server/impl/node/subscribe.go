@@ -0,0 +1,130 @@
+package node
+
+import (
+	"context"
+	"sync"
+
+	"github.com/memoio/go-settlement/server/types"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// subBuffer is items kept for a slow subscriber; it is dropped when full
+const subBuffer = 64
+
+type eventSub struct {
+	filter *types.EventFilter
+	ch     chan *types.Event
+}
+
+// subHub keeps subscriptions of heads and events
+type subHub struct {
+	sync.Mutex
+	next   uint64
+	heads  map[uint64]chan *types.Block
+	events map[uint64]*eventSub
+}
+
+func newSubHub() *subHub {
+	return &subHub{
+		heads:  make(map[uint64]chan *types.Block),
+		events: make(map[uint64]*eventSub),
+	}
+}
+
+func (h *subHub) pubHead(b *types.Block) {
+	h.Lock()
+	defer h.Unlock()
+
+	for id, ch := range h.heads {
+		select {
+		case ch <- b:
+		default:
+			log.Warnf("head subscriber %d is too slow, drop it", id)
+			close(ch)
+			delete(h.heads, id)
+		}
+	}
+}
+
+func (h *subHub) pubEvent(ev *types.Event) {
+	h.Lock()
+	defer h.Unlock()
+
+	for id, es := range h.events {
+		f := es.filter
+		if ev.Height < f.FromHeight || (f.ToHeight > 0 && ev.Height > f.ToHeight) {
+			continue
+		}
+
+		if !f.Match(ev) {
+			continue
+		}
+
+		select {
+		case es.ch <- ev:
+		default:
+			log.Warnf("event subscriber %d is too slow, drop it", id)
+			close(es.ch)
+			delete(h.events, id)
+		}
+	}
+}
+
+// SubscribeHeads sends each sealed block until ctx is done
+func (n *Node) SubscribeHeads(ctx context.Context, caller utils.Address) (<-chan *types.Block, error) {
+	h := n.subs
+	ch := make(chan *types.Block, subBuffer)
+
+	h.Lock()
+	id := h.next
+	h.next++
+	h.heads[id] = ch
+	h.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		h.Lock()
+		defer h.Unlock()
+		_, ok := h.heads[id]
+		if ok {
+			close(ch)
+			delete(h.heads, id)
+		}
+	}()
+
+	return ch, nil
+}
+
+// SubscribeEvents sends each new event selected by filter until ctx is done
+func (n *Node) SubscribeEvents(ctx context.Context, caller utils.Address, filter *types.EventFilter) (<-chan *types.Event, error) {
+	if filter == nil {
+		filter = new(types.EventFilter)
+	}
+
+	h := n.subs
+	es := &eventSub{
+		filter: filter,
+		ch:     make(chan *types.Event, subBuffer),
+	}
+
+	h.Lock()
+	id := h.next
+	h.next++
+	h.events[id] = es
+	h.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		h.Lock()
+		defer h.Unlock()
+		_, ok := h.events[id]
+		if ok {
+			close(es.ch)
+			delete(h.events, id)
+		}
+	}()
+
+	return es.ch, nil
+}
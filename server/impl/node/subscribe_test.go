@@ -0,0 +1,98 @@
+package node
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/memoio/go-settlement/server/contract"
+	"github.com/memoio/go-settlement/server/store"
+	"github.com/memoio/go-settlement/server/types"
+)
+
+func TestSubscribe(t *testing.T) {
+	n, err := NewNode(store.NewMemStore(), contract.NewMockClock(1600000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admin := testNewKey(t)
+	founder := testNewKey(t)
+	taddr := testErc(t, n, admin)
+	testCreateRoleMgr(t, n, admin, taddr, founder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	heads, err := n.SubscribeHeads(ctx, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evs, err := n.SubscribeEvents(ctx, admin, &types.EventFilter{
+		Contract: taddr,
+		Types:    []string{types.EventTransfer},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nil filter gets all
+	all, err := n.SubscribeEvents(ctx, admin, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// approval is filtered out
+	uid := n.GetNonce(admin, admin)
+	_, err = n.Approve(uid, sign(t, admin, "Approve", uid, taddr, founder, big.NewInt(10)), taddr, admin, founder, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-all:
+		if ev.Type != types.EventApproval {
+			t.Fatal("wrong event is sent to nil filter: ", ev.Type)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event is sent to nil filter")
+	}
+
+	uid = n.GetNonce(admin, admin)
+	tx, err := n.Transfer(uid, sign(t, admin, "Transfer", uid, taddr, founder, big.NewInt(10)), taddr, admin, founder, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-evs:
+		if ev.Type != types.EventTransfer || ev.TxHash != tx {
+			t.Fatal("wrong event is sent")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event is sent")
+	}
+
+	testAdvanceTime(t, n, admin, 1)
+
+	select {
+	case b := <-heads:
+		if b.Height != 1 || len(b.Txs) != 6 {
+			t.Fatal("wrong head is sent")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no head is sent")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-heads:
+		if ok {
+			t.Fatal("head channel is not closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("head channel is not closed")
+	}
+}
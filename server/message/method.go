@@ -0,0 +1,48 @@
+package message
+
+import (
+	"github.com/memoio/go-settlement/utils"
+)
+
+// Method IDs of Message; Params is cbor of the Paras type noted
+const (
+	MethodTransfer           uint32 = iota + 1 // ParasCommon{TokenIndex, Extra: to}; Value to role To if Extra is empty
+	MethodApprove                              // ParasCommon{TokenIndex, Extra: spender}; as Transfer
+	MethodRegister                             // ParasBase{Addr, Sig}
+	MethodRegisterToken                        // ParasBase{Addr}
+	MethodRegisterKeeper                       // SignedParasCommon{Index, Extra: blsKey, Auth: [ksign]}
+	MethodRegisterProvider                     // SignedParasCommon{Index, Auth: [psign]}
+	MethodRegisterUser                         // ParasCommon{Index, GIndex, Extra: blsKey}
+	MethodCreateGroup                          // ParasCommon{Index: level}
+	MethodAddKeeperToGroup                     // SignedParasCommon{Index, GIndex, Auth: asigns}
+	MethodAddProviderToGroup                   // ParasCommon{Index, GIndex}
+	MethodPledge                               // ParasCommon{Index, Amount}
+	MethodWithdraw                             // ParasCommon{Index, TokenIndex, Amount}
+	MethodRecharge                             // ParasCommon{Index: user, TokenIndex, Amount}
+	MethodWithdrawFromFs                       // ParasCommon{Index, TokenIndex, Amount}
+	MethodProWithdraw                          // SignedParasProWithdraw
+	MethodAddOrder                             // SignedParasOrder
+	MethodSubOrder                             // SignedParasOrder
+	MethodTransferFrom                         // ParasCommon{Index: from, TokenIndex, Extra: to}; Value from role Index, to as Transfer
+	MethodMintToken                            // ParasCommon{TokenIndex, Extra: target}; Value minted to target as Transfer
+	MethodBurn                                 // ParasCommon{TokenIndex}; Value burned from From, the token admin
+	MethodAirDrop                              // ParasCommon{TokenIndex, Extra: addresses}; Value to each address
+)
+
+// MethodPush is method name in digest of a message
+const MethodPush = "PushMessage"
+
+// Serialize encodes m in canonical cbor, as kept by node
+func (m *Message) Serialize() ([]byte, error) {
+	return utils.CanonicalMarshal(m)
+}
+
+// Digest is signed by From, it covers whole message on chain chainID
+func (m *Message) Digest(chainID uint64) (utils.Hash, error) {
+	buf, err := m.Serialize()
+	if err != nil {
+		return utils.NilHash, err
+	}
+
+	return utils.CallDigest(MethodPush, chainID, m.Nonce, buf)
+}
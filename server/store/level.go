@@ -0,0 +1,84 @@
+package store
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+var _ KVStore = (*levelStore)(nil)
+
+type levelStore struct {
+	db *leveldb.DB
+}
+
+// NewLevelStore opens or creates a leveldb datastore in dir
+func NewLevelStore(dir string) (KVStore, error) {
+	db, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &levelStore{db: db}, nil
+}
+
+func (l *levelStore) Get(key []byte) ([]byte, error) {
+	val, err := l.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return val, err
+}
+
+func (l *levelStore) Has(key []byte) (bool, error) {
+	return l.db.Has(key, nil)
+}
+
+func (l *levelStore) Put(key, value []byte) error {
+	return l.db.Put(key, value, nil)
+}
+
+func (l *levelStore) Delete(key []byte) error {
+	return l.db.Delete(key, nil)
+}
+
+func (l *levelStore) Iter(prefix []byte, fn func(key, value []byte) error) error {
+	it := l.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+
+	for it.Next() {
+		err := fn(it.Key(), it.Value())
+		if err != nil {
+			return err
+		}
+	}
+
+	return it.Error()
+}
+
+func (l *levelStore) NewBatch() Batch {
+	return &levelBatch{
+		db:    l.db,
+		batch: new(leveldb.Batch),
+	}
+}
+
+func (l *levelStore) Close() error {
+	return l.db.Close()
+}
+
+type levelBatch struct {
+	db    *leveldb.DB
+	batch *leveldb.Batch
+}
+
+func (b *levelBatch) Put(key, value []byte) {
+	b.batch.Put(key, value)
+}
+
+func (b *levelBatch) Delete(key []byte) {
+	b.batch.Delete(key)
+}
+
+func (b *levelBatch) Commit() error {
+	return b.db.Write(b.batch, nil)
+}
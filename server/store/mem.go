@@ -0,0 +1,127 @@
+package store
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+var _ KVStore = (*memStore)(nil)
+
+// memStore keeps everything in memory, for tests and dev nodes
+type memStore struct {
+	sync.RWMutex
+	data map[string][]byte
+}
+
+func NewMemStore() KVStore {
+	return &memStore{
+		data: make(map[string][]byte),
+	}
+}
+
+func (m *memStore) Get(key []byte) ([]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	val, ok := m.data[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]byte(nil), val...), nil
+}
+
+func (m *memStore) Has(key []byte) (bool, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	_, ok := m.data[string(key)]
+	return ok, nil
+}
+
+func (m *memStore) Put(key, value []byte) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.data[string(key)] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *memStore) Delete(key []byte) error {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.data, string(key))
+	return nil
+}
+
+func (m *memStore) Iter(prefix []byte, fn func(key, value []byte) error) error {
+	m.RLock()
+	keys := make([]string, 0, len(m.data))
+	for k := range m.data {
+		if bytes.HasPrefix([]byte(k), prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	vals := make([][]byte, len(keys))
+	for i, k := range keys {
+		vals[i] = m.data[k]
+	}
+	m.RUnlock()
+
+	for i, k := range keys {
+		err := fn([]byte(k), vals[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *memStore) NewBatch() Batch {
+	return &memBatch{
+		m: m,
+	}
+}
+
+func (m *memStore) Close() error {
+	return nil
+}
+
+type memOp struct {
+	key    string
+	value  []byte
+	delete bool
+}
+
+type memBatch struct {
+	m   *memStore
+	ops []memOp
+}
+
+func (b *memBatch) Put(key, value []byte) {
+	b.ops = append(b.ops, memOp{key: string(key), value: append([]byte(nil), value...)})
+}
+
+func (b *memBatch) Delete(key []byte) {
+	b.ops = append(b.ops, memOp{key: string(key), delete: true})
+}
+
+func (b *memBatch) Commit() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	for _, op := range b.ops {
+		if op.delete {
+			delete(b.m.data, op.key)
+		} else {
+			b.m.data[op.key] = op.value
+		}
+	}
+
+	return nil
+}
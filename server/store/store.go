@@ -0,0 +1,30 @@
+package store
+
+import (
+	"errors"
+)
+
+var ErrNotFound = errors.New("key not found")
+
+// KVStore is the datastore the node keeps its state in
+type KVStore interface {
+	Get(key []byte) ([]byte, error)
+	Has(key []byte) (bool, error)
+	Put(key, value []byte) error
+	Delete(key []byte) error
+
+	// Iter calls fn on each key with prefix, in key order; stops at first error
+	Iter(prefix []byte, fn func(key, value []byte) error) error
+
+	// NewBatch groups writes so they are applied atomically
+	NewBatch() Batch
+
+	Close() error
+}
+
+// Batch is a set of writes committed at once
+type Batch interface {
+	Put(key, value []byte)
+	Delete(key []byte)
+	Commit() error
+}
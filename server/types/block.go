@@ -0,0 +1,50 @@
+package types
+
+import (
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/utils"
+)
+
+var encMode, _ = cbor.CanonicalEncOptions().EncMode()
+
+type BlockHeader struct {
+	_ struct{} `cbor:",toarray"`
+
+	Height uint64
+	Time   uint64 // contract time of all txs in block
+	Parent utils.Hash
+	TxRoot utils.Hash
+
+	ChainID uint64 // set at genesis, in digest of all signatures
+}
+
+// Hash is hash of the header, which covers txs by TxRoot
+func (h *BlockHeader) Hash() utils.Hash {
+	buf, err := encMode.Marshal(h)
+	if err != nil {
+		panic(err)
+	}
+	return utils.HashOf(buf)
+}
+
+type Block struct {
+	BlockHeader
+	Txs []utils.Hash // hash of calls in order
+}
+
+// Genesis is the parent of first block on chain chainID
+func Genesis(chainID uint64) *Block {
+	b := &Block{}
+	b.TxRoot = TxRoot(nil)
+	b.ChainID = chainID
+	return b
+}
+
+// TxRoot is hash of tx hashes in order
+func TxRoot(txs []utils.Hash) utils.Hash {
+	buf := make([]byte, 0, len(txs)*utils.HashLength)
+	for _, tx := range txs {
+		buf = append(buf, tx[:]...)
+	}
+	return utils.HashOf(buf)
+}
@@ -0,0 +1,7 @@
+package types
+
+import "errors"
+
+var (
+	ErrEventType = errors.New("unknown event type")
+)
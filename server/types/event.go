@@ -0,0 +1,236 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/memoio/go-settlement/utils"
+)
+
+// event types
+const (
+	EventTransfer             = "Transfer"
+	EventApproval             = "Approval"
+	EventRegistered           = "Registered"
+	EventGroupCreated         = "GroupCreated"
+	EventOrderAdded           = "OrderAdded"
+	EventOrderSubtracted      = "OrderSubtracted"
+	EventRepair               = "Repair"
+	EventProviderPaid         = "ProviderPaid"
+	EventKeeperRewarded       = "KeeperRewarded"
+	EventPledged              = "Pledged"
+	EventWithdrawn            = "Withdrawn"
+	EventProposed             = "Proposed"
+	EventApproved             = "Approved"
+	EventExecuted             = "Executed"
+	EventOwnershipPending     = "OwnershipPending"
+	EventOwnershipTransferred = "OwnershipTransferred"
+	EventAddressChanging      = "AddressChanging"
+	EventAddressChanged       = "AddressChanged"
+	EventAddressCanceled      = "AddressCanceled"
+	EventFee                  = "Fee"
+)
+
+// Event is emitted by contract during a tx
+type Event struct {
+	Contract utils.Address
+	Type     string
+	Indexes  []uint64 // role indexes involved
+	Data     []byte   // cbor of typed event
+	Height   uint64
+	TxHash   utils.Hash
+}
+
+// Decode returns typed event in Data, such as *TransferEvent
+func (e *Event) Decode() (interface{}, error) {
+	var ev interface{}
+	switch e.Type {
+	case EventTransfer:
+		ev = new(TransferEvent)
+	case EventApproval:
+		ev = new(ApprovalEvent)
+	case EventRegistered:
+		ev = new(RegisteredEvent)
+	case EventGroupCreated:
+		ev = new(GroupCreatedEvent)
+	case EventOrderAdded, EventOrderSubtracted:
+		ev = new(OrderEvent)
+	case EventRepair:
+		ev = new(RepairEvent)
+	case EventProviderPaid:
+		ev = new(ProviderPaidEvent)
+	case EventKeeperRewarded:
+		ev = new(KeeperRewardedEvent)
+	case EventPledged:
+		ev = new(PledgedEvent)
+	case EventWithdrawn:
+		ev = new(WithdrawnEvent)
+	case EventProposed, EventApproved, EventExecuted:
+		ev = new(ProposalEvent)
+	case EventOwnershipPending, EventOwnershipTransferred:
+		ev = new(OwnershipEvent)
+	case EventAddressChanging, EventAddressChanged, EventAddressCanceled:
+		ev = new(AddressEvent)
+	case EventFee:
+		ev = new(FeeEvent)
+	default:
+		return nil, ErrEventType
+	}
+
+	err := cbor.Unmarshal(e.Data, ev)
+	if err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+type TransferEvent struct {
+	From  utils.Address // nil when minted
+	To    utils.Address // nil when burned
+	Value *big.Int
+}
+
+// FeeEvent is gas fee paid by a call, it is not a Transfer
+type FeeEvent struct {
+	Payer      utils.Address
+	Foundation utils.Address
+	Value      *big.Int
+}
+
+type ApprovalEvent struct {
+	Owner   utils.Address
+	Spender utils.Address
+	Value   *big.Int // added allowance
+}
+
+type RegisteredEvent struct {
+	Index    uint64
+	Addr     utils.Address
+	RoleType uint8
+	GIndex   uint64
+}
+
+type GroupCreatedEvent struct {
+	GIndex uint64
+	Level  uint16
+	FsAddr utils.Address
+}
+
+// OrderEvent is for OrderAdded and OrderSubtracted
+type OrderEvent struct {
+	Keeper     uint64
+	User       uint64
+	Provider   uint64
+	Start      uint64
+	End        uint64
+	Size       uint64
+	Nonce      uint64
+	TokenIndex uint32
+	Price      *big.Int
+}
+
+type RepairEvent struct {
+	Keeper      uint64
+	Provider    uint64
+	NewProvider uint64
+	Start       uint64
+	End         uint64
+	Size        uint64
+	Nonce       uint64
+	TokenIndex  uint32
+	Price       *big.Int
+	Added       bool // false for SubRepair
+}
+
+type ProviderPaidEvent struct {
+	Provider   uint64
+	TokenIndex uint32
+	Amount     *big.Int
+}
+
+type KeeperRewardedEvent struct {
+	Keeper     uint64
+	TokenIndex uint32
+	Amount     *big.Int
+}
+
+type PledgedEvent struct {
+	Index  uint64
+	Amount *big.Int
+}
+
+// WithdrawnEvent is for withdraw from pledge or fs
+type WithdrawnEvent struct {
+	Index      uint64
+	TokenIndex uint32
+	Amount     *big.Int
+}
+
+// ProposalEvent is of admin proposal ID with op Op, Admin is who acts
+type ProposalEvent struct {
+	ID    uint64
+	Op    uint8
+	Admin utils.Address
+}
+
+// OwnershipEvent is for OwnershipPending and OwnershipTransferred
+type OwnershipEvent struct {
+	Owner    utils.Address
+	NewOwner utils.Address // nil if pending transfer is canceled
+}
+
+// AddressEvent is for change of address of Index; Ready is when
+// a pending change can be confirmed, or when it is applied
+type AddressEvent struct {
+	Index   uint64
+	OldAddr utils.Address
+	NewAddr utils.Address
+	Ready   uint64
+}
+
+// EventFilter selects events; empty field matches all
+type EventFilter struct {
+	Contract   utils.Address
+	Types      []string
+	Indexes    []uint64 // event involves one of them
+	FromHeight uint64
+	ToHeight   uint64 // latest if 0
+}
+
+// Match reports whether e is selected by f, height is not checked;
+// nil f selects all
+func (f *EventFilter) Match(e *Event) bool {
+	if f == nil {
+		return true
+	}
+
+	if f.Contract != utils.NilAddress && f.Contract != e.Contract {
+		return false
+	}
+
+	if len(f.Types) > 0 {
+		ok := false
+		for _, typ := range f.Types {
+			if typ == e.Type {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	if len(f.Indexes) > 0 {
+		for _, index := range f.Indexes {
+			for _, ei := range e.Indexes {
+				if index == ei {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	return true
+}
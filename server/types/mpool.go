@@ -0,0 +1,17 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/memoio/go-settlement/utils"
+)
+
+// PendingCall is a call waiting in mpool for earlier nonces of its caller
+type PendingCall struct {
+	TxHash   utils.Hash
+	Caller   utils.Address
+	Nonce    uint64
+	Method   string
+	GasLimit uint64
+	GasPrice *big.Int
+}
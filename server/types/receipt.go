@@ -0,0 +1,73 @@
+package types
+
+import (
+	"github.com/memoio/go-settlement/utils"
+)
+
+// receipt status
+const (
+	ReceiptFailed uint8 = iota
+	ReceiptSuccess
+)
+
+// error codes in receipt
+const (
+	CodeOK uint32 = iota
+	CodeUnknown
+	CodeRes
+	CodeInput
+	CodeEmpty
+	CodeExist
+	CodeValue
+	CodeNoSuchAddr
+	CodeMisType
+	CodeRoleType
+	CodeBalanceNotEnough
+	CodePermission
+	CodeNonce
+	CodeMethod
+	CodeOutOfGas
+	CodeSign
+	CodeQuorum
+	CodeClock
+	CodeBlock
+	CodeReceipt
+	CodeGas
+	CodeFee
+	CodeGasPrice
+	CodeMessage
+	CodeNonceGap
+	CodeReplace
+	CodeChainID
+	CodeGenesis
+	CodeSpec
+	CodePersist
+	CodeNonceFuture
+	CodeMpoolFull
+	CodeNoRoleMgr
+	CodePanic
+)
+
+// Receipt is result of a tx in block
+type Receipt struct {
+	TxHash  utils.Hash
+	Height  uint64
+	Index   uint32 // index of tx in block
+	Status  uint8
+	ErrCode uint32
+	Err     string
+	GasUsed uint64
+	Events  []*Event
+}
+
+// CreateResult is address of contract made by a call, with its tx hash
+type CreateResult struct {
+	Addr   utils.Address
+	TxHash utils.Hash
+}
+
+// ProposeResult is id of proposal made by a call, with its tx hash
+type ProposeResult struct {
+	ID     uint64
+	TxHash utils.Hash
+}
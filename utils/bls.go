@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+// BLS signatures on BLS12-381: public key is in G1, signature in G2,
+// both uncompressed; a proof of possession is a signature of the public key
+const (
+	BlsSecretKeyLength = 32
+	BlsPubKeyLength    = 96
+	BlsSignatureLength = 192
+)
+
+// domain separation tags of hash to G2
+var (
+	blsSigDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+	blsPopDST = []byte("BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+)
+
+var (
+	ErrBlsKey       = errors.New("bls key is not right")
+	ErrBlsSignature = errors.New("bls signature is not right")
+)
+
+// field modulus of BLS12-381
+var blsP, _ = new(big.Int).SetString("1a0111ea397fe69a4b1ba7b6434bacd764774b84f38512bf6730d2a0f6b0f6241eabfffeb153ffffb9feffffffffaaab", 16)
+
+type BlsKey struct {
+	SecretKey []byte
+	PubKey    []byte
+}
+
+func GenerateBlsKey(rand io.Reader) (*BlsKey, error) {
+	g1 := bls12381.NewG1()
+	buf := make([]byte, 64)
+	for {
+		_, err := io.ReadFull(rand, buf)
+		if err != nil {
+			return nil, err
+		}
+
+		s := new(big.Int).SetBytes(buf)
+		s.Mod(s, g1.Q())
+		if s.Sign() == 0 {
+			continue
+		}
+
+		sk := make([]byte, BlsSecretKeyLength)
+		s.FillBytes(sk)
+
+		pk, err := BlsPublicKey(sk)
+		if err != nil {
+			return nil, err
+		}
+
+		return &BlsKey{
+			SecretKey: sk,
+			PubKey:    pk,
+		}, nil
+	}
+}
+
+func blsScalar(sk []byte) (*big.Int, error) {
+	s := new(big.Int).SetBytes(sk)
+	if len(sk) != BlsSecretKeyLength || s.Sign() == 0 || s.Cmp(bls12381.NewG1().Q()) >= 0 {
+		return nil, ErrBlsKey
+	}
+	return s, nil
+}
+
+// BlsPublicKey is sk*G1
+func BlsPublicKey(sk []byte) ([]byte, error) {
+	s, err := blsScalar(sk)
+	if err != nil {
+		return nil, err
+	}
+
+	g1 := bls12381.NewG1()
+	pk := g1.MulScalar(g1.New(), g1.One(), s)
+	return g1.ToBytes(pk), nil
+}
+
+// BlsSign signs msg of any length
+func BlsSign(sk, msg []byte) ([]byte, error) {
+	return blsSign(sk, msg, blsSigDST)
+}
+
+func blsSign(sk, msg, dst []byte) ([]byte, error) {
+	s, err := blsScalar(sk)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := hashToG2(msg, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	g2 := bls12381.NewG2()
+	return g2.ToBytes(g2.MulScalar(g2.New(), h, s)), nil
+}
+
+// BlsVerify checks sig of pk on msg
+func BlsVerify(pk, msg, sig []byte) bool {
+	return blsVerify(pk, msg, sig, blsSigDST)
+}
+
+func blsVerify(pk, msg, sig, dst []byte) bool {
+	p, err := blsPubKey(pk)
+	if err != nil {
+		return false
+	}
+
+	s, err := blsSignature(sig)
+	if err != nil {
+		return false
+	}
+
+	h, err := hashToG2(msg, dst)
+	if err != nil {
+		return false
+	}
+
+	// e(pk, H(msg)) == e(G1, sig)
+	e := bls12381.NewPairingEngine()
+	e.AddPair(p, h)
+	e.AddPairInv(e.G1.One(), s)
+	return e.Check()
+}
+
+// BlsProve makes proof of possession of sk, which is its sig of own public key
+func BlsProve(sk []byte) ([]byte, error) {
+	pk, err := BlsPublicKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	return blsSign(sk, pk, blsPopDST)
+}
+
+// BlsCheckKey checks pk is a valid public key and pop is its proof of possession;
+// keys in aggregate must be checked so, or one may cancel out others
+func BlsCheckKey(pk, pop []byte) error {
+	if !blsVerify(pk, pk, pop, blsPopDST) {
+		return ErrBlsKey
+	}
+	return nil
+}
+
+// BlsAggregate adds up sigs, which then is checked by BlsVerifyAggregate
+func BlsAggregate(sigs [][]byte) ([]byte, error) {
+	if len(sigs) == 0 {
+		return nil, ErrBlsSignature
+	}
+
+	g2 := bls12381.NewG2()
+	res := g2.Zero()
+	for _, sig := range sigs {
+		s, err := blsSignature(sig)
+		if err != nil {
+			return nil, err
+		}
+		g2.Add(res, res, s)
+	}
+
+	return g2.ToBytes(res), nil
+}
+
+// BlsVerifyAggregate checks aggregated sig of all pks on the same msg
+func BlsVerifyAggregate(pks [][]byte, msg, sig []byte) bool {
+	if len(pks) == 0 {
+		return false
+	}
+
+	g1 := bls12381.NewG1()
+	apk := g1.Zero()
+	for _, pk := range pks {
+		p, err := blsPubKey(pk)
+		if err != nil {
+			return false
+		}
+		g1.Add(apk, apk, p)
+	}
+
+	return BlsVerify(g1.ToBytes(apk), msg, sig)
+}
+
+func blsPubKey(pk []byte) (*bls12381.PointG1, error) {
+	g1 := bls12381.NewG1()
+	if len(pk) != BlsPubKeyLength {
+		return nil, ErrBlsKey
+	}
+
+	p, err := g1.FromBytes(pk)
+	if err != nil || g1.IsZero(p) || !g1.InCorrectSubgroup(p) {
+		return nil, ErrBlsKey
+	}
+	return p, nil
+}
+
+func blsSignature(sig []byte) (*bls12381.PointG2, error) {
+	g2 := bls12381.NewG2()
+	if len(sig) != BlsSignatureLength {
+		return nil, ErrBlsSignature
+	}
+
+	s, err := g2.FromBytes(sig)
+	if err != nil || !g2.InCorrectSubgroup(s) {
+		return nil, ErrBlsSignature
+	}
+	return s, nil
+}
+
+// hashToG2 maps msg to G2 by SSWU of two field elements from expand_message_xmd
+func hashToG2(msg, dst []byte) (*bls12381.PointG2, error) {
+	u := expandMsgXmd(msg, dst, 256)
+
+	g2 := bls12381.NewG2()
+	res := g2.Zero()
+	for i := 0; i < 2; i++ {
+		// fe2 is c1 || c0, each reduced from 64 bytes
+		fe := make([]byte, 96)
+		c0 := new(big.Int).SetBytes(u[i*128 : i*128+64])
+		c1 := new(big.Int).SetBytes(u[i*128+64 : i*128+128])
+		c1.Mod(c1, blsP).FillBytes(fe[:48])
+		c0.Mod(c0, blsP).FillBytes(fe[48:])
+
+		q, err := g2.MapToCurve(fe)
+		if err != nil {
+			return nil, err
+		}
+		g2.Add(res, res, q)
+	}
+
+	return g2.Affine(res), nil
+}
+
+// expandMsgXmd is expand_message_xmd with sha256 of hash to curve
+func expandMsgXmd(msg, dst []byte, n int) []byte {
+	ell := (n + sha256.Size - 1) / sha256.Size
+	dstPrime := append(append([]byte(nil), dst...), byte(len(dst)))
+
+	h := sha256.New()
+	h.Write(make([]byte, sha256.BlockSize))
+	h.Write(msg)
+	h.Write([]byte{byte(n >> 8), byte(n), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(b0)
+	h.Write([]byte{1})
+	h.Write(dstPrime)
+	bi := h.Sum(nil)
+
+	res := append([]byte(nil), bi...)
+	for i := 2; i <= ell; i++ {
+		x := make([]byte, sha256.Size)
+		for j := range x {
+			x[j] = b0[j] ^ bi[j]
+		}
+
+		h.Reset()
+		h.Write(x)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		res = append(res, bi...)
+	}
+
+	return res[:n]
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+func TestExpandMsgXmd(t *testing.T) {
+	// vector of RFC 9380, K.1
+	res := expandMsgXmd([]byte(""), []byte("QUUX-V01-CS02-with-expander-SHA256-128"), 0x20)
+	if hex.EncodeToString(res) != "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235" {
+		t.Fatal("expand message is wrong: ", hex.EncodeToString(res))
+	}
+}
+
+func TestHashToG2(t *testing.T) {
+	// vector of RFC 9380, J.10.1 BLS12381G2_XMD:SHA-256_SSWU_RO_, msg ""
+	p, err := hashToG2([]byte(""), []byte("QUUX-V01-CS02-with-BLS12381G2_XMD:SHA-256_SSWU_RO_"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// x.c1 || x.c0 || y.c1 || y.c0
+	want := "05cb8437535e20ecffaef7752baddf98034139c38452458baeefab379ba13dff5bf5dd71b72418717047f5b0f37da03d" +
+		"0141ebfbdca40eb85b87142e130ab689c673cf60f1a3e98d69335266f30d9b8d4ac44c1038e9dcdd5393faf5c41fb78a" +
+		"12424ac32561493f3fe3c260708a12b7c620e7be00099a974e259ddc7d1f6395c3c811cdd19f1e8dbf3e9ecfdcbab8d6" +
+		"0503921d7f6a12805e72940b963c0cf3471c7b2a524950ca195d11062ee75ec076daf2d4bc358c4b190c0c98064fdd92"
+	res := bls12381.NewG2().ToBytes(p)
+	if hex.EncodeToString(res) != want {
+		t.Fatal("hash to G2 is wrong: ", hex.EncodeToString(res))
+	}
+}
+
+func TestBls(t *testing.T) {
+	keys := make([]*BlsKey, 3)
+	for i := range keys {
+		k, err := GenerateBlsKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = k
+	}
+
+	msg := []byte("test")
+	sigs := make([][]byte, len(keys))
+	pks := make([][]byte, len(keys))
+	for i, k := range keys {
+		sig, err := BlsSign(k.SecretKey, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !BlsVerify(k.PubKey, msg, sig) {
+			t.Fatal("verify fail")
+		}
+		if BlsVerify(k.PubKey, []byte("other"), sig) {
+			t.Fatal("sig of other msg should fail")
+		}
+		sigs[i] = sig
+		pks[i] = k.PubKey
+
+		pop, err := BlsProve(k.SecretKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if BlsCheckKey(k.PubKey, pop) != nil {
+			t.Fatal("proof of possession fail")
+		}
+		// pop is not a sig of pk as msg
+		if BlsCheckKey(k.PubKey, sig) == nil {
+			t.Fatal("sig should not be a proof")
+		}
+		psig, err := BlsSign(k.SecretKey, k.PubKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(psig, pop) || BlsCheckKey(k.PubKey, psig) == nil {
+			t.Fatal("proof should be separated from sig")
+		}
+	}
+
+	asig, err := BlsAggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !BlsVerifyAggregate(pks, msg, asig) {
+		t.Fatal("verify aggregate fail")
+	}
+	if BlsVerifyAggregate(pks[:2], msg, asig) {
+		t.Fatal("aggregate of less keys should fail")
+	}
+
+	asig, err = BlsAggregate(sigs[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !BlsVerifyAggregate(pks[:2], msg, asig) {
+		t.Fatal("verify aggregate of two fail")
+	}
+
+	if BlsCheckKey(make([]byte, BlsPubKeyLength), make([]byte, BlsSignatureLength)) == nil {
+		t.Fatal("zero key should fail")
+	}
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+// DefaultChainID is chain ID of a settlement chain unless its genesis sets one
+const DefaultChainID uint64 = 1
+
+var encMode cbor.EncMode
+
+func init() {
+	em, err := cbor.CanonicalEncOptions().EncMode()
+	if err != nil {
+		panic(err)
+	}
+	encMode = em
+}
+
+// CanonicalMarshal encodes v in canonical cbor
+func CanonicalMarshal(v interface{}) ([]byte, error) {
+	return encMode.Marshal(v)
+}
+
+// EncodeParams encodes call params as canonical cbor array; params are
+// arguments of node method after uid, sig and caller, in order
+func EncodeParams(params ...interface{}) ([]byte, error) {
+	if params == nil {
+		params = []interface{}{}
+	}
+	return encMode.Marshal(params)
+}
+
+// domain tags of signed digests, one for each operation
+const (
+	DomainCall          = "settle/call"
+	DomainRegister      = "settle/register"
+	DomainKeeper        = "settle/keeper"
+	DomainProvider      = "settle/provider"
+	DomainAddKeeper     = "settle/addkeeper"
+	DomainReady         = "settle/ready"
+	DomainAddOrder      = "settle/addorder"
+	DomainSubOrder      = "settle/suborder"
+	DomainAddRepair     = "settle/addrepair"
+	DomainSubRepair     = "settle/subrepair"
+	DomainProWithdraw   = "settle/prowithdraw"
+	DomainChangeAddress = "settle/changeaddress"
+)
+
+// Digest is blake2b of canonical cbor of [tag, chainID, fields...];
+// a sig on it is only valid for this operation on this chain
+func Digest(tag string, chainID uint64, fields ...interface{}) (Hash, error) {
+	buf, err := encMode.Marshal(append([]interface{}{tag, chainID}, fields...))
+	if err != nil {
+		return NilHash, err
+	}
+
+	return HashOf(buf), nil
+}
+
+// CallDigest is signed by caller of a call:
+// Digest of DomainCall with [method, nonce, params]
+func CallDigest(method string, chainID, nonce uint64, params []byte) (Hash, error) {
+	return Digest(DomainCall, chainID, method, nonce, params)
+}
+
+// SignCall signs digest of call method with params at nonce by sk
+func SignCall(sk []byte, method string, chainID, nonce uint64, params ...interface{}) ([]byte, error) {
+	pb, err := EncodeParams(params...)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := CallDigest(method, chainID, nonce, pb)
+	if err != nil {
+		return nil, err
+	}
+
+	return Sign(sk, h[:])
+}
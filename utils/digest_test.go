@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestEncodeParams(t *testing.T) {
+	type transferParams struct {
+		_     struct{} `cbor:",toarray"`
+		TAddr Address
+		To    Address
+		Value *big.Int
+		Extra []byte
+	}
+
+	ta := HexToAddress("0x01")
+	to := HexToAddress("0x02")
+	pb, err := CanonicalMarshal(&transferParams{TAddr: ta, To: to, Value: big.NewInt(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := EncodeParams(ta, to, big.NewInt(10), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(pb, args) {
+		t.Fatal("params are encoded differently")
+	}
+
+	h1, err := CallDigest("Transfer", DefaultChainID, 0, pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := CallDigest("Transfer", DefaultChainID+1, 0, pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 == h2 {
+		t.Fatal("digest should cover chain ID")
+	}
+}
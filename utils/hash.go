@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"encoding/hex"
+
+	blake2b "github.com/minio/blake2b-simd"
+)
+
+const (
+	HashLength = 32
+)
+
+// Hash is blake2b-256 of data
+type Hash [HashLength]byte
+
+// NilHash is a nil
+var NilHash Hash
+
+func BytesToHash(b []byte) Hash {
+	var h Hash
+	if len(b) > HashLength {
+		b = b[len(b)-HashLength:]
+	}
+	copy(h[HashLength-len(b):], b)
+	return h
+}
+
+func HexToHash(s string) Hash {
+	return BytesToHash(FromHex(s))
+}
+
+func HashOf(data []byte) Hash {
+	return Hash(blake2b.Sum256(data))
+}
+
+func (h Hash) String() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *Hash) UnmarshalText(b []byte) error {
+	*h = HexToHash(string(b))
+	return nil
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"crypto/ecdsa"
 	"encoding/hex"
+	"errors"
 	"io"
 
 	"github.com/btcsuite/btcd/btcec"
@@ -19,6 +20,11 @@ type Address [AddressLength]byte
 // NilAddress is a nil
 var NilAddress Address
 
+var (
+	ErrAddress = errors.New("address is not right")
+	ErrKey     = errors.New("secret key is not right")
+)
+
 func BytesToAddress(b []byte) Address {
 	var a Address
 	a.SetBytes(b)
@@ -41,6 +47,27 @@ func (a *Address) hex() string {
 	return "0x" + hex.EncodeToString(a[:])
 }
 
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.hex()), nil
+}
+
+// UnmarshalText accepts hex with or without 0x of exact length
+func (a *Address) UnmarshalText(b []byte) error {
+	s := string(b)
+	if has0xPrefix(s) {
+		s = s[2:]
+	}
+	buf, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	if len(buf) != AddressLength {
+		return ErrAddress
+	}
+	copy(a[:], buf)
+	return nil
+}
+
 func HexToAddress(s string) Address {
 	return BytesToAddress(FromHex(s))
 }
@@ -84,6 +111,26 @@ func GenerateKey(rand io.Reader) (*Key, error) {
 	return k, nil
 }
 
+// ToKey restores key of secret key sk
+func ToKey(sk []byte) (*Key, error) {
+	if len(sk) != 32 {
+		return nil, ErrKey
+	}
+
+	priv, pk := btcec.PrivKeyFromBytes(btcec.S256(), sk)
+	if priv.D.Sign() == 0 || priv.D.Cmp(btcec.S256().N) >= 0 {
+		return nil, ErrKey
+	}
+
+	return &Key{
+		SecretKey: priv.Serialize(),
+		PubKey:    pk.SerializeUncompressed(),
+	}, nil
+}
+
+// ToAddress is last 20 bytes of blake2b-256 of the 64 byte uncompressed pk;
+// Ethereum uses keccak-256 instead, so it differs from Ethereum address of
+// the same key (keystore.EthAddress)
 func ToAddress(pk []byte) Address {
 	if len(pk) == 65 {
 		pk = pk[1:]
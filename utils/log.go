@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"os"
+	"path/filepath"
+	"sync"
+
 	"github.com/mitchellh/go-homedir"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -9,6 +13,12 @@ import (
 
 var mLogger *zap.SugaredLogger
 
+// level and output of all loggers, changed by SetupLogger
+var (
+	logLevel  = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	logOutput = new(swapWriter)
+)
+
 func Logger(name string) *zap.SugaredLogger {
 	return mLogger.Named(name)
 }
@@ -16,15 +26,12 @@ func Logger(name string) *zap.SugaredLogger {
 // StartLogger starts
 func init() {
 
-	debugLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl >= zapcore.DebugLevel
-	})
-
-	debugWriter := getLogWriter("debug")
+	root, _ := homedir.Expand("~/.memo")
+	logOutput.w = getLogWriter(filepath.Join(root, "logs"), "debug")
 
 	encoder := getEncoder()
 
-	core := zapcore.NewCore(encoder, debugWriter, debugLevel)
+	core := zapcore.NewCore(encoder, logOutput, logLevel)
 
 	// NewProduction
 	logger := zap.New(core, zap.AddCaller())
@@ -52,12 +59,52 @@ func getEncoder() zapcore.Encoder {
 	return zapcore.NewJSONEncoder(encoderConfig)
 }
 
-func getLogWriter(filename string) zapcore.WriteSyncer {
+// SetupLogger sets level of all loggers, and output: stdout, stderr
+// or a dir which log file is written in
+func SetupLogger(level, output string) error {
+	err := logLevel.UnmarshalText([]byte(level))
+	if err != nil {
+		return err
+	}
 
-	root, _ := homedir.Expand("~/.memo")
+	var w zapcore.WriteSyncer
+	switch output {
+	case "stdout":
+		w = zapcore.Lock(os.Stdout)
+	case "stderr":
+		w = zapcore.Lock(os.Stderr)
+	default:
+		w = getLogWriter(output, "debug")
+	}
+
+	logOutput.Lock()
+	logOutput.w = w
+	logOutput.Unlock()
+
+	return nil
+}
+
+// swapWriter lets loggers made at init write to output set later
+type swapWriter struct {
+	sync.RWMutex
+	w zapcore.WriteSyncer
+}
+
+func (s *swapWriter) Write(p []byte) (int, error) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.w.Write(p)
+}
+
+func (s *swapWriter) Sync() error {
+	s.RLock()
+	defer s.RUnlock()
+	return s.w.Sync()
+}
 
+func getLogWriter(dir, filename string) zapcore.WriteSyncer {
 	lumberJackLogger := &lumberjack.Logger{
-		Filename:   root + "/logs/" + filename + ".log",
+		Filename:   filepath.Join(dir, filename+".log"),
 		MaxSize:    100, //MB
 		MaxBackups: 3,
 		MaxAge:     30, //days